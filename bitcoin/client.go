@@ -82,8 +82,30 @@ const (
 	// https://developer.bitcoin.org/reference/rpc/getrawmempool.html
 	requestMethodRawMempool requestMethod = "getrawmempool"
 
+	// https://developer.bitcoin.org/reference/rpc/getmempoolentry.html
+	requestMethodGetMempoolEntry requestMethod = "getmempoolentry"
+
+	// https://developer.bitcoin.org/reference/rpc/getrawtransaction.html
+	requestMethodGetRawTransaction requestMethod = "getrawtransaction"
+
 	// blockNotFoundErrCode is the RPC error code when a block cannot be found
 	blockNotFoundErrCode = -5
+
+	// transactionNotFoundErrCode is the RPC error code when a
+	// transaction is not in the mempool
+	transactionNotFoundErrCode = -5
+
+	// TransactionErrCode is the RPC error code when a transaction
+	// cannot be submitted, including when its inputs are missing
+	TransactionErrCode = -25
+
+	// TransactionRejectedErrCode is the RPC error code when a
+	// transaction is rejected by the mempool policy
+	TransactionRejectedErrCode = -26
+
+	// TransactionAlreadyInChainErrCode is the RPC error code when a
+	// transaction is already included in a block
+	TransactionAlreadyInChainErrCode = -27
 )
 
 const (
@@ -107,6 +129,10 @@ var (
 	// cannot be found by the node
 	ErrBlockNotFound = errors.New("unable to find block")
 
+	// ErrTransactionNotInMempool is returned when the requested
+	// transaction is not in the mempool of the node
+	ErrTransactionNotInMempool = errors.New("transaction not in mempool")
+
 	// ErrJSONRPCError is returned when receiving an error from a JSON-RPC response
 	ErrJSONRPCError = errors.New("JSON-RPC error")
 )
@@ -337,6 +363,44 @@ func (b *Client) RawMempool(
 	return response.Result, nil
 }
 
+// MempoolEntry returns the size and fees of a transaction
+// in the mempool and of its unconfirmed ancestors.
+func (b *Client) MempoolEntry(
+	ctx context.Context,
+	txid string,
+) (*MempoolEntry, error) {
+	// Parameters:
+	//   1. txid
+	params := []interface{}{txid}
+
+	response := &mempoolEntryResponse{}
+	if err := b.post(ctx, requestMethodGetMempoolEntry, params, response); err != nil {
+		return nil, fmt.Errorf("%w: error getting mempool entry %s", err, txid)
+	}
+
+	return response.Result, nil
+}
+
+// RawTransaction returns a transaction of the mempool. Without
+// -txindex, bitcoind only returns confirmed transactions that
+// have unspent outputs.
+func (b *Client) RawTransaction(
+	ctx context.Context,
+	txid string,
+) (*Transaction, error) {
+	// Parameters:
+	//   1. txid
+	//   2. verbose
+	params := []interface{}{txid, true}
+
+	response := &decodeTransactionResponse{}
+	if err := b.post(ctx, requestMethodGetRawTransaction, params, response); err != nil {
+		return nil, fmt.Errorf("%w: error getting raw transaction %s", err, txid)
+	}
+
+	return response.Result, nil
+}
+
 // getPeerInfo performs the `getpeerinfo` JSON-RPC request
 func (b *Client) getPeerInfo(
 	ctx context.Context,
@@ -406,6 +470,7 @@ func (b *Client) getBlock(
 				return nil, fmt.Errorf("%w: error decoding block with hexstring %s", err, hexstring)
 			}
 			txV.Result.Weight = txV.Result.Vsize * weightMultiplier
+			txV.Result.Hex = hexstring
 			txs = append(txs, txV.Result)
 		}
 		blockResponse.Result.Txs = txs
@@ -1345,6 +1345,89 @@ func TestSuggestedFeeRate(t *testing.T) {
 	}
 }
 
+func TestSendRawTransaction(t *testing.T) {
+	tests := map[string]struct {
+		responses []responseFixture
+
+		expectedHash  string
+		expectedError error
+	}{
+		"successful": {
+			responses: []responseFixture{
+				{
+					status: http.StatusOK,
+					body:   loadFixture("send_raw_transaction.json"),
+					url:    url,
+				},
+			},
+			expectedHash: "2f2bdf5b2e2ef8a3e6ad8b1b3bbac0e8a39eb13a0b6de9aeac5d4d82d5dd4e8c",
+		},
+		"rejected": {
+			responses: []responseFixture{
+				{
+					status: http.StatusOK,
+					body:   loadFixture("send_raw_transaction_rejected.json"),
+					url:    url,
+				},
+			},
+			expectedError: &RPCError{
+				Code:    TransactionRejectedErrCode,
+				Message: "64: dust",
+			},
+		},
+		"500 error": {
+			responses: []responseFixture{
+				{
+					status: http.StatusInternalServerError,
+					body:   "{}",
+					url:    url,
+				},
+			},
+			expectedError: errors.New("invalid response: 500 Internal Server Error"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				assert = assert.New(t)
+			)
+
+			responses := make(chan responseFixture, len(test.responses))
+			for _, response := range test.responses {
+				responses <- response
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				response := <-responses
+				assert.Equal("application/json", r.Header.Get("Content-Type"))
+				assert.Equal("POST", r.Method)
+				assert.Equal(response.url, r.URL.RequestURI())
+
+				w.WriteHeader(response.status)
+				fmt.Fprintln(w, response.body)
+			}))
+
+			client := NewClient(ts.URL, MainnetGenesisBlockIdentifier, MainnetCurrency)
+			hash, err := client.SendRawTransaction(context.Background(), "01000000")
+			if test.expectedError != nil {
+				assert.Contains(err.Error(), test.expectedError.Error())
+
+				var rpcErr *RPCError
+				if errors.As(test.expectedError, &rpcErr) {
+					var actual *RPCError
+					assert.True(errors.As(err, &actual))
+					assert.Equal(rpcErr, actual)
+					assert.True(errors.Is(err, ErrJSONRPCError))
+				}
+			} else {
+				assert.NoError(err)
+				assert.Equal(test.expectedHash, hash)
+			}
+		})
+	}
+}
+
 func TestRawMempool(t *testing.T) {
 	tests := map[string]struct {
 		responses []responseFixture
@@ -1411,6 +1494,197 @@ func TestRawMempool(t *testing.T) {
 	}
 }
 
+func TestMempoolEntry(t *testing.T) {
+	tests := map[string]struct {
+		responses []responseFixture
+
+		expectedEntry *MempoolEntry
+		expectedError error
+	}{
+		"successful": {
+			responses: []responseFixture{
+				{
+					status: http.StatusOK,
+					body:   loadFixture("mempool_entry.json"),
+					url:    url,
+				},
+			},
+			expectedEntry: &MempoolEntry{
+				Size:          226,
+				Fee:           0.00226,
+				AncestorCount: 2,
+				AncestorSize:  452,
+				AncestorFees:  452000,
+			},
+		},
+		"not in mempool": {
+			responses: []responseFixture{
+				{
+					status: http.StatusOK,
+					body:   loadFixture("mempool_entry_not_found.json"),
+					url:    url,
+				},
+			},
+			expectedError: ErrTransactionNotInMempool,
+		},
+		"500 error": {
+			responses: []responseFixture{
+				{
+					status: http.StatusInternalServerError,
+					body:   "{}",
+					url:    url,
+				},
+			},
+			expectedError: errors.New("invalid response: 500 Internal Server Error"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				assert = assert.New(t)
+			)
+
+			responses := make(chan responseFixture, len(test.responses))
+			for _, response := range test.responses {
+				responses <- response
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				response := <-responses
+				assert.Equal("application/json", r.Header.Get("Content-Type"))
+				assert.Equal("POST", r.Method)
+				assert.Equal(response.url, r.URL.RequestURI())
+
+				w.WriteHeader(response.status)
+				fmt.Fprintln(w, response.body)
+			}))
+
+			client := NewClient(ts.URL, MainnetGenesisBlockIdentifier, MainnetCurrency)
+			entry, err := client.MempoolEntry(
+				context.Background(),
+				"43370435829c24fabb266d2f802c0963180fe8b37f8d51ee712651f9b5d18393",
+			)
+			if test.expectedError != nil {
+				assert.Contains(err.Error(), test.expectedError.Error())
+				if test.expectedError == ErrTransactionNotInMempool {
+					assert.True(errors.Is(err, ErrTransactionNotInMempool))
+				}
+			} else {
+				assert.NoError(err)
+				assert.Equal(test.expectedEntry, entry)
+			}
+		})
+	}
+}
+
+func TestRawTransaction(t *testing.T) {
+	tests := map[string]struct {
+		responses []responseFixture
+
+		expectedTransaction *Transaction
+		expectedError       error
+	}{
+		"successful": {
+			responses: []responseFixture{
+				{
+					status: http.StatusOK,
+					body:   loadFixture("raw_transaction.json"),
+					url:    url,
+				},
+			},
+			expectedTransaction: &Transaction{
+				Hex:      "01000000017f9cd2e4a63e0a6d4b1f5a5d5e4ccbb6c3f9a2e8a52b4addc80305b5a55741b1010000000100ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac0065cd1d000000001976a914751e76e8199196d454941c45d1b3a323f1433bd688ac00000000", // nolint
+				Hash:     "43370435829c24fabb266d2f802c0963180fe8b37f8d51ee712651f9b5d18393",
+				Size:     120,
+				Vsize:    120,
+				Version:  1,
+				Locktime: 0,
+				Inputs: []*Input{
+					{
+						TxHash: "b14157a5b50503c8dd4a2ba5e8a2f9c3b6cb4c5e5d5a1f4b6d0a3ea6e4d29c7f",
+						Vout:   1,
+						ScriptSig: &ScriptSig{
+							ASM: "0",
+							Hex: "00",
+						},
+						Sequence: 4294967295,
+					},
+				},
+				Outputs: []*Output{
+					{
+						Value: 10,
+						Index: 0,
+						ScriptPubKey: &ScriptPubKey{
+							ASM:          "OP_DUP OP_HASH160 06afd46bcdfd22ef94ac122aa11f241244a37ecc OP_EQUALVERIFY OP_CHECKSIG", // nolint
+							Hex:          "76a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac",
+							RequiredSigs: 1,
+							Type:         "pubkeyhash",
+							Addresses:    []string{"nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64"},
+						},
+					},
+					{
+						Value: 5,
+						Index: 1,
+						ScriptPubKey: &ScriptPubKey{
+							ASM:          "OP_DUP OP_HASH160 751e76e8199196d454941c45d1b3a323f1433bd6 OP_EQUALVERIFY OP_CHECKSIG", // nolint
+							Hex:          "76a914751e76e8199196d454941c45d1b3a323f1433bd688ac",
+							RequiredSigs: 1,
+							Type:         "pubkeyhash",
+							Addresses:    []string{"nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"},
+						},
+					},
+				},
+			},
+		},
+		"500 error": {
+			responses: []responseFixture{
+				{
+					status: http.StatusInternalServerError,
+					body:   "{}",
+					url:    url,
+				},
+			},
+			expectedError: errors.New("invalid response: 500 Internal Server Error"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				assert = assert.New(t)
+			)
+
+			responses := make(chan responseFixture, len(test.responses))
+			for _, response := range test.responses {
+				responses <- response
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				response := <-responses
+				assert.Equal("application/json", r.Header.Get("Content-Type"))
+				assert.Equal("POST", r.Method)
+				assert.Equal(response.url, r.URL.RequestURI())
+
+				w.WriteHeader(response.status)
+				fmt.Fprintln(w, response.body)
+			}))
+
+			client := NewClient(ts.URL, MainnetGenesisBlockIdentifier, MainnetCurrency)
+			tx, err := client.RawTransaction(
+				context.Background(),
+				"43370435829c24fabb266d2f802c0963180fe8b37f8d51ee712651f9b5d18393",
+			)
+			if test.expectedError != nil {
+				assert.Contains(err.Error(), test.expectedError.Error())
+			} else {
+				assert.NoError(err)
+				assert.Equal(test.expectedTransaction, tx)
+			}
+		})
+	}
+}
+
 // loadFixture takes a file name and returns the response fixture.
 func loadFixture(fileName string) string {
 	content, err := ioutil.ReadFile(fmt.Sprintf("client_fixtures/%s", fileName))
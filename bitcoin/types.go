@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
 	"github.com/coinbase/rosetta-sdk-go/types"
 )
 
@@ -80,13 +81,31 @@ const (
 // Fee estimate constants
 // Source: https://bitcoinops.org/en/tools/calc-size/
 const (
-	MinFeeRate            = float64(0.00001) // nolint:gomnd
-	TransactionOverhead   = 12               // 4 version, 2 segwit flag, 1 vin, 1 vout, 4 lock time
-	InputSize             = 68               // 4 prev index, 32 prev hash, 4 sequence, 1 script size, ~27 script witness
-	OutputOverhead        = 9                // 8 value, 1 script size
-	P2PKHScriptPubkeySize = 25               // P2PKH size
+	MinFeeRate                = float64(0.00001) // nolint:gomnd
+	TransactionOverhead       = 12               // 4 version, 2 segwit flag, 1 vin, 1 vout, 4 lock time
+	LegacyTransactionOverhead = 10               // 4 version, 1 vin, 1 vout, 4 lock time
+	InputSize                 = 68               // 4 prev index, 32 prev hash, 4 sequence, 1 script size, ~27 script witness
+	InputOverhead             = 40               // 4 prev index, 32 prev hash, 4 sequence
+	SignatureSize             = 73               // 1 push, 71 DER signature, 1 sighash type
+	CompressedPubKeySize      = 34               // 1 push, 33 public key
+	UncompressedPubKeySize    = 66               // 1 push, 65 public key
+	P2SHInputSize             = 297              // 2-of-3 multisig when the redeem script is unknown
+	OutputOverhead            = 9                // 8 value, 1 script size
+	P2PKHScriptPubkeySize     = 25               // P2PKH size
 )
 
+// MaxMultiSigPubKeys is the largest number of public keys
+// in a standard P2SH multisig redeem script.
+const MaxMultiSigPubKeys = 15
+
+// maxLockTimeNumSize is the largest number of bytes of the
+// lock time of OP_CHECKLOCKTIMEVERIFY.
+const maxLockTimeNumSize = 5
+
+// PaymentHashSize is the size of the SHA-256 payment hash
+// of a hash time locked contract and of its preimage.
+const PaymentHashSize = 32
+
 var (
 	// MainnetGenesisBlockIdentifier is the genesis block for mainnet.
 	MainnetGenesisBlockIdentifier = &types.BlockIdentifier{
@@ -145,6 +164,21 @@ type ScriptPubKey struct {
 	RequiredSigs int64    `json:"reqSigs,omitempty"`
 	Type         string   `json:"type"`
 	Addresses    []string `json:"addresses,omitempty"`
+
+	// RedeemScript is the hex-encoded redeem script of
+	// a P2SH script, when it is known by the indexer. It
+	// is never returned by bitcoind.
+	RedeemScript string `json:"redeemScript,omitempty"`
+}
+
+// HTLC is a hash time locked contract. Its coins are
+// claimed by Recipient with the preimage of PaymentHash
+// or refunded to Refund from LockTime.
+type HTLC struct {
+	PaymentHash []byte
+	Recipient   *btcutil.AddressPubKey
+	Refund      *btcutil.AddressPubKey
+	LockTime    int64
 }
 
 // ScriptSig is a script on the input operations of a
@@ -155,6 +189,25 @@ type ScriptSig struct {
 	Hex string `json:"hex"`
 }
 
+// MempoolEntry is a transaction in the mempool. The
+// ancestor statistics include the transaction itself.
+// This struct only contains the information necessary
+// for this implementation.
+type MempoolEntry struct {
+	// Size is the size of the transaction in bytes.
+	Size int64 `json:"size"`
+
+	// Fee is the fee of the transaction in DOGE.
+	Fee float64 `json:"fee"`
+
+	AncestorCount int64 `json:"ancestorcount"`
+	AncestorSize  int64 `json:"ancestorsize"`
+
+	// AncestorFees is the fee of the transaction and
+	// of its unconfirmed ancestors in Satoshis.
+	AncestorFees int64 `json:"ancestorfees"`
+}
+
 // BlockchainInfo is information about the Bitcoin network.
 // This struct only contains the information necessary for
 // this implementation.
@@ -401,6 +454,28 @@ type responseError struct {
 	Message string `json:"message"`
 }
 
+// RPCError is a JSON-RPC error response. Callers
+// use errors.As to inspect the code and message
+// returned by the node.
+type RPCError struct {
+	Code    int64
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf(
+		"%s: error JSON RPC response, code: %d, message: %s",
+		ErrJSONRPCError,
+		e.Code,
+		e.Message,
+	)
+}
+
+// Unwrap returns ErrJSONRPCError.
+func (e *RPCError) Unwrap() error {
+	return ErrJSONRPCError
+}
+
 // stringResponse is the response body for requests (with verbosity == 0)
 type stringResponse struct {
 	Result string         `json:"result"`
@@ -550,12 +625,10 @@ func (s sendRawTransactionResponse) Err() error {
 		return nil
 	}
 
-	return fmt.Errorf(
-		"%w: error JSON RPC response, code: %d, message: %s",
-		ErrJSONRPCError,
-		s.Error.Code,
-		s.Error.Message,
-	)
+	return &RPCError{
+		Code:    s.Error.Code,
+		Message: s.Error.Message,
+	}
 }
 
 type suggestedFeeRate struct {
@@ -600,6 +673,29 @@ func (r rawMempoolResponse) Err() error {
 	)
 }
 
+// mempoolEntryResponse is the response body for `getmempoolentry` requests.
+type mempoolEntryResponse struct {
+	Result *MempoolEntry  `json:"result"`
+	Error  *responseError `json:"error"`
+}
+
+func (m mempoolEntryResponse) Err() error {
+	if m.Error == nil {
+		return nil
+	}
+
+	if m.Error.Code == transactionNotFoundErrCode {
+		return ErrTransactionNotInMempool
+	}
+
+	return fmt.Errorf(
+		"%w: error JSON RPC response, code: %d, message: %s",
+		ErrJSONRPCError,
+		m.Error.Code,
+		m.Error.Message,
+	)
+}
+
 // CoinIdentifier converts a tx hash and vout into
 // the canonical CoinIdentifier.Identifier used in
 // rosetta-bitcoin.
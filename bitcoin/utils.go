@@ -15,13 +15,17 @@
 package bitcoin
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/coinbase/rosetta-sdk-go/types"
 )
@@ -30,6 +34,9 @@ import (
 // with a *types.CoinIdentifier.
 func ParseCoinIdentifier(coinIdentifier *types.CoinIdentifier) (*chainhash.Hash, uint32, error) {
 	utxoSpent := strings.Split(coinIdentifier.Identifier, ":")
+	if len(utxoSpent) != 2 { // nolint:gomnd
+		return nil, 0, fmt.Errorf("coin identifier %s is not hash:index", coinIdentifier.Identifier)
+	}
 
 	outpointHash := utxoSpent[0]
 	if len(outpointHash) != TransactionHashLength {
@@ -68,3 +75,361 @@ func ParseSingleAddress(
 
 	return class, address, nil
 }
+
+// MultiSigScript returns the standard m-of-n multisig
+// redeem script for a collection of serialized public keys.
+func MultiSigScript(
+	chainParams *chaincfg.Params,
+	pubKeys [][]byte,
+	nRequired int,
+) ([]byte, error) {
+	if nRequired < 1 || nRequired > len(pubKeys) {
+		return nil, fmt.Errorf("threshold %d is not in [1, %d]", nRequired, len(pubKeys))
+	}
+
+	if len(pubKeys) > MaxMultiSigPubKeys {
+		return nil, fmt.Errorf("expecting at most %d public keys, got %d", MaxMultiSigPubKeys, len(pubKeys))
+	}
+
+	addresses := make([]*btcutil.AddressPubKey, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		address, err := btcutil.NewAddressPubKey(pubKey, chainParams)
+		if err != nil {
+			return nil, fmt.Errorf("%w unable to parse public key %d", err, i)
+		}
+
+		addresses[i] = address
+	}
+
+	return txscript.MultiSigScript(addresses, nRequired)
+}
+
+// ParseMultiSigScript extracts the public keys and the number
+// of required signatures from a standard multisig redeem script
+// or throws an error.
+func ParseMultiSigScript(
+	chainParams *chaincfg.Params,
+	script []byte,
+) ([]*btcutil.AddressPubKey, int, error) {
+	class, addresses, nRequired, err := txscript.ExtractPkScriptAddrs(script, chainParams)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w unable to extract script addresses", err)
+	}
+
+	if class != txscript.MultiSigTy {
+		return nil, 0, fmt.Errorf("expecting %s script, got %s", txscript.MultiSigTy, class)
+	}
+
+	pubKeys := make([]*btcutil.AddressPubKey, len(addresses))
+	for i, address := range addresses {
+		pubKey, ok := address.(*btcutil.AddressPubKey)
+		if !ok {
+			return nil, 0, fmt.Errorf("address %d is not a public key", i)
+		}
+
+		pubKeys[i] = pubKey
+	}
+
+	return pubKeys, nRequired, nil
+}
+
+// LockTimeScript returns the redeem script locking coins
+// to a serialized public key until lockTime:
+//
+//	<lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP <pubKey> OP_CHECKSIG
+func LockTimeScript(
+	chainParams *chaincfg.Params,
+	pubKey []byte,
+	lockTime int64,
+) ([]byte, error) {
+	if lockTime <= 0 || lockTime > math.MaxUint32 {
+		return nil, fmt.Errorf("lock time %d is not in [1, %d]", lockTime, uint32(math.MaxUint32))
+	}
+
+	if _, err := btcutil.NewAddressPubKey(pubKey, chainParams); err != nil {
+		return nil, fmt.Errorf("%w unable to parse public key", err)
+	}
+
+	return txscript.NewScriptBuilder().
+		AddInt64(lockTime).
+		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		AddOp(txscript.OP_DROP).
+		AddData(pubKey).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// ParseLockTimeScript extracts the public key and the lock
+// time from a lock time redeem script or throws an error.
+func ParseLockTimeScript(
+	chainParams *chaincfg.Params,
+	script []byte,
+) (*btcutil.AddressPubKey, int64, error) {
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w unable to parse script", err)
+	}
+
+	if len(pushes) != 2 { // nolint:gomnd
+		return nil, 0, fmt.Errorf("expecting 2 pushes, got %d", len(pushes))
+	}
+
+	lockTime, err := parseScriptNum(pushes[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Rebuilding the script rejects any other
+	// opcode and non-canonical pushes.
+	expected, err := LockTimeScript(chainParams, pushes[1], lockTime)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !bytes.Equal(script, expected) {
+		return nil, 0, errors.New("script is not a lock time script")
+	}
+
+	pubKey, err := btcutil.NewAddressPubKey(pushes[1], chainParams)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w unable to parse public key", err)
+	}
+
+	return pubKey, lockTime, nil
+}
+
+// HTLCScript returns the redeem script of a hash time locked
+// contract paying to recipientKey with the preimage of
+// paymentHash or to refundKey from lockTime:
+//
+//	OP_IF
+//		OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <paymentHash> OP_EQUALVERIFY <recipientKey>
+//	OP_ELSE
+//		<lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP <refundKey>
+//	OP_ENDIF
+//	OP_CHECKSIG
+func HTLCScript(
+	chainParams *chaincfg.Params,
+	paymentHash []byte,
+	recipientKey []byte,
+	refundKey []byte,
+	lockTime int64,
+) ([]byte, error) {
+	if len(paymentHash) != PaymentHashSize {
+		return nil, fmt.Errorf("expecting %d bytes payment hash, got %d", PaymentHashSize, len(paymentHash))
+	}
+
+	if lockTime <= 0 || lockTime > math.MaxUint32 {
+		return nil, fmt.Errorf("lock time %d is not in [1, %d]", lockTime, uint32(math.MaxUint32))
+	}
+
+	if _, err := btcutil.NewAddressPubKey(recipientKey, chainParams); err != nil {
+		return nil, fmt.Errorf("%w unable to parse recipient public key", err)
+	}
+
+	if _, err := btcutil.NewAddressPubKey(refundKey, chainParams); err != nil {
+		return nil, fmt.Errorf("%w unable to parse refund public key", err)
+	}
+
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_IF).
+		AddOp(txscript.OP_SIZE).
+		AddInt64(PaymentHashSize).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_SHA256).
+		AddData(paymentHash).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddData(recipientKey).
+		AddOp(txscript.OP_ELSE).
+		AddInt64(lockTime).
+		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		AddOp(txscript.OP_DROP).
+		AddData(refundKey).
+		AddOp(txscript.OP_ENDIF).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// ParseHTLCScript extracts the contract of a hash time
+// locked contract redeem script or throws an error.
+func ParseHTLCScript(chainParams *chaincfg.Params, script []byte) (*HTLC, error) {
+	pushes, err := txscript.PushedData(script)
+	if err != nil {
+		return nil, fmt.Errorf("%w unable to parse script", err)
+	}
+
+	// The preimage size, payment hash, recipient
+	// key, lock time and refund key.
+	if len(pushes) != 5 { // nolint:gomnd
+		return nil, fmt.Errorf("expecting 5 pushes, got %d", len(pushes))
+	}
+
+	lockTime, err := parseScriptNum(pushes[3])
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuilding the script rejects any other
+	// opcode and non-canonical pushes.
+	expected, err := HTLCScript(chainParams, pushes[1], pushes[2], pushes[4], lockTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(script, expected) {
+		return nil, errors.New("script is not a hash time locked contract")
+	}
+
+	recipient, err := btcutil.NewAddressPubKey(pushes[2], chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("%w unable to parse recipient public key", err)
+	}
+
+	refund, err := btcutil.NewAddressPubKey(pushes[4], chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("%w unable to parse refund public key", err)
+	}
+
+	return &HTLC{
+		PaymentHash: pushes[1],
+		Recipient:   recipient,
+		Refund:      refund,
+		LockTime:    lockTime,
+	}, nil
+}
+
+// EstimateInputSize returns the estimated size in bytes of an
+// input spending a script of the provided class. The redeem
+// script is used to estimate P2SH inputs and compressed
+// indicates the format of the public key of P2PKH inputs.
+func EstimateInputSize(
+	chainParams *chaincfg.Params,
+	class txscript.ScriptClass,
+	redeemScript []byte,
+	compressed bool,
+) int {
+	switch class {
+	case txscript.WitnessV0PubKeyHashTy:
+		return InputSize
+	case txscript.PubKeyTy:
+		return InputOverhead + scriptSize(SignatureSize)
+	case txscript.ScriptHashTy:
+		if len(redeemScript) == 0 {
+			return P2SHInputSize
+		}
+
+		// <sig> <redeemScript>
+		if _, _, err := ParseLockTimeScript(chainParams, redeemScript); err == nil {
+			return InputOverhead + scriptSize(SignatureSize+pushSize(len(redeemScript)))
+		}
+
+		// <sig> <preimage> OP_TRUE <redeemScript> claims
+		// are larger than refunds.
+		if _, err := ParseHTLCScript(chainParams, redeemScript); err == nil {
+			return InputOverhead + scriptSize(
+				SignatureSize+pushSize(PaymentHashSize)+1+pushSize(len(redeemScript)),
+			)
+		}
+
+		_, nRequired, err := txscript.CalcMultiSigStats(redeemScript)
+		if err != nil {
+			return P2SHInputSize
+		}
+
+		// OP_0 <sigs> <redeemScript>
+		return InputOverhead + scriptSize(
+			1+nRequired*SignatureSize+pushSize(len(redeemScript)),
+		)
+	default:
+		pubKeySize := CompressedPubKeySize
+		if !compressed {
+			pubKeySize = UncompressedPubKeySize
+		}
+
+		return InputOverhead + scriptSize(SignatureSize+pubKeySize)
+	}
+}
+
+// sigHashTypes are the names of the supported
+// signature hash types.
+var sigHashTypes = map[string]txscript.SigHashType{
+	"ALL":                 txscript.SigHashAll,
+	"NONE":                txscript.SigHashNone,
+	"SINGLE":              txscript.SigHashSingle,
+	"ALL|ANYONECANPAY":    txscript.SigHashAll | txscript.SigHashAnyOneCanPay,
+	"NONE|ANYONECANPAY":   txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+	"SINGLE|ANYONECANPAY": txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+}
+
+// ParseSigHashType returns the signature hash type named
+// name, like "SINGLE|ANYONECANPAY". An empty name is
+// SIGHASH_ALL.
+func ParseSigHashType(name string) (txscript.SigHashType, error) {
+	if len(name) == 0 {
+		return txscript.SigHashAll, nil
+	}
+
+	hashType, ok := sigHashTypes[name]
+	if !ok {
+		return 0, fmt.Errorf("sighash type %s is not supported", name)
+	}
+
+	return hashType, nil
+}
+
+// SigHashTypeName returns the name of hashType.
+func SigHashTypeName(hashType txscript.SigHashType) string {
+	for name, sigHashType := range sigHashTypes {
+		if sigHashType == hashType {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("0x%x", uint32(hashType))
+}
+
+// parseScriptNum parses a number pushed by a script,
+// like the lock time of OP_CHECKLOCKTIMEVERIFY.
+func parseScriptNum(data []byte) (int64, error) {
+	if len(data) > maxLockTimeNumSize {
+		return 0, fmt.Errorf("number of %d bytes is too long", len(data))
+	}
+
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	// Numbers are little endian with a sign bit.
+	result := int64(0)
+	for i, b := range data {
+		result |= int64(b) << uint8(8*i) // nolint:gomnd
+	}
+
+	if data[len(data)-1]&0x80 != 0 { // nolint:gomnd
+		result &= ^(int64(0x80) << uint8(8*(len(data)-1))) // nolint:gomnd
+		return -result, nil
+	}
+
+	return result, nil
+}
+
+// scriptSize returns the serialized size of a script
+// of length n, including its length prefix.
+func scriptSize(n int) int {
+	return wire.VarIntSerializeSize(uint64(n)) + n
+}
+
+// pushSize returns the size of a canonical data
+// push of length n, including its opcode.
+func pushSize(n int) int {
+	switch {
+	case n < txscript.OP_PUSHDATA1:
+		return 1 + n
+	case n <= 0xff: // nolint:gomnd
+		return 2 + n // nolint:gomnd
+	case n <= 0xffff: // nolint:gomnd
+		return 3 + n // nolint:gomnd
+	default:
+		return 5 + n // nolint:gomnd
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitcoin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateInputSize(t *testing.T) {
+	pubKey1, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	pubKey2, _ := hex.DecodeString("02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	pubKey3, _ := hex.DecodeString("02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9")
+	redeemScript, err := MultiSigScript(
+		&chaincfg.MainNetParams,
+		[][]byte{pubKey1, pubKey2, pubKey3},
+		2,
+	)
+	assert.NoError(t, err)
+	lockTimeScript, err := LockTimeScript(&chaincfg.MainNetParams, pubKey1, 2000000)
+	assert.NoError(t, err)
+	htlcScript, err := HTLCScript(
+		&chaincfg.MainNetParams,
+		bytes.Repeat([]byte{0x11}, PaymentHashSize),
+		pubKey1,
+		pubKey2,
+		2000000,
+	)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		class        txscript.ScriptClass
+		redeemScript []byte
+		compressed   bool
+		expected     int
+	}{
+		"p2pkh compressed": {
+			class:      txscript.PubKeyHashTy,
+			compressed: true,
+			expected:   148,
+		},
+		"p2pkh uncompressed": {
+			class:    txscript.PubKeyHashTy,
+			expected: 180,
+		},
+		"p2pk": {
+			class:    txscript.PubKeyTy,
+			expected: 114,
+		},
+		"p2wpkh": {
+			class:    txscript.WitnessV0PubKeyHashTy,
+			expected: InputSize,
+		},
+		"p2sh 2-of-3": {
+			class:        txscript.ScriptHashTy,
+			redeemScript: redeemScript,
+			compressed:   true,
+			expected:     P2SHInputSize,
+		},
+		"p2sh lock time": {
+			class:        txscript.ScriptHashTy,
+			redeemScript: lockTimeScript,
+			compressed:   true,
+			expected:     156,
+		},
+		"p2sh htlc": {
+			class:        txscript.ScriptHashTy,
+			redeemScript: htlcScript,
+			compressed:   true,
+			expected:     267,
+		},
+		"p2sh unknown redeem script": {
+			class:    txscript.ScriptHashTy,
+			expected: P2SHInputSize,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(
+				t,
+				test.expected,
+				EstimateInputSize(
+					&chaincfg.MainNetParams,
+					test.class,
+					test.redeemScript,
+					test.compressed,
+				),
+			)
+		})
+	}
+}
+
+func TestLockTimeScript(t *testing.T) {
+	pubKey, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	multiSigScript, err := MultiSigScript(&chaincfg.MainNetParams, [][]byte{pubKey}, 1)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		script   string
+		pubKey   []byte
+		lockTime int64
+
+		expectedScript string
+		expectedErr    string
+	}{
+		"height": {
+			pubKey:         pubKey,
+			lockTime:       3500000,
+			expectedScript: "03e06735b1752102" + "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+		},
+		"timestamp": {
+			pubKey:         pubKey,
+			lockTime:       1700000000,
+			expectedScript: "0400f15365b1752102" + "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+		},
+		"sign bit": {
+			pubKey:         pubKey,
+			lockTime:       0x80000000,
+			expectedScript: "050000008000b1752102" + "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+		},
+		"zero lock time": {
+			pubKey:      pubKey,
+			expectedErr: "lock time 0 is not in [1, 4294967295]",
+		},
+		"invalid public key": {
+			pubKey:      []byte{0x02, 0x01},
+			lockTime:    3500000,
+			expectedErr: "unable to parse public key",
+		},
+		"multisig script": {
+			script:      hex.EncodeToString(multiSigScript),
+			expectedErr: "expecting 2 pushes, got 1",
+		},
+		"other opcode": {
+			script:      "03e06735b1752102" + "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ad",
+			expectedErr: "script is not a lock time script",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			script, err := hex.DecodeString(test.script)
+			assert.NoError(t, err)
+			if len(script) == 0 {
+				script, err = LockTimeScript(&chaincfg.MainNetParams, test.pubKey, test.lockTime)
+				if len(test.expectedErr) > 0 {
+					assert.Contains(t, err.Error(), test.expectedErr)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedScript, hex.EncodeToString(script))
+			}
+
+			pubKey, lockTime, err := ParseLockTimeScript(&chaincfg.MainNetParams, script)
+			if len(test.expectedErr) > 0 {
+				assert.Contains(t, err.Error(), test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.pubKey, pubKey.ScriptAddress())
+			assert.Equal(t, test.lockTime, lockTime)
+		})
+	}
+}
+
+func TestHTLCScript(t *testing.T) {
+	paymentHash := bytes.Repeat([]byte{0x11}, PaymentHashSize)
+	recipientKey, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	refundKey, _ := hex.DecodeString("02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	lockTimeScript, err := LockTimeScript(&chaincfg.MainNetParams, recipientKey, 3500000)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		script      string
+		paymentHash []byte
+		lockTime    int64
+
+		expectedScript string
+		expectedErr    string
+	}{
+		"height": {
+			paymentHash: paymentHash,
+			lockTime:    3500000,
+			expectedScript: "6382012088a820" + hex.EncodeToString(paymentHash) + "8821" +
+				hex.EncodeToString(recipientKey) + "6703e06735b17521" + hex.EncodeToString(refundKey) + "68ac",
+		},
+		"timestamp": {
+			paymentHash: paymentHash,
+			lockTime:    1700000000,
+			expectedScript: "6382012088a820" + hex.EncodeToString(paymentHash) + "8821" +
+				hex.EncodeToString(recipientKey) + "670400f15365b17521" + hex.EncodeToString(refundKey) + "68ac",
+		},
+		"short payment hash": {
+			paymentHash: paymentHash[:20],
+			lockTime:    3500000,
+			expectedErr: "expecting 32 bytes payment hash, got 20",
+		},
+		"zero lock time": {
+			paymentHash: paymentHash,
+			expectedErr: "lock time 0 is not in [1, 4294967295]",
+		},
+		"lock time script": {
+			script:      hex.EncodeToString(lockTimeScript),
+			expectedErr: "expecting 5 pushes, got 2",
+		},
+		"other hash": {
+			script: "6382012088a920" + hex.EncodeToString(paymentHash) + "8821" +
+				hex.EncodeToString(recipientKey) + "6703e06735b17521" + hex.EncodeToString(refundKey) + "68ac",
+			expectedErr: "script is not a hash time locked contract",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			script, err := hex.DecodeString(test.script)
+			assert.NoError(t, err)
+			if len(script) == 0 {
+				script, err = HTLCScript(
+					&chaincfg.MainNetParams,
+					test.paymentHash,
+					recipientKey,
+					refundKey,
+					test.lockTime,
+				)
+				if len(test.expectedErr) > 0 {
+					assert.Contains(t, err.Error(), test.expectedErr)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedScript, hex.EncodeToString(script))
+			}
+
+			htlc, err := ParseHTLCScript(&chaincfg.MainNetParams, script)
+			if len(test.expectedErr) > 0 {
+				assert.Contains(t, err.Error(), test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.paymentHash, htlc.PaymentHash)
+			assert.Equal(t, recipientKey, htlc.Recipient.ScriptAddress())
+			assert.Equal(t, refundKey, htlc.Refund.ScriptAddress())
+			assert.Equal(t, test.lockTime, htlc.LockTime)
+		})
+	}
+}
+
+func TestParseSigHashType(t *testing.T) {
+	tests := map[string]struct {
+		name string
+
+		expected    txscript.SigHashType
+		expectedErr bool
+	}{
+		"default": {
+			expected: txscript.SigHashAll,
+		},
+		"all": {
+			name:     "ALL",
+			expected: txscript.SigHashAll,
+		},
+		"none": {
+			name:     "NONE",
+			expected: txscript.SigHashNone,
+		},
+		"single anyonecanpay": {
+			name:     "SINGLE|ANYONECANPAY",
+			expected: txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+		},
+		"unsupported": {
+			name:        "SINGLE|NONE",
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			hashType, err := ParseSigHashType(test.name)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, hashType)
+			if len(test.name) > 0 {
+				assert.Equal(t, test.name, SigHashTypeName(hashType))
+			}
+		})
+	}
+}
@@ -99,6 +99,23 @@ const (
 	// read to determine the port for the Rosetta
 	// implementation.
 	PortEnv = "PORT"
+
+	// MaxFeeRateEnv is the optional environment
+	// variable read to determine the fee rate in
+	// Satoshis per kB above which transactions are
+	// not submitted.
+	MaxFeeRateEnv = "MAX_FEE_RATE"
+
+	// MaxFeeMultipleEnv is the optional environment
+	// variable read to determine the multiple of the
+	// suggested fee above which unsigned transactions
+	// are not created.
+	MaxFeeMultipleEnv = "MAX_FEE_MULTIPLE"
+
+	// MaxFeeEnv is the optional environment variable
+	// read to determine the fee in Satoshis above which
+	// unsigned transactions are not created.
+	MaxFeeEnv = "MAX_FEE"
 )
 
 // PruningConfiguration is the configuration to
@@ -109,6 +126,36 @@ type PruningConfiguration struct {
 	MinHeight int64
 }
 
+// ConstructionConfiguration is the configuration to
+// use for validation in the Construction API.
+type ConstructionConfiguration struct {
+	// DustLimit is the amount in Satoshis below which
+	// an output is rejected.
+	DustLimit int64
+
+	// MinChange is the smallest amount in Satoshis
+	// accepted for an output paying back to an input
+	// address.
+	MinChange int64
+
+	// MaxFeeRate is the fee rate in Satoshis per kB
+	// above which a signed transaction is refused
+	// before broadcast. A MaxFeeRate of 0 accepts
+	// any fee.
+	MaxFeeRate int64
+
+	// MaxFeeMultiple is the multiple of the suggested
+	// fee above which the fee implied by the operations
+	// of an unsigned transaction is refused. A
+	// MaxFeeMultiple of 0 accepts any multiple.
+	MaxFeeMultiple float64
+
+	// MaxFee is the fee in Satoshis above which the fee
+	// implied by the operations of an unsigned transaction
+	// is refused. A MaxFee of 0 accepts any fee.
+	MaxFee int64
+}
+
 // Configuration determines how
 type Configuration struct {
 	Mode                   Mode
@@ -120,6 +167,7 @@ type Configuration struct {
 	RPCPort                int
 	ConfigPath             string
 	Pruning                *PruningConfiguration
+	Construction           *ConstructionConfiguration
 	IndexerPath            string
 	BitcoindPath           string
 	Compressors            []*encoder.CompressorEntry
@@ -0,0 +1,154 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dogecoin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// hash160Size is the size of the hash
+// encoded in a base58 address.
+const hash160Size = 20
+
+// addressNetwork is the address encoding of a network
+// whose addresses are commonly confused with Dogecoin
+// addresses.
+type addressNetwork struct {
+	name             string
+	pubKeyHashAddrID byte
+	scriptHashAddrID byte
+	bech32HRP        string
+}
+
+// addressNetworks are the networks recognized by
+// DecodeAddress. Networks may share version bytes, in
+// which case all of them are reported.
+var addressNetworks = []*addressNetwork{
+	{
+		name:             "Dogecoin mainnet",
+		pubKeyHashAddrID: MainNetParams.PubKeyHashAddrID,
+		scriptHashAddrID: MainNetParams.ScriptHashAddrID,
+		bech32HRP:        MainNetParams.Bech32HRPSegwit,
+	},
+	{
+		name:             "Dogecoin testnet",
+		pubKeyHashAddrID: TestNet3Params.PubKeyHashAddrID,
+		scriptHashAddrID: TestNet3Params.ScriptHashAddrID,
+		bech32HRP:        TestNet3Params.Bech32HRPSegwit,
+	},
+	{
+		name:             "Bitcoin mainnet",
+		pubKeyHashAddrID: chaincfg.MainNetParams.PubKeyHashAddrID,
+		scriptHashAddrID: chaincfg.MainNetParams.ScriptHashAddrID,
+		bech32HRP:        chaincfg.MainNetParams.Bech32HRPSegwit,
+	},
+	{
+		name:             "Bitcoin testnet",
+		pubKeyHashAddrID: chaincfg.TestNet3Params.PubKeyHashAddrID,
+		scriptHashAddrID: chaincfg.TestNet3Params.ScriptHashAddrID,
+		bech32HRP:        chaincfg.TestNet3Params.Bech32HRPSegwit,
+	},
+	{
+		name:             "Litecoin mainnet",
+		pubKeyHashAddrID: 0x30, // starts with L
+		scriptHashAddrID: 0x32, // starts with M
+		bech32HRP:        "ltc",
+	},
+	{
+		name:             "Litecoin testnet",
+		pubKeyHashAddrID: 0x6f, // starts with m or n
+		scriptHashAddrID: 0x3a, // starts with Q
+		bech32HRP:        "tltc",
+	},
+}
+
+// ForeignAddressError is returned by DecodeAddress
+// for an address of another network.
+type ForeignAddressError struct {
+	Address string
+
+	// Networks are the networks the address may
+	// belong to, like "Bitcoin mainnet".
+	Networks []string
+}
+
+// Error implements the error interface.
+func (e *ForeignAddressError) Error() string {
+	return fmt.Sprintf("%s is a %s address", e.Address, strings.Join(e.Networks, " or "))
+}
+
+// DecodeAddress decodes an address of the network of params.
+// Addresses of the other Dogecoin network, of Bitcoin or of
+// Litecoin are refused with a *ForeignAddressError.
+func DecodeAddress(address string, params *chaincfg.Params) (btcutil.Address, error) {
+	addr, err := btcutil.DecodeAddress(address, params)
+	if err == nil && addr.IsForNet(params) {
+		return addr, nil
+	}
+
+	if networks := foreignNetworks(address, params); len(networks) > 0 {
+		return nil, &ForeignAddressError{
+			Address:  address,
+			Networks: networks,
+		}
+	}
+
+	// Segwit addresses are decoded for any
+	// network registered in chaincfg.
+	if err == nil {
+		err = fmt.Errorf("%s is not a %s address", address, params.Name)
+	}
+
+	return nil, err
+}
+
+// foreignNetworks returns the names of the networks other
+// than the one of params whose version bytes or human-readable
+// part match address.
+func foreignNetworks(address string, params *chaincfg.Params) []string {
+	networks := []string{}
+	if hrp, _, err := bech32.Decode(address); err == nil {
+		for _, network := range addressNetworks {
+			if network.bech32HRP == hrp && hrp != params.Bech32HRPSegwit {
+				networks = append(networks, network.name)
+			}
+		}
+
+		return networks
+	}
+
+	decoded, version, err := base58.CheckDecode(address)
+	if err != nil || len(decoded) != hash160Size {
+		return networks
+	}
+
+	for _, network := range addressNetworks {
+		if network.bech32HRP == params.Bech32HRPSegwit {
+			continue
+		}
+
+		if network.pubKeyHashAddrID == version || network.scriptHashAddrID == version {
+			networks = append(networks, network.name)
+		}
+	}
+
+	return networks
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dogecoin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeAddress(t *testing.T) {
+	tests := map[string]struct {
+		address string
+		params  *chaincfg.Params
+
+		networks []string
+		err      bool
+	}{
+		"mainnet p2pkh": {
+			address: "D5ERdEN1gsouFSs7zsq7VYJxyWP6dP28H1",
+			params:  MainnetParams,
+		},
+		"mainnet p2sh": {
+			address: "9rXbkMyi1S6thykRoXAZcY8fwUKYsy6cXE",
+			params:  MainnetParams,
+		},
+		"testnet p2pkh": {
+			address: "nUHVMF6vcrGd8RSK2hUZjwuGDNmPeNoBRb",
+			params:  TestnetParams,
+		},
+		"testnet segwit": {
+			address: "tdge1qqypqxpq9qcrsszg2pvxq6rs0zqg3yyc526p66m",
+			params:  TestnetParams,
+		},
+		"testnet p2pkh on mainnet": {
+			address:  "nUHVMF6vcrGd8RSK2hUZjwuGDNmPeNoBRb",
+			params:   MainnetParams,
+			networks: []string{"Dogecoin testnet"},
+		},
+		"testnet p2sh on mainnet": {
+			address:  "2MsLZ5FqqYpjM1Q1W4X81zMVZTF9gdbhVwd",
+			params:   MainnetParams,
+			networks: []string{"Dogecoin testnet", "Bitcoin testnet"},
+		},
+		"mainnet p2pkh on testnet": {
+			address:  "D5ERdEN1gsouFSs7zsq7VYJxyWP6dP28H1",
+			params:   TestnetParams,
+			networks: []string{"Dogecoin mainnet"},
+		},
+		"bitcoin p2pkh": {
+			address:  "16L5yRNPTuciSgXGHqYwn9N6NeoKqopAu",
+			params:   MainnetParams,
+			networks: []string{"Bitcoin mainnet"},
+		},
+		"bitcoin p2sh": {
+			address:  "31nM1WuowNDzocNxPPW9NQWJEtwWpjfcLj",
+			params:   MainnetParams,
+			networks: []string{"Bitcoin mainnet"},
+		},
+		"bitcoin segwit": {
+			address:  "bc1qqypqxpq9qcrsszg2pvxq6rs0zqg3yyc5fcj4z3",
+			params:   MainnetParams,
+			networks: []string{"Bitcoin mainnet"},
+		},
+		"bitcoin testnet p2pkh": {
+			address:  "mfcHP2WMCVLsVZA8yrovmhMgxNFW9r98xw",
+			params:   MainnetParams,
+			networks: []string{"Bitcoin testnet", "Litecoin testnet"},
+		},
+		"litecoin p2pkh": {
+			address:  "LKKHMBjCU89fyFNgSRprDoD8Jb25N8uWvd",
+			params:   TestnetParams,
+			networks: []string{"Litecoin mainnet"},
+		},
+		"litecoin segwit": {
+			address:  "ltc1qqypqxpq9qcrsszg2pvxq6rs0zqg3yyc5dyg36p",
+			params:   MainnetParams,
+			networks: []string{"Litecoin mainnet"},
+		},
+		"invalid checksum": {
+			address: "D5ERdEN1gsouFSs7zsq7VYJxyWP6dP28H2",
+			params:  MainnetParams,
+			err:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			addr, err := DecodeAddress(test.address, test.params)
+			var foreignErr *ForeignAddressError
+			switch {
+			case len(test.networks) > 0:
+				assert.Nil(t, addr)
+				assert.True(t, errors.As(err, &foreignErr))
+				assert.Equal(t, test.networks, foreignErr.Networks)
+			case test.err:
+				assert.Nil(t, addr)
+				assert.Error(t, err)
+				assert.False(t, errors.As(err, &foreignErr))
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, test.address, addr.EncodeAddress())
+			}
+		})
+	}
+}
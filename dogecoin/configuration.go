@@ -110,6 +110,7 @@ func LoadConfiguration(baseDirectory string) (*configuration.Configuration, erro
 		config.GenesisBlockIdentifier = MainnetGenesisBlockIdentifier
 		config.Params = MainnetParams
 		config.Currency = MainnetCurrency
+		config.Construction = MainnetConstruction
 		config.ConfigPath = mainnetConfigPath
 		config.RPCPort = mainnetRPCPort
 		config.Compressors = []*encoder.CompressorEntry{
@@ -126,6 +127,7 @@ func LoadConfiguration(baseDirectory string) (*configuration.Configuration, erro
 		config.GenesisBlockIdentifier = TestnetGenesisBlockIdentifier
 		config.Params = TestnetParams
 		config.Currency = TestnetCurrency
+		config.Construction = TestnetConstruction
 		config.ConfigPath = testnetConfigPath
 		config.RPCPort = testnetRPCPort
 		config.Compressors = []*encoder.CompressorEntry{
@@ -140,6 +142,50 @@ func LoadConfiguration(baseDirectory string) (*configuration.Configuration, erro
 		return nil, fmt.Errorf("%s is not a valid network", networkValue)
 	}
 
+	// The network defaults are shared, so the
+	// fee limits are set on a copy.
+	construction := *config.Construction
+	config.Construction = &construction
+
+	if maxFeeRateValue := os.Getenv(configuration.MaxFeeRateEnv); len(maxFeeRateValue) > 0 {
+		maxFeeRate, err := strconv.ParseInt(maxFeeRateValue, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse max fee rate %s", err, maxFeeRateValue)
+		}
+
+		if maxFeeRate < 0 {
+			return nil, fmt.Errorf("max fee rate %s must not be negative", maxFeeRateValue)
+		}
+
+		construction.MaxFeeRate = maxFeeRate
+	}
+
+	if maxFeeMultipleValue := os.Getenv(configuration.MaxFeeMultipleEnv); len(maxFeeMultipleValue) > 0 {
+		maxFeeMultiple, err := strconv.ParseFloat(maxFeeMultipleValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse max fee multiple %s", err, maxFeeMultipleValue)
+		}
+
+		if maxFeeMultiple < 0 {
+			return nil, fmt.Errorf("max fee multiple %s must not be negative", maxFeeMultipleValue)
+		}
+
+		construction.MaxFeeMultiple = maxFeeMultiple
+	}
+
+	if maxFeeValue := os.Getenv(configuration.MaxFeeEnv); len(maxFeeValue) > 0 {
+		maxFee, err := strconv.ParseInt(maxFeeValue, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse max fee %s", err, maxFeeValue)
+		}
+
+		if maxFee < 0 {
+			return nil, fmt.Errorf("max fee %s must not be negative", maxFeeValue)
+		}
+
+		construction.MaxFee = maxFee
+	}
+
 	portValue := os.Getenv(configuration.PortEnv)
 	if len(portValue) == 0 {
 		return nil, errors.New("PORT must be populated")
@@ -35,6 +35,10 @@ func TestLoadConfiguration(t *testing.T) {
 		Network string
 		Port    string
 
+		MaxFeeRate     string
+		MaxFeeMultiple string
+		MaxFee         string
+
 		cfg *configuration.Configuration
 		err error
 	}{
@@ -62,6 +66,7 @@ func TestLoadConfiguration(t *testing.T) {
 				},
 				Params:                 MainnetParams,
 				Currency:               MainnetCurrency,
+				Construction:           MainnetConstruction,
 				GenesisBlockIdentifier: MainnetGenesisBlockIdentifier,
 				Port:                   1000,
 				RPCPort:                mainnetRPCPort,
@@ -91,6 +96,7 @@ func TestLoadConfiguration(t *testing.T) {
 				},
 				Params:                 TestnetParams,
 				Currency:               TestnetCurrency,
+				Construction:           TestnetConstruction,
 				GenesisBlockIdentifier: TestnetGenesisBlockIdentifier,
 				Port:                   1000,
 				RPCPort:                testnetRPCPort,
@@ -108,6 +114,121 @@ func TestLoadConfiguration(t *testing.T) {
 				},
 			},
 		},
+		"max fee rate set": {
+			Mode:       string(configuration.Offline),
+			Network:    configuration.Mainnet,
+			Port:       "1000",
+			MaxFeeRate: "10000000",
+			cfg: &configuration.Configuration{
+				Mode: configuration.Offline,
+				Network: &types.NetworkIdentifier{
+					Network:    MainnetNetwork,
+					Blockchain: Blockchain,
+				},
+				Params:   MainnetParams,
+				Currency: MainnetCurrency,
+				Construction: &configuration.ConstructionConfiguration{
+					DustLimit:      HardDustLimit,
+					MinChange:      MinChange,
+					MaxFeeRate:     10000000,
+					MaxFeeMultiple: MaxFeeMultiple,
+				},
+				GenesisBlockIdentifier: MainnetGenesisBlockIdentifier,
+				Port:                   1000,
+				RPCPort:                mainnetRPCPort,
+				ConfigPath:             mainnetConfigPath,
+				Pruning: &configuration.PruningConfiguration{
+					Frequency: pruneFrequency,
+					Depth:     pruneDepth,
+					MinHeight: minPruneHeight,
+				},
+				Compressors: []*encoder.CompressorEntry{
+					{
+						Namespace:      transactionNamespace,
+						DictionaryPath: mainnetTransactionDictionary,
+					},
+				},
+			},
+		},
+		"invalid max fee rate": {
+			Mode:       string(configuration.Offline),
+			Network:    configuration.Mainnet,
+			Port:       "1000",
+			MaxFeeRate: "fast",
+			err:        errors.New("unable to parse max fee rate fast"),
+		},
+		"negative max fee rate": {
+			Mode:       string(configuration.Offline),
+			Network:    configuration.Mainnet,
+			Port:       "1000",
+			MaxFeeRate: "-1",
+			err:        errors.New("max fee rate -1 must not be negative"),
+		},
+		"max fee set": {
+			Mode:           string(configuration.Offline),
+			Network:        configuration.Testnet,
+			Port:           "1000",
+			MaxFeeMultiple: "2.5",
+			MaxFee:         "100000000",
+			cfg: &configuration.Configuration{
+				Mode: configuration.Offline,
+				Network: &types.NetworkIdentifier{
+					Network:    TestnetNetwork,
+					Blockchain: Blockchain,
+				},
+				Params:   TestnetParams,
+				Currency: TestnetCurrency,
+				Construction: &configuration.ConstructionConfiguration{
+					DustLimit:      HardDustLimit,
+					MinChange:      MinChange,
+					MaxFeeMultiple: 2.5,
+					MaxFee:         100000000,
+				},
+				GenesisBlockIdentifier: TestnetGenesisBlockIdentifier,
+				Port:                   1000,
+				RPCPort:                testnetRPCPort,
+				ConfigPath:             testnetConfigPath,
+				Pruning: &configuration.PruningConfiguration{
+					Frequency: pruneFrequency,
+					Depth:     pruneDepth,
+					MinHeight: minPruneHeight,
+				},
+				Compressors: []*encoder.CompressorEntry{
+					{
+						Namespace:      transactionNamespace,
+						DictionaryPath: testnetTransactionDictionary,
+					},
+				},
+			},
+		},
+		"invalid max fee multiple": {
+			Mode:           string(configuration.Offline),
+			Network:        configuration.Mainnet,
+			Port:           "1000",
+			MaxFeeMultiple: "many",
+			err:            errors.New("unable to parse max fee multiple many"),
+		},
+		"negative max fee multiple": {
+			Mode:           string(configuration.Offline),
+			Network:        configuration.Mainnet,
+			Port:           "1000",
+			MaxFeeMultiple: "-2.5",
+			err:            errors.New("max fee multiple -2.5 must not be negative"),
+		},
+		"invalid max fee": {
+			Mode:    string(configuration.Offline),
+			Network: configuration.Mainnet,
+			Port:    "1000",
+			MaxFee:  "lots",
+			err:     errors.New("unable to parse max fee lots"),
+		},
+		"negative max fee": {
+			Mode:    string(configuration.Offline),
+			Network: configuration.Mainnet,
+			Port:    "1000",
+			MaxFee:  "-1",
+			err:     errors.New("max fee -1 must not be negative"),
+		},
 		"invalid mode": {
 			Mode:    "bad mode",
 			Network: configuration.Testnet,
@@ -137,14 +258,19 @@ func TestLoadConfiguration(t *testing.T) {
 			os.Setenv(configuration.ModeEnv, test.Mode)
 			os.Setenv(configuration.NetworkEnv, test.Network)
 			os.Setenv(configuration.PortEnv, test.Port)
+			os.Setenv(configuration.MaxFeeRateEnv, test.MaxFeeRate)
+			os.Setenv(configuration.MaxFeeMultipleEnv, test.MaxFeeMultiple)
+			os.Setenv(configuration.MaxFeeEnv, test.MaxFee)
 
 			cfg, err := LoadConfiguration(newDir)
 			if test.err != nil {
 				assert.Nil(t, cfg)
 				assert.Contains(t, err.Error(), test.err.Error())
 			} else {
-				test.cfg.IndexerPath = path.Join(newDir, "indexer")
-				test.cfg.BitcoindPath = path.Join(newDir, "dogecoind")
+				if test.cfg.Mode == configuration.Online {
+					test.cfg.IndexerPath = path.Join(newDir, "indexer")
+					test.cfg.BitcoindPath = path.Join(newDir, "dogecoind")
+				}
 				assert.Equal(t, test.cfg, cfg)
 				assert.NoError(t, err)
 			}
@@ -0,0 +1,129 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dogecoin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	// MessageMagic is prepended to the messages signed
+	// by the signmessage RPC of Dogecoin Core.
+	MessageMagic = "Dogecoin Signed Message:\n"
+
+	// compactSignatureSize is the size of a compact
+	// recoverable signature: a header byte, R and S.
+	compactSignatureSize = 65
+
+	// compactHeader is the header byte of a compact
+	// signature with recovery id 0 of an uncompressed
+	// public key. Compressed public keys add 4.
+	compactHeader = 27
+
+	// compressedFlag is added to the header byte of the
+	// compact signature of a compressed public key.
+	compressedFlag = 4
+)
+
+// MessageDigest returns the double SHA-256 hash of message
+// prefixed with MessageMagic, like signmessage.
+func MessageDigest(message string) []byte {
+	var buf bytes.Buffer
+
+	// Writes to a bytes.Buffer cannot fail.
+	_ = wire.WriteVarString(&buf, 0, MessageMagic)
+	_ = wire.WriteVarString(&buf, 0, message)
+
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// EncodeMessageSignature returns the base64 encoding of the compact
+// signature, as returned by signmessage, of a 65-byte recoverable
+// signature laid out as R, S and a recovery id of 0 or 1.
+func EncodeMessageSignature(signature []byte, compressed bool) (string, error) {
+	if len(signature) != compactSignatureSize {
+		return "", fmt.Errorf("expected a signature of %d bytes, got %d", compactSignatureSize, len(signature))
+	}
+
+	recoveryID := signature[compactSignatureSize-1]
+	if recoveryID > 1 {
+		return "", fmt.Errorf("recovery id %d is not 0 or 1", recoveryID)
+	}
+
+	header := compactHeader + recoveryID
+	if compressed {
+		header += compressedFlag
+	}
+
+	compact := append([]byte{header}, signature[:compactSignatureSize-1]...)
+
+	return base64.StdEncoding.EncodeToString(compact), nil
+}
+
+// RecoverMessageSigner returns the P2PKH address of the public
+// key recovered from a base64 encoded compact signature of
+// message, in the format of the public key it encodes.
+func RecoverMessageSigner(
+	message string,
+	signature string,
+	params *chaincfg.Params,
+) (*btcutil.AddressPubKeyHash, error) {
+	compact, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed base64 encoding", err)
+	}
+
+	if len(compact) != compactSignatureSize {
+		return nil, fmt.Errorf("expected a signature of %d bytes, got %d", compactSignatureSize, len(compact))
+	}
+
+	publicKey, compressed, err := btcec.RecoverCompact(btcec.S256(), compact, MessageDigest(message))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to recover public key", err)
+	}
+
+	serialized := publicKey.SerializeUncompressed()
+	if compressed {
+		serialized = publicKey.SerializeCompressed()
+	}
+
+	return btcutil.NewAddressPubKeyHash(btcutil.Hash160(serialized), params)
+}
+
+// VerifyMessage returns true if signature is a base64 encoded
+// compact signature of message by the key hashed by address,
+// like verifymessage. It returns an error if the signature is
+// malformed.
+func VerifyMessage(
+	address *btcutil.AddressPubKeyHash,
+	message string,
+	signature string,
+	params *chaincfg.Params,
+) (bool, error) {
+	signer, err := RecoverMessageSigner(message, signature, params)
+	if err != nil {
+		return false, err
+	}
+
+	return signer.EncodeAddress() == address.EncodeAddress(), nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dogecoin
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// The signatures are the compact signatures of "Much wow"
+// by the private key 1.
+const (
+	compressedMessageSignature   = "IIApyBWkuRrzYCEY+2uZNOaDZouNpQN1J8GPJzA8dlY+AZkwU/o5yiWsXI+yEC6r2RwGjqfnTSGXn999zt6K0Qo="
+	uncompressedMessageSignature = "HIApyBWkuRrzYCEY+2uZNOaDZouNpQN1J8GPJzA8dlY+AZkwU/o5yiWsXI+yEC6r2RwGjqfnTSGXn999zt6K0Qo="
+)
+
+func TestMessageDigest(t *testing.T) {
+	assert.Equal(
+		t,
+		"dcd4f28661cd6d2aec48bd6d5b1a8f32f6b2ddc08fe049317b85efc28d516e12",
+		hex.EncodeToString(MessageDigest("Much wow")),
+	)
+}
+
+func TestEncodeMessageSignature(t *testing.T) {
+	// R || S || recovery id
+	signature, _ := hex.DecodeString(
+		"8029c815a4b91af3602118fb6b9934e683668b8da5037527c18f27303c76563e" +
+			"01993053fa39ca25ac5c8fb2102eabd91c068ea7e74d21979fdf7dcede8ad10a" +
+			"01",
+	)
+
+	encoded, err := EncodeMessageSignature(signature, true)
+	assert.NoError(t, err)
+	assert.Equal(t, compressedMessageSignature, encoded)
+
+	encoded, err = EncodeMessageSignature(signature, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uncompressedMessageSignature, encoded)
+
+	_, err = EncodeMessageSignature(signature[:64], true)
+	assert.Error(t, err)
+
+	signature[64] = 2
+	_, err = EncodeMessageSignature(signature, true)
+	assert.Error(t, err)
+}
+
+func TestVerifyMessage(t *testing.T) {
+	tests := map[string]struct {
+		address   string
+		message   string
+		signature string
+
+		valid bool
+		err   bool
+	}{
+		"compressed": {
+			address:   "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			message:   "Much wow",
+			signature: compressedMessageSignature,
+			valid:     true,
+		},
+		"uncompressed": {
+			address:   "nhUXqN5cYNPvLLzk6Tn8ZPowqkeJP7Qws2",
+			message:   "Much wow",
+			signature: uncompressedMessageSignature,
+			valid:     true,
+		},
+		"key format mismatch": {
+			address:   "nhUXqN5cYNPvLLzk6Tn8ZPowqkeJP7Qws2",
+			message:   "Much wow",
+			signature: compressedMessageSignature,
+		},
+		"other message": {
+			address:   "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			message:   "Such wow",
+			signature: compressedMessageSignature,
+		},
+		"malformed base64": {
+			address:   "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			message:   "Much wow",
+			signature: "not base64!",
+			err:       true,
+		},
+		"short signature": {
+			address:   "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			message:   "Much wow",
+			signature: "IIApyBWkuRrzYCEY",
+			err:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			addr, err := btcutil.DecodeAddress(test.address, TestnetParams)
+			assert.NoError(t, err)
+
+			valid, err := VerifyMessage(
+				addr.(*btcutil.AddressPubKeyHash),
+				test.message,
+				test.signature,
+				TestnetParams,
+			)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.valid, valid)
+		})
+	}
+}
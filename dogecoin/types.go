@@ -16,6 +16,8 @@
 package dogecoin
 
 import (
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/configuration"
+
 	"github.com/coinbase/rosetta-sdk-go/types"
 )
 
@@ -42,6 +44,48 @@ const (
 	// TransactionHashLength is the length
 	// of any transaction hash in Dogecoin.
 	TransactionHashLength = 64
+
+	// MaxStandardTxSize is the size in bytes above
+	// which a transaction is not relayed by Dogecoin
+	// Core (MAX_STANDARD_TX_SIZE).
+	MaxStandardTxSize = 100000
+)
+
+// Fee policy constants of Dogecoin Core 1.14
+// Source: https://github.com/dogecoin/dogecoin/blob/1.14.5/doc/fee-recommendation.md
+const (
+	// MinRelayFeeRate is the default minimum fee rate
+	// in Satoshis per kB for a transaction to be relayed
+	// (0.001 DOGE/kB).
+	MinRelayFeeRate = 100000
+
+	// RecommendedMinFeeRate is the recommended minimum
+	// fee rate in Satoshis per kB used by the wallet
+	// (0.01 DOGE/kB).
+	RecommendedMinFeeRate = 1000000
+
+	// FallbackFeeRate is the fee rate in Satoshis per kB
+	// used by the wallet when no fee estimate is available.
+	FallbackFeeRate = RecommendedMinFeeRate
+
+	// DustLimit is the amount in Satoshis below which an
+	// output is considered dust and is charged an extra
+	// fee (0.01 DOGE).
+	DustLimit = 1000000
+
+	// HardDustLimit is the amount in Satoshis below which
+	// an output is not relayed at all (0.001 DOGE).
+	HardDustLimit = 100000
+
+	// MinChange is the smallest change output in Satoshis
+	// created by the wallet (0.01 DOGE). Smaller change
+	// costs more in dust fees than it is worth.
+	MinChange = 1000000
+
+	// MaxFeeMultiple is the multiple of the suggested fee
+	// above which an unsigned transaction is refused, as
+	// its operations likely miss a change output.
+	MaxFeeMultiple = 10
 )
 
 var (
@@ -64,6 +108,14 @@ var (
 		Hash: "bb0a78264637406b6360aad926284d544d7049f45189db5664f3c4d07350559e",
 	}
 
+	// MainnetConstruction is the Construction API
+	// configuration for mainnet.
+	MainnetConstruction = &configuration.ConstructionConfiguration{
+		DustLimit:      HardDustLimit,
+		MinChange:      MinChange,
+		MaxFeeMultiple: MaxFeeMultiple,
+	}
+
 	// TestnetParams are the params for testnet.
 	TestnetParams = &TestNet3Params
 
@@ -72,4 +124,12 @@ var (
 		Symbol:   "DOGETEST",
 		Decimals: Decimals,
 	}
+
+	// TestnetConstruction is the Construction API
+	// configuration for testnet.
+	TestnetConstruction = &configuration.ConstructionConfiguration{
+		DustLimit:      HardDustLimit,
+		MinChange:      MinChange,
+		MaxFeeMultiple: MaxFeeMultiple,
+	}
 )
@@ -6,6 +6,7 @@ require (
 	github.com/DataDog/zstd v1.4.8 // indirect
 	github.com/btcsuite/btcd v0.21.0-beta
 	github.com/btcsuite/btcutil v1.0.2
+	github.com/btcsuite/btcutil/psbt v1.0.2
 	github.com/coinbase/rosetta-sdk-go v0.6.10
 	github.com/dgraph-io/badger/v2 v2.2007.2
 	github.com/ethereum/go-ethereum v1.10.3 // indirect
@@ -17,6 +17,7 @@ package indexer
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"runtime"
@@ -28,6 +29,8 @@ import (
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/services"
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/utils"
 
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
 	"github.com/coinbase/rosetta-sdk-go/asserter"
 	"github.com/coinbase/rosetta-sdk-go/storage/database"
 	storageErrs "github.com/coinbase/rosetta-sdk-go/storage/errors"
@@ -71,6 +74,10 @@ const (
 
 	// semaphoreWeight is the weight of each semaphore request.
 	semaphoreWeight = int64(1)
+
+	// redeemScriptNamespace prefixes the keys of the
+	// redeem scripts recorded by AddRedeemScripts.
+	redeemScriptNamespace = "redeem-script"
 )
 
 var (
@@ -862,6 +869,15 @@ func (i *Indexer) GetScriptPubKeys(
 			break
 		}
 
+		if scripts[j] != nil {
+			redeemScript, err := i.findRedeemScript(ctx, scripts[j], databaseTransaction)
+			if err != nil {
+				return nil, fmt.Errorf("%w: unable to find redeem script", err)
+			}
+
+			scripts[j].RedeemScript = redeemScript
+		}
+
 		if scripts[j] == nil {
 			return nil, fmt.Errorf("unable to find script for coin %s", coinIdentifier.Identifier)
 		}
@@ -870,6 +886,125 @@ func (i *Indexer) GetScriptPubKeys(
 	return scripts, nil
 }
 
+// GetRawTransactions gets the serialized transactions
+// creating a collection of *types.Coin. The blocks
+// containing the transactions are fetched from the node.
+func (i *Indexer) GetRawTransactions(
+	ctx context.Context,
+	coins []*types.Coin,
+) ([]string, error) {
+	databaseTransaction := i.database.ReadTransaction(ctx)
+	defer databaseTransaction.Discard(ctx)
+
+	blocks := map[string]*bitcoin.Block{}
+	rawTransactions := make([]string, len(coins))
+	for j, coin := range coins {
+		transactionHash, _, err := bitcoin.ParseCoinIdentifier(coin.CoinIdentifier)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to parse coin identifier", err)
+		}
+
+		blockIdentifier, _, err := i.blockStorage.FindTransaction(
+			ctx,
+			&types.TransactionIdentifier{Hash: transactionHash.String()},
+			databaseTransaction,
+		)
+		if err != nil || blockIdentifier == nil {
+			return nil, fmt.Errorf(
+				"%w: unable to find transaction %s",
+				err,
+				transactionHash.String(),
+			)
+		}
+
+		btcBlock, ok := blocks[blockIdentifier.Hash]
+		if !ok {
+			btcBlock, _, err = i.client.GetRawBlock(
+				ctx,
+				&types.PartialBlockIdentifier{Hash: &blockIdentifier.Hash},
+			)
+			if err != nil {
+				return nil, fmt.Errorf("%w: unable to get raw block %s", err, blockIdentifier.Hash)
+			}
+
+			blocks[blockIdentifier.Hash] = btcBlock
+		}
+
+		for _, tx := range btcBlock.Txs {
+			if tx.Hash == transactionHash.String() {
+				rawTransactions[j] = tx.Hex
+				break
+			}
+		}
+
+		if len(rawTransactions[j]) == 0 {
+			return nil, fmt.Errorf(
+				"unable to find raw transaction %s in block %s",
+				transactionHash.String(),
+				blockIdentifier.Hash,
+			)
+		}
+	}
+
+	return rawTransactions, nil
+}
+
+// redeemScriptKey returns the key of the redeem
+// script with hash scriptHash.
+func redeemScriptKey(scriptHash []byte) []byte {
+	return []byte(fmt.Sprintf("%s/%x", redeemScriptNamespace, scriptHash))
+}
+
+// AddRedeemScripts records a collection of redeem scripts,
+// so GetScriptPubKeys can return the redeem script of
+// coins paying to their P2SH address.
+func (i *Indexer) AddRedeemScripts(ctx context.Context, redeemScripts [][]byte) error {
+	dbTx := i.database.WriteTransaction(ctx, redeemScriptNamespace, false)
+	defer dbTx.Discard(ctx)
+
+	for _, redeemScript := range redeemScripts {
+		key := redeemScriptKey(btcutil.Hash160(redeemScript))
+		if err := dbTx.Set(ctx, key, redeemScript, true); err != nil {
+			return fmt.Errorf("%w: unable to store redeem script", err)
+		}
+	}
+
+	if err := dbTx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: unable to commit redeem scripts", err)
+	}
+
+	return nil
+}
+
+// findRedeemScript returns the hex-encoded redeem script
+// of a P2SH script or an empty string if it is unknown.
+func (i *Indexer) findRedeemScript(
+	ctx context.Context,
+	script *bitcoin.ScriptPubKey,
+	dbTx database.Transaction,
+) (string, error) {
+	rawScript, err := hex.DecodeString(script.Hex)
+	if err != nil || txscript.GetScriptClass(rawScript) != txscript.ScriptHashTy {
+		return "", nil
+	}
+
+	pushes, err := txscript.PushedData(rawScript)
+	if err != nil || len(pushes) != 1 {
+		return "", nil
+	}
+
+	exists, redeemScript, err := dbTx.Get(ctx, redeemScriptKey(pushes[0]))
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		return "", nil
+	}
+
+	return hex.EncodeToString(redeemScript), nil
+}
+
 // GetBlockLazy returns a *types.BlockResponse from the indexer's block storage.
 // All transactions in a block must be fetched individually.
 func (i *Indexer) GetBlockLazy(
@@ -18,6 +18,7 @@ package indexer
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -29,6 +30,8 @@ import (
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
 	mocks "github.com/rosetta-dogecoin/rosetta-dogecoin/mocks/indexer"
 
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
 	"github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/coinbase/rosetta-sdk-go/utils"
 	"github.com/stretchr/testify/assert"
@@ -252,6 +255,10 @@ func TestIndexer_Transactions(t *testing.T) {
 		Account *types.AccountIdentifier
 	}
 
+	// One coin pays to the P2SH address of redeemScript.
+	redeemScript := []byte{txscript.OP_TRUE}
+	scriptHashCoin := fmt.Sprintf("%x:%d", sha256.Sum256([]byte("block 5 transaction 3")), index0)
+
 	coinBank := map[string]*coinBankEntry{}
 	for i := int64(0); i <= 1000; i++ {
 		identifier := &types.BlockIdentifier{
@@ -275,6 +282,10 @@ func TestIndexer_Transactions(t *testing.T) {
 			scriptPubKey := &bitcoin.ScriptPubKey{
 				ASM: coinIdentifier,
 			}
+			if coinIdentifier == scriptHashCoin {
+				scriptPubKey.Hex = fmt.Sprintf("a914%x87", btcutil.Hash160(redeemScript))
+				scriptPubKey.Type = "scripthash"
+			}
 			marshal, err := types.MarshalMap(scriptPubKey)
 			assert.NoError(t, err)
 			tx := &types.Transaction{
@@ -419,6 +430,49 @@ func TestIndexer_Transactions(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, expectedPubKeys, pubKeys)
 
+				// Ensure raw transactions are fetched
+				// from the blocks containing them.
+				rawHash := fmt.Sprintf("%x", sha256.Sum256([]byte("block 5 transaction 2")))
+				blockHash := getBlockHash(5)
+				mockClient.On(
+					"GetRawBlock",
+					ctx,
+					&types.PartialBlockIdentifier{Hash: &blockHash},
+				).Return(
+					&bitcoin.Block{
+						Hash: blockHash,
+						Txs: []*bitcoin.Transaction{
+							{Hash: rawHash, Hex: "0100"},
+						},
+					},
+					[]string{},
+					nil,
+				).Once()
+				rawTransactions, err := i.GetRawTransactions(ctx, []*types.Coin{
+					{
+						CoinIdentifier: &types.CoinIdentifier{Identifier: rawHash + ":0"},
+					},
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"0100"}, rawTransactions)
+
+				// Ensure recorded redeem scripts are
+				// returned with their ScriptPubKey.
+				scriptHashCoins := []*types.Coin{
+					{
+						CoinIdentifier: &types.CoinIdentifier{Identifier: scriptHashCoin},
+						Amount: &types.Amount{
+							Value:    fmt.Sprintf("-%s", coinBank[scriptHashCoin].Coin.Amount.Value),
+							Currency: dogecoin.TestnetCurrency,
+						},
+					},
+				}
+				assert.NoError(t, i.AddRedeemScripts(ctx, [][]byte{redeemScript}))
+				pubKeys, err = i.GetScriptPubKeys(ctx, scriptHashCoins)
+				assert.NoError(t, err)
+				assert.Equal(t, coinBank[scriptHashCoin].Script.Hex, pubKeys[0].Hex)
+				assert.Equal(t, hex.EncodeToString(redeemScript), pubKeys[0].RedeemScript)
+
 				cancel()
 				close(waitForFinish)
 				return
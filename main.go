@@ -158,7 +158,7 @@ func main() {
 		bitcoin.OperationTypes,
 		services.HistoricalBalanceLookup,
 		[]*types.NetworkIdentifier{cfg.Network},
-		nil,
+		services.CallMethods,
 		services.MempoolCoins,
 	)
 	if err != nil {
@@ -5,6 +5,8 @@ package services
 import (
 	context "context"
 
+	bitcoin "github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
+
 	mock "github.com/stretchr/testify/mock"
 
 	types "github.com/coinbase/rosetta-sdk-go/types"
@@ -38,6 +40,29 @@ func (_m *Client) GetPeers(_a0 context.Context) ([]*types.Peer, error) {
 	return r0, r1
 }
 
+// MempoolEntry provides a mock function with given fields: _a0, _a1
+func (_m *Client) MempoolEntry(_a0 context.Context, _a1 string) (*bitcoin.MempoolEntry, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *bitcoin.MempoolEntry
+	if rf, ok := ret.Get(0).(func(context.Context, string) *bitcoin.MempoolEntry); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bitcoin.MempoolEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RawMempool provides a mock function with given fields: _a0
 func (_m *Client) RawMempool(_a0 context.Context) ([]string, error) {
 	ret := _m.Called(_a0)
@@ -61,6 +86,29 @@ func (_m *Client) RawMempool(_a0 context.Context) ([]string, error) {
 	return r0, r1
 }
 
+// RawTransaction provides a mock function with given fields: _a0, _a1
+func (_m *Client) RawTransaction(_a0 context.Context, _a1 string) (*bitcoin.Transaction, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *bitcoin.Transaction
+	if rf, ok := ret.Get(0).(func(context.Context, string) *bitcoin.Transaction); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bitcoin.Transaction)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SendRawTransaction provides a mock function with given fields: _a0, _a1
 func (_m *Client) SendRawTransaction(_a0 context.Context, _a1 string) (string, error) {
 	ret := _m.Called(_a0, _a1)
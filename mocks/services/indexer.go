@@ -17,6 +17,20 @@ type Indexer struct {
 	mock.Mock
 }
 
+// AddRedeemScripts provides a mock function with given fields: _a0, _a1
+func (_m *Indexer) AddRedeemScripts(_a0 context.Context, _a1 [][]byte) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, [][]byte) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetBalance provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *Indexer) GetBalance(_a0 context.Context, _a1 *types.AccountIdentifier, _a2 *types.Currency, _a3 *types.PartialBlockIdentifier) (*types.Amount, *types.BlockIdentifier, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
@@ -127,6 +141,29 @@ func (_m *Indexer) GetCoins(_a0 context.Context, _a1 *types.AccountIdentifier) (
 	return r0, r1, r2
 }
 
+// GetRawTransactions provides a mock function with given fields: _a0, _a1
+func (_m *Indexer) GetRawTransactions(_a0 context.Context, _a1 []*types.Coin) ([]string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, []*types.Coin) []string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*types.Coin) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetScriptPubKeys provides a mock function with given fields: _a0, _a1
 func (_m *Indexer) GetScriptPubKeys(_a0 context.Context, _a1 []*types.Coin) ([]*bitcoin.ScriptPubKey, error) {
 	ret := _m.Called(_a0, _a1)
@@ -0,0 +1,423 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/configuration"
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// batchPlanner splits the recipients of a batch payout
+// into transactions funded by the coins of an account.
+type batchPlanner struct {
+	selector *coinSelector
+	strategy string
+	maxSize  int64
+
+	// outputs are the OUTPUT operations paying the
+	// recipients and outputSizes their sizes.
+	outputs     []*types.Operation
+	outputSizes []int64
+	amounts     []int64
+
+	// accountCoins are the coins of the account and
+	// accountAmounts their amounts.
+	accountCoins   []*types.Coin
+	accountAmounts []int64
+
+	// coins are the indexes of the accountCoins not
+	// spent by a transaction and coinAmounts their
+	// amounts.
+	coins       []int
+	coinAmounts []int64
+}
+
+// selectorFor returns the coin selector of
+// the transaction paying recipients.
+func (p *batchPlanner) selectorFor(recipients []int) *coinSelector {
+	selector := *p.selector
+	selector.baseSize = int64(bitcoin.LegacyTransactionOverhead)
+	selector.outputs = make([]int64, len(recipients))
+	for i, recipient := range recipients {
+		selector.baseSize += p.outputSizes[recipient]
+		selector.outputs[i] = p.amounts[recipient]
+	}
+
+	return &selector
+}
+
+// plan selects the coins paying recipients with the
+// strategy of p. It returns the selection, as indexes
+// of p.coins, and the estimated size of the transaction.
+func (p *batchPlanner) plan(recipients []int) (*selectedCoins, int64, error) {
+	selector := p.selectorFor(recipients)
+	selection, err := selector.Select(p.strategy, p.coinAmounts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return selection, selector.size(len(selection.Indexes), selection.Change), nil
+}
+
+// fill returns the recipients, from next, paid by a
+// transaction of at most p.maxSize bytes, the selection
+// paying them and the size of the transaction. Coins are
+// selected largest first and only added when they no
+// longer fund the recipients, so the selection grows with
+// the transaction instead of being searched again for
+// every recipient.
+func (p *batchPlanner) fill(next int) ([]int, *selectedCoins, int64, error) {
+	order := make([]int, len(p.coinAmounts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return p.coinAmounts[order[i]] > p.coinAmounts[order[j]]
+	})
+
+	recipients := []int{}
+	var selection selectedCoins
+	var size int64
+	inputs := 0
+	total := int64(0)
+	for i := next; i < len(p.outputs); i++ {
+		candidate := append(recipients[:len(recipients):len(recipients)], i)
+		selector := p.selectorFor(candidate)
+
+		var change, fee int64
+		ok := false
+		if inputs > 0 {
+			change, fee, ok = selector.settle(inputs, total)
+		}
+		for !ok && inputs < len(order) {
+			total += p.coinAmounts[order[inputs]]
+			inputs++
+			change, fee, ok = selector.settle(inputs, total)
+		}
+		if !ok {
+			return recipients, nil, 0, fmt.Errorf(
+				"%w: %d coins cannot fund %d",
+				errInsufficientFunds,
+				len(order),
+				selector.target(),
+			)
+		}
+
+		candidateSize := selector.size(inputs, change)
+		if candidateSize > p.maxSize {
+			break
+		}
+
+		recipients = candidate
+		selection = selectedCoins{Indexes: order[:inputs], Change: change, Fee: fee}
+		size = candidateSize
+	}
+
+	if len(recipients) == 0 {
+		return recipients, nil, 0, nil
+	}
+
+	selection.Indexes = append([]int{}, selection.Indexes...)
+	sort.Ints(selection.Indexes)
+
+	return recipients, &selection, size, nil
+}
+
+// spend removes the coins of selection from p.coins and
+// returns their indexes in the coins of the account.
+func (p *batchPlanner) spend(selection *selectedCoins) []int {
+	selected := map[int]struct{}{}
+	spent := make([]int, len(selection.Indexes))
+	for i, index := range selection.Indexes {
+		selected[index] = struct{}{}
+		spent[i] = p.coins[index]
+	}
+
+	coins := []int{}
+	coinAmounts := []int64{}
+	for i, coin := range p.coins {
+		if _, ok := selected[i]; ok {
+			continue
+		}
+
+		coins = append(coins, coin)
+		coinAmounts = append(coinAmounts, p.coinAmounts[i])
+	}
+	p.coins = coins
+	p.coinAmounts = coinAmounts
+
+	return spent
+}
+
+// validateBatchPayout returns the OUTPUT operations
+// paying the recipients of params and their amounts.
+func (s *ConstructionAPIService) validateBatchPayout(
+	params *batchPayoutParams,
+) ([]*types.Operation, []int64, *types.Error) {
+	if params.From == nil || len(params.Recipients) == 0 {
+		return nil, nil, wrapErr(
+			ErrUnclearIntent,
+			errors.New("a batch payout requires an account and recipients"),
+		)
+	}
+
+	if err := validCoinSelection(params.CoinSelection); err != nil {
+		return nil, nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	if params.MaxSize < 0 || params.MaxSize > dogecoin.MaxStandardTxSize {
+		return nil, nil, wrapErr(ErrUnclearIntent, fmt.Errorf(
+			"max size %d is not between 0 and the standard size of %d",
+			params.MaxSize,
+			dogecoin.MaxStandardTxSize,
+		))
+	}
+
+	if _, rErr := s.decodeAddress(params.From.Address); rErr != nil {
+		return nil, nil, rErr
+	}
+
+	if len(params.ChangeAddress) > 0 {
+		if _, rErr := s.decodeAddress(params.ChangeAddress); rErr != nil {
+			return nil, nil, rErr
+		}
+	}
+
+	outputs := make([]*types.Operation, len(params.Recipients))
+	values := make([]string, len(params.Recipients))
+	for i, recipient := range params.Recipients {
+		if recipient == nil {
+			return nil, nil, wrapErr(ErrUnclearIntent, fmt.Errorf("recipient %d is missing", i))
+		}
+
+		outputs[i] = &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: int64(i),
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: recipient.Address,
+			},
+			Amount: &types.Amount{
+				Value:    recipient.Amount,
+				Currency: s.config.Currency,
+			},
+		}
+		values[i] = recipient.Amount
+	}
+
+	amounts, err := parseAmounts(values)
+	if err != nil {
+		return nil, nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	for i, amount := range amounts {
+		if amount <= 0 {
+			return nil, nil, wrapErr(
+				ErrUnclearIntent,
+				fmt.Errorf("amount %d of recipient %d is not positive", amount, i),
+			)
+		}
+	}
+
+	if rErr := s.validateAddresses(outputs); rErr != nil {
+		return nil, nil, rErr
+	}
+
+	if rErr := s.validateOutputs(outputs); rErr != nil {
+		return nil, nil, rErr
+	}
+
+	return outputs, amounts, nil
+}
+
+// batchPayout pays the recipients of params with the coins
+// of params.From. The recipients are paid in order by as few
+// transactions as fit the maximum size, each spending its own
+// coins and paying its change to params.ChangeAddress. The
+// coins of a transaction are selected largest first while
+// its recipients are added, and searched once more with
+// params.CoinSelection when they are all known.
+func (s *ConstructionAPIService) batchPayout(
+	ctx context.Context,
+	params *batchPayoutParams,
+) (*batchPayoutResult, *types.Error) {
+	if s.config.Mode != configuration.Online {
+		return nil, wrapErr(ErrUnavailableOffline, errors.New("coins cannot be selected offline"))
+	}
+
+	outputs, amounts, rErr := s.validateBatchPayout(params)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	feeRate, rErr := s.feeRate(ctx, &preprocessOptions{
+		FeeRate:       params.FeeRate,
+		FeeMultiplier: params.FeeMultiplier,
+	})
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	coins, coinAmounts, rErr := s.accountCoins(ctx, params.From)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	options := &preprocessOptions{
+		From:          params.From,
+		ChangeAddress: params.ChangeAddress,
+	}
+	if len(options.ChangeAddress) == 0 {
+		options.ChangeAddress = params.From.Address
+	}
+
+	// Selected coins and the change output are
+	// assumed to be of the class of their address.
+	planner := &batchPlanner{
+		selector: &coinSelector{
+			feePolicy: s.feePolicy,
+			feeRate:   feeRate,
+			inputSize: int64(s.addressInputSize(params.From.Address)),
+			changeSize: int64(s.outputSize(&types.Operation{
+				Type:    bitcoin.OutputOpType,
+				Account: &types.AccountIdentifier{Address: options.ChangeAddress},
+			})),
+			minChange: s.minChange(),
+		},
+		strategy:       params.CoinSelection,
+		maxSize:        params.MaxSize,
+		outputs:        outputs,
+		outputSizes:    make([]int64, len(outputs)),
+		amounts:        amounts,
+		accountCoins:   coins,
+		accountAmounts: coinAmounts,
+		coins:          make([]int, len(coins)),
+		coinAmounts:    coinAmounts,
+	}
+	if planner.maxSize == 0 {
+		planner.maxSize = dogecoin.MaxStandardTxSize
+	}
+	for i, output := range outputs {
+		planner.outputSizes[i] = int64(s.outputSize(output))
+	}
+	for i := range coins {
+		planner.coins[i] = i
+	}
+
+	result := &batchPayoutResult{Transactions: []*batchTransaction{}}
+	for next := 0; next < len(outputs); {
+		recipients, selection, size, err := planner.fill(next)
+		if errors.Is(err, errInsufficientFunds) {
+			recipient := next + len(recipients)
+			rErr := wrapErr(ErrInsufficientFunds, fmt.Errorf("%w: unable to pay recipient %d", err, recipient))
+			rErr.Details["recipient"] = recipient
+
+			return nil, rErr
+		}
+		if err != nil {
+			return nil, wrapErr(ErrUnclearIntent, err)
+		}
+
+		if len(recipients) == 0 {
+			rErr := wrapErr(ErrUnclearIntent, fmt.Errorf(
+				"recipient %d cannot be paid by a transaction of at most %d bytes",
+				next,
+				planner.maxSize,
+			))
+			rErr.Details["recipient"] = next
+
+			return nil, rErr
+		}
+
+		// The recipients of the transaction are known, so
+		// branch and bound searches their coins only once.
+		if planner.strategy == BranchAndBound {
+			planned, plannedSize, err := planner.plan(recipients)
+			if err == nil && plannedSize <= planner.maxSize {
+				selection, size = planned, plannedSize
+			}
+		}
+
+		transaction, rErr := s.batchTransaction(ctx, planner, options, recipients, selection, size)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		result.Transactions = append(result.Transactions, transaction)
+		next += len(recipients)
+	}
+
+	return result, nil
+}
+
+// batchTransaction returns the transaction paying recipients
+// with the coins of selection, which are no longer available
+// to the next transactions of planner.
+func (s *ConstructionAPIService) batchTransaction(
+	ctx context.Context,
+	planner *batchPlanner,
+	options *preprocessOptions,
+	recipients []int,
+	selection *selectedCoins,
+	size int64,
+) (*batchTransaction, *types.Error) {
+	outputs := make([]*types.Operation, len(recipients))
+	for i, recipient := range recipients {
+		outputs[i] = planner.outputs[recipient]
+	}
+
+	var metadata constructionMetadata
+	spendCoins(planner.accountCoins, planner.accountAmounts, planner.spend(selection), options, &metadata)
+	if selection.Change > 0 {
+		metadata.Change = s.changeOperation(options, len(outputs), selection.Change)
+	}
+
+	operations := selectedOperations(outputs, &metadata)
+	if rErr := s.validateOutputs(operations); rErr != nil {
+		return nil, rErr
+	}
+
+	scripts, rErr := s.scriptPubKeys(ctx, options)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	// The operations spend the selected coins, so
+	// ConstructionPayloads selects no more coins.
+	payloadsMetadata, err := types.MarshalMap(&constructionMetadata{
+		ScriptPubKeys: scripts,
+		SuggestedFee:  selection.Fee,
+	})
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	return &batchTransaction{
+		Operations: operations,
+		Metadata:   payloadsMetadata,
+		Recipients: recipients,
+		Fee:        selection.Fee,
+		Size:       size,
+	}, nil
+}
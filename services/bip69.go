@@ -0,0 +1,166 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// bip69InputOrder returns the order of the INPUT operations
+// sorted per BIP-69, by the hash of the previous transaction
+// in its displayed byte order and then by output index.
+func bip69InputOrder(inputs []*types.Operation) ([]int, error) {
+	outPoints := make([]wire.OutPoint, len(inputs))
+	for i, input := range inputs {
+		if input.CoinChange == nil {
+			return nil, errors.New("CoinChange cannot be nil")
+		}
+
+		hash, index, err := bitcoin.ParseCoinIdentifier(input.CoinChange.CoinIdentifier)
+		if err != nil {
+			return nil, err
+		}
+
+		outPoints[i] = wire.OutPoint{Hash: *hash, Index: index}
+	}
+
+	order := identityOrder(len(inputs))
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := outPoints[order[i]], outPoints[order[j]]
+		if c := bytes.Compare(reversed(a.Hash[:]), reversed(b.Hash[:])); c != 0 {
+			return c < 0
+		}
+
+		return a.Index < b.Index
+	})
+
+	return order, nil
+}
+
+// bip69OutputOrder returns the order of outputs sorted per
+// BIP-69, by amount and then by scriptPubKey.
+func bip69OutputOrder(outputs []*wire.TxOut) []int {
+	order := identityOrder(len(outputs))
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := outputs[order[i]], outputs[order[j]]
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+
+		return bytes.Compare(a.PkScript, b.PkScript) < 0
+	})
+
+	return order
+}
+
+// identityOrder returns the indexes from 0 to n-1.
+func identityOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+
+	return order
+}
+
+// reversed returns a reversed copy of b.
+func reversed(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+
+	return r
+}
+
+// sortInputs sorts the INPUT operations and the
+// per-input metadata of a transaction per BIP-69.
+func sortInputs(
+	inputs []*types.Operation,
+	amounts []*big.Int,
+	metadata *constructionMetadata,
+) error {
+	if len(metadata.ScriptPubKeys) != len(inputs) {
+		return fmt.Errorf("expected %d script pub keys, got %d", len(inputs), len(metadata.ScriptPubKeys))
+	}
+
+	if len(metadata.PreviousTransactions) > 0 && len(metadata.PreviousTransactions) != len(inputs) {
+		return fmt.Errorf(
+			"expected %d previous transactions, got %d",
+			len(inputs),
+			len(metadata.PreviousTransactions),
+		)
+	}
+
+	order, err := bip69InputOrder(inputs)
+	if err != nil {
+		return err
+	}
+
+	sortedInputs := make([]*types.Operation, len(order))
+	sortedAmounts := make([]*big.Int, len(order))
+	sortedScripts := make([]*bitcoin.ScriptPubKey, len(order))
+	sortedTransactions := make([]string, len(order))
+	for i, index := range order {
+		sortedInputs[i] = inputs[index]
+		sortedAmounts[i] = amounts[index]
+		sortedScripts[i] = metadata.ScriptPubKeys[index]
+		if len(metadata.PreviousTransactions) > 0 {
+			sortedTransactions[i] = metadata.PreviousTransactions[index]
+		}
+	}
+
+	copy(inputs, sortedInputs)
+	copy(amounts, sortedAmounts)
+	copy(metadata.ScriptPubKeys, sortedScripts)
+	if len(metadata.PreviousTransactions) > 0 {
+		copy(metadata.PreviousTransactions, sortedTransactions)
+	}
+
+	return nil
+}
+
+// restoreOperationOrder sets the index of the operations parsed
+// from a transaction sorted per BIP-69 to the index of the
+// operations it was built from and sorts them by index. The
+// first len(inputIndexes) operations are the inputs.
+func restoreOperationOrder(ops []*types.Operation, inputIndexes []int64, outputIndexes []int64) error {
+	if len(inputIndexes)+len(outputIndexes) != len(ops) {
+		return fmt.Errorf(
+			"expected %d operation indexes, got %d",
+			len(ops),
+			len(inputIndexes)+len(outputIndexes),
+		)
+	}
+
+	for i, index := range append(append([]int64{}, inputIndexes...), outputIndexes...) {
+		ops[i].OperationIdentifier.Index = index
+	}
+
+	sort.SliceStable(ops, func(i, j int) bool {
+		return ops[i].OperationIdentifier.Index < ops[j].OperationIdentifier.Index
+	})
+
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBIP69InputOrder(t *testing.T) {
+	coins := []string{
+		"e53e6b9a7c2c6f2b0e0d6f54b35e4e6f6d7a4bb0b2a5c43bb4ef2b53e2a32e2f:1",
+		"0e53ec5dfb2cb8a71fec32dc9a634a35b7e24799295ddd5278217822e0b31f57:1",
+		"0e53ec5dfb2cb8a71fec32dc9a634a35b7e24799295ddd5278217822e0b31f57:0",
+		"26aa6e6d8b9e49bb0630aac301db6757c02e3619feb4ee0eea81eb1672947024:1",
+	}
+
+	inputs := make([]*types.Operation, len(coins))
+	for i, coin := range coins {
+		inputs[i] = &types.Operation{
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{Identifier: coin},
+				CoinAction:     types.CoinSpent,
+			},
+		}
+	}
+
+	order, err := bip69InputOrder(inputs)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 1, 3, 0}, order)
+
+	inputs[0].CoinChange = nil
+	_, err = bip69InputOrder(inputs)
+	assert.Error(t, err)
+}
+
+func TestBIP69OutputOrder(t *testing.T) {
+	outputs := []*wire.TxOut{
+		{Value: 2000, PkScript: []byte{0x76, 0xa9, 0x02}},
+		{Value: 1000, PkScript: []byte{0x76, 0xa9, 0x02}},
+		{Value: 2000, PkScript: []byte{0x76, 0xa9, 0x01}},
+		{Value: 400, PkScript: []byte{0xa9, 0x00}},
+	}
+
+	assert.Equal(t, []int{3, 1, 2, 0}, bip69OutputOrder(outputs))
+}
+
+func TestRestoreOperationOrder(t *testing.T) {
+	ops := make([]*types.Operation, 4)
+	for i := range ops {
+		networkIndex := int64(i)
+		ops[i] = &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index:        int64(i),
+				NetworkIndex: &networkIndex,
+			},
+		}
+	}
+
+	assert.Error(t, restoreOperationOrder(ops, []int64{1}, []int64{0}))
+
+	assert.NoError(t, restoreOperationOrder(ops, []int64{1, 0}, []int64{3, 2}))
+	for i, op := range ops {
+		assert.Equal(t, int64(i), op.OperationIdentifier.Index)
+	}
+	assert.Equal(t, int64(1), *ops[0].OperationIdentifier.NetworkIndex)
+	assert.Equal(t, int64(0), *ops[1].OperationIdentifier.NetworkIndex)
+	assert.Equal(t, int64(3), *ops[2].OperationIdentifier.NetworkIndex)
+	assert.Equal(t, int64(2), *ops[3].OperationIdentifier.NetworkIndex)
+}
@@ -0,0 +1,254 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/configuration"
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// MessageDigestMethod returns the payload signing
+	// a message with the key of an address.
+	MessageDigestMethod = "message_digest"
+
+	// MessageSignatureMethod returns the base64 encoded
+	// signature of a message, like signmessage, from the
+	// signature of the payload of MessageDigestMethod.
+	MessageSignatureMethod = "message_signature"
+
+	// VerifyMessageMethod verifies the base64 encoded
+	// signature of a message, like verifymessage.
+	VerifyMessageMethod = "verify_message"
+
+	// BatchPayoutMethod selects the coins of an account
+	// paying a list of recipients and returns the operations
+	// and the metadata of the ConstructionPayloadsRequest of
+	// each transaction. The recipients are split across
+	// transactions that would exceed the standard size.
+	// It requires a node.
+	//
+	// It is a /call planner rather than a preprocess option
+	// on purpose: the Construction API builds one transaction
+	// per intent, and a batch may need several transactions.
+	// Each planned transaction skips preprocess and metadata
+	// and is built from /construction/payloads on.
+	BatchPayoutMethod = "batch_payout"
+)
+
+// CallMethods are the methods supported by /call.
+var CallMethods = []string{
+	MessageDigestMethod,
+	MessageSignatureMethod,
+	VerifyMessageMethod,
+	BatchPayoutMethod,
+}
+
+// CallAPIService implements the server.CallAPIServicer interface.
+type CallAPIService struct {
+	config *configuration.Configuration
+
+	// construction selects the coins of
+	// BatchPayoutMethod.
+	construction *ConstructionAPIService
+}
+
+// NewCallAPIService creates a new instance of a CallAPIService.
+func NewCallAPIService(
+	config *configuration.Configuration,
+	client Client,
+	i Indexer,
+	feePolicy FeePolicy,
+) server.CallAPIServicer {
+	return &CallAPIService{
+		config: config,
+		construction: &ConstructionAPIService{
+			config:    config,
+			client:    client,
+			i:         i,
+			feePolicy: feePolicy,
+		},
+	}
+}
+
+// Call implements the /call endpoint.
+func (s *CallAPIService) Call(
+	ctx context.Context,
+	request *types.CallRequest,
+) (*types.CallResponse, *types.Error) {
+	var result interface{}
+	var rErr *types.Error
+	idempotent := true
+	switch request.Method {
+	case MessageDigestMethod:
+		result, rErr = s.messageDigest(request.Parameters)
+	case MessageSignatureMethod:
+		result, rErr = s.messageSignature(request.Parameters)
+	case VerifyMessageMethod:
+		result, rErr = s.verifyMessage(request.Parameters)
+	case BatchPayoutMethod:
+		// The coins and the fee rate change over time.
+		idempotent = false
+		result, rErr = s.batchPayout(ctx, request.Parameters)
+	default:
+		return nil, wrapErr(ErrUnimplemented, fmt.Errorf("method %s is not supported", request.Method))
+	}
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	resultMap, err := types.MarshalMap(result)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	return &types.CallResponse{
+		Result:     resultMap,
+		Idempotent: idempotent,
+	}, nil
+}
+
+// messageSigner returns the P2PKH address signing
+// messages. Like signmessage, other addresses cannot
+// sign messages.
+func (s *CallAPIService) messageSigner(address string) (*btcutil.AddressPubKeyHash, *types.Error) {
+	addr, rErr := decodeNetworkAddress(address, s.config.Params)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	pubKeyHash, ok := addr.(*btcutil.AddressPubKeyHash)
+	if !ok {
+		return nil, wrapErr(
+			ErrUnsupportedScriptType,
+			fmt.Errorf("%s is not a P2PKH address and cannot sign messages", address),
+		)
+	}
+
+	return pubKeyHash, nil
+}
+
+// messageDigest returns the payload of the digest of a
+// message, to be signed by the key of the address.
+func (s *CallAPIService) messageDigest(parameters map[string]interface{}) (*messageDigestResult, *types.Error) {
+	var params messageParams
+	if err := types.UnmarshalMap(parameters, &params); err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	if _, rErr := s.messageSigner(params.Address); rErr != nil {
+		return nil, rErr
+	}
+
+	return &messageDigestResult{
+		Payload: &types.SigningPayload{
+			AccountIdentifier: &types.AccountIdentifier{
+				Address: params.Address,
+			},
+			Bytes:         dogecoin.MessageDigest(params.Message),
+			SignatureType: types.EcdsaRecovery,
+		},
+	}, nil
+}
+
+// messageSignature returns the base64 encoded compact
+// signature of a message from the recoverable signature
+// of its digest. The format of the public key recorded
+// in the compact signature is the one hashed by the
+// address.
+func (s *CallAPIService) messageSignature(
+	parameters map[string]interface{},
+) (*messageSignatureResult, *types.Error) {
+	var params messageSignatureParams
+	if err := types.UnmarshalMap(parameters, &params); err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	signer, rErr := s.messageSigner(params.Address)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	if params.Signature == nil || params.Signature.SignatureType != types.EcdsaRecovery {
+		return nil, wrapErr(
+			ErrInvalidMessageSignature,
+			fmt.Errorf("expected a %s signature", types.EcdsaRecovery),
+		)
+	}
+
+	for _, compressed := range []bool{true, false} {
+		signature, err := dogecoin.EncodeMessageSignature(params.Signature.Bytes, compressed)
+		if err != nil {
+			return nil, wrapErr(ErrInvalidMessageSignature, err)
+		}
+
+		valid, err := dogecoin.VerifyMessage(signer, params.Message, signature, s.config.Params)
+		if err != nil {
+			return nil, wrapErr(ErrInvalidMessageSignature, err)
+		}
+
+		if valid {
+			return &messageSignatureResult{Signature: signature}, nil
+		}
+	}
+
+	return nil, wrapErr(
+		ErrPublicKeyMismatch,
+		errors.New("the message is not signed by the key of the address"),
+	)
+}
+
+// verifyMessage verifies the base64 encoded compact
+// signature of a message against an address.
+func (s *CallAPIService) verifyMessage(parameters map[string]interface{}) (*verifyMessageResult, *types.Error) {
+	var params verifyMessageParams
+	if err := types.UnmarshalMap(parameters, &params); err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	signer, rErr := s.messageSigner(params.Address)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	valid, err := dogecoin.VerifyMessage(signer, params.Message, params.Signature, s.config.Params)
+	if err != nil {
+		return nil, wrapErr(ErrInvalidMessageSignature, err)
+	}
+
+	return &verifyMessageResult{Valid: valid}, nil
+}
+
+// batchPayout returns the transactions paying the
+// recipients of a batch payout.
+func (s *CallAPIService) batchPayout(
+	ctx context.Context,
+	parameters map[string]interface{},
+) (*batchPayoutResult, *types.Error) {
+	var params batchPayoutParams
+	if err := types.UnmarshalMap(parameters, &params); err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	return s.construction.batchPayout(ctx, &params)
+}
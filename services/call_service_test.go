@@ -0,0 +1,488 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/configuration"
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
+	mocks "github.com/rosetta-dogecoin/rosetta-dogecoin/mocks/services"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// signRecoverable signs a *types.SigningPayload and returns
+// the signature in the R || S || V form of ecdsa_recovery.
+func signRecoverable(t *testing.T, privKey *btcec.PrivateKey, payload *types.SigningPayload) []byte {
+	compact, err := btcec.SignCompact(btcec.S256(), privKey, payload.Bytes, true)
+	if err != nil {
+		t.Fatalf("could not sign payload %x", payload.Bytes)
+	}
+
+	// The header of a compact signature of a
+	// compressed key is 31 plus the recovery id.
+	return append(compact[1:], compact[0]-31) // nolint:gomnd
+}
+
+func TestCallService_SignedMessage(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Offline,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	servicer := NewCallAPIService(cfg, nil, nil, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	otherKey, _ := forcePrivateKey(2)
+
+	tests := map[string]struct {
+		address string
+		signer  *btcec.PrivateKey
+
+		expectedSignature string
+		expectedErr       *types.Error
+	}{
+		"compressed": {
+			address:           "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			signer:            privKey,
+			expectedSignature: "IIApyBWkuRrzYCEY+2uZNOaDZouNpQN1J8GPJzA8dlY+AZkwU/o5yiWsXI+yEC6r2RwGjqfnTSGXn999zt6K0Qo=",
+		},
+		"uncompressed": {
+			address:           "nhUXqN5cYNPvLLzk6Tn8ZPowqkeJP7Qws2",
+			signer:            privKey,
+			expectedSignature: "HIApyBWkuRrzYCEY+2uZNOaDZouNpQN1J8GPJzA8dlY+AZkwU/o5yiWsXI+yEC6r2RwGjqfnTSGXn999zt6K0Qo=",
+		},
+		"other key": {
+			address:     "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			signer:      otherKey,
+			expectedErr: ErrPublicKeyMismatch,
+		},
+		"p2sh": {
+			address:     "2MsLZ5FqqYpjM1Q1W4X81zMVZTF9gdbhVwd",
+			expectedErr: ErrUnsupportedScriptType,
+		},
+		"mainnet": {
+			address:     "DFpN6QqFfUm3gKNaxN6tNcab1FArL9cZLE",
+			expectedErr: ErrForeignAddress,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			digestResponse, err := servicer.Call(ctx, &types.CallRequest{
+				NetworkIdentifier: networkIdentifier,
+				Method:            MessageDigestMethod,
+				Parameters: map[string]interface{}{
+					"address": test.address,
+					"message": "Much wow",
+				},
+			})
+			if test.expectedErr != nil && test.signer == nil {
+				assert.Nil(t, digestResponse)
+				assert.Equal(t, test.expectedErr.Code, err.Code)
+				return
+			}
+			assert.Nil(t, err)
+			assert.True(t, digestResponse.Idempotent)
+
+			var digest messageDigestResult
+			assert.NoError(t, types.UnmarshalMap(digestResponse.Result, &digest))
+			assert.Equal(t, &types.SigningPayload{
+				AccountIdentifier: &types.AccountIdentifier{Address: test.address},
+				Bytes:             dogecoin.MessageDigest("Much wow"),
+				SignatureType:     types.EcdsaRecovery,
+			}, digest.Payload)
+
+			signatureResponse, err := servicer.Call(ctx, &types.CallRequest{
+				NetworkIdentifier: networkIdentifier,
+				Method:            MessageSignatureMethod,
+				Parameters: forceMarshalMap(t, &messageSignatureParams{
+					Address: test.address,
+					Message: "Much wow",
+					Signature: &types.Signature{
+						SigningPayload: digest.Payload,
+						PublicKey:      publicKey,
+						SignatureType:  types.EcdsaRecovery,
+						Bytes:          signRecoverable(t, test.signer, digest.Payload),
+					},
+				}),
+			})
+			if test.expectedErr != nil {
+				assert.Nil(t, signatureResponse)
+				assert.Equal(t, test.expectedErr.Code, err.Code)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]interface{}{
+				"signature": test.expectedSignature,
+			}, signatureResponse.Result)
+
+			for message, valid := range map[string]bool{"Much wow": true, "Such wow": false} {
+				verifyResponse, err := servicer.Call(ctx, &types.CallRequest{
+					NetworkIdentifier: networkIdentifier,
+					Method:            VerifyMessageMethod,
+					Parameters: map[string]interface{}{
+						"address":   test.address,
+						"message":   message,
+						"signature": test.expectedSignature,
+					},
+				})
+				assert.Nil(t, err)
+				assert.Equal(t, map[string]interface{}{"valid": valid}, verifyResponse.Result)
+			}
+		})
+	}
+
+	// A recoverable signature is required
+	signatureResponse, err := servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            MessageSignatureMethod,
+		Parameters: forceMarshalMap(t, &messageSignatureParams{
+			Address: "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			Message: "Much wow",
+			Signature: &types.Signature{
+				SigningPayload: &types.SigningPayload{Bytes: dogecoin.MessageDigest("Much wow")},
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+				Bytes:          make([]byte, 64), // nolint:gomnd
+			},
+		}),
+	})
+	assert.Nil(t, signatureResponse)
+	assert.Equal(t, ErrInvalidMessageSignature.Code, err.Code)
+
+	verifyResponse, err := servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            VerifyMessageMethod,
+		Parameters: map[string]interface{}{
+			"address":   "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2",
+			"message":   "Much wow",
+			"signature": "not base64!",
+		},
+	})
+	assert.Nil(t, verifyResponse)
+	assert.Equal(t, ErrInvalidMessageSignature.Code, err.Code)
+
+	callResponse, err := servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            "getbalance",
+	})
+	assert.Nil(t, callResponse)
+	assert.Equal(t, ErrUnimplemented.Code, err.Code)
+}
+
+func TestCallService_BatchPayout(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+		Construction: &configuration.ConstructionConfiguration{
+			DustLimit: dogecoin.HardDustLimit,
+			MinChange: dogecoin.MinChange,
+		},
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewCallAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	construction := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	sender := &types.AccountIdentifier{Address: "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"}
+	senderAddr, _ := btcutil.DecodeAddress(sender.Address, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	recipient := "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64"
+
+	accountCoins := make([]*types.Coin, 3)
+	for i := range accountCoins {
+		accountCoins[i] = &types.Coin{
+			CoinIdentifier: &types.CoinIdentifier{
+				Identifier: fmt.Sprintf("b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:%d", i),
+			},
+			Amount: &types.Amount{
+				Value:    "1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		}
+	}
+	scripts := []*bitcoin.ScriptPubKey{
+		{
+			Hex:          hex.EncodeToString(senderScript),
+			RequiredSigs: 1,
+			Type:         txscript.PubKeyHashTy.String(),
+			Addresses:    []string{sender.Address},
+		},
+	}
+
+	recipients := make([]*batchRecipient, 6)
+	for i := range recipients {
+		recipients[i] = &batchRecipient{Address: recipient, Amount: "300000000"}
+	}
+
+	// Each transaction of at most 300 bytes pays three
+	// recipients from a coin: 10 + 3 * 34 + 148 + 34 bytes.
+	mockIndexer.On("GetCoins", ctx, sender).Return(accountCoins, nil, nil).Once()
+	mockIndexer.On("GetScriptPubKeys", ctx, mock.Anything).Return(scripts, nil).Twice()
+	callResponse, err := servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            BatchPayoutMethod,
+		Parameters: forceMarshalMap(t, &batchPayoutParams{
+			From:       sender,
+			Recipients: recipients,
+			FeeRate:    dogecoin.RecommendedMinFeeRate,
+			MaxSize:    300,
+		}),
+	})
+	assert.Nil(t, err)
+	assert.False(t, callResponse.Idempotent)
+
+	var result batchPayoutResult
+	assert.NoError(t, types.UnmarshalMap(callResponse.Result, &result))
+	assert.Len(t, result.Transactions, 2)
+	for i, transaction := range result.Transactions {
+		assert.Equal(t, []int{3 * i, 3*i + 1, 3*i + 2}, transaction.Recipients)
+		assert.Equal(t, int64(dogecoin.RecommendedMinFeeRate), transaction.Fee)
+		assert.Equal(t, int64(294), transaction.Size)
+
+		// The coin, the recipients and the change
+		assert.Len(t, transaction.Operations, 5)
+		assert.Equal(t, bitcoin.InputOpType, transaction.Operations[0].Type)
+		assert.Equal(t, accountCoins[i].CoinIdentifier, transaction.Operations[0].CoinChange.CoinIdentifier)
+		assert.Equal(t, "-1000000000", transaction.Operations[0].Amount.Value)
+		assert.Equal(t, sender, transaction.Operations[4].Account)
+		assert.Equal(t, "99000000", transaction.Operations[4].Amount.Value)
+
+		payloadsResponse, err := construction.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+			NetworkIdentifier: networkIdentifier,
+			Operations:        transaction.Operations,
+			Metadata:          transaction.Metadata,
+		})
+		assert.Nil(t, err)
+		assert.Len(t, payloadsResponse.Payloads, 1)
+
+		parseResponse, err := construction.ConstructionParse(ctx, &types.ConstructionParseRequest{
+			NetworkIdentifier: networkIdentifier,
+			Transaction:       payloadsResponse.UnsignedTransaction,
+		})
+		assert.Nil(t, err)
+
+		var metadata parseMetadata
+		assert.NoError(t, types.UnmarshalMap(parseResponse.Metadata, &metadata))
+		assert.Equal(t, transaction.Fee, metadata.Fee)
+	}
+
+	// Branch and bound searches the coins of
+	// each transaction once its recipients are known.
+	manyRecipients := make([]*batchRecipient, 1000)
+	for i := range manyRecipients {
+		manyRecipients[i] = &batchRecipient{Address: recipient, Amount: "200000000"}
+	}
+	mockIndexer.On("GetCoins", ctx, sender).Return(accountCoins, nil, nil).Once()
+	mockIndexer.On("GetScriptPubKeys", ctx, mock.Anything).Return(scripts, nil).Once()
+	callResponse, err = servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            BatchPayoutMethod,
+		Parameters: forceMarshalMap(t, &batchPayoutParams{
+			From:          sender,
+			Recipients:    manyRecipients[:12],
+			CoinSelection: BranchAndBound,
+			FeeRate:       dogecoin.RecommendedMinFeeRate,
+		}),
+	})
+	assert.Nil(t, err)
+
+	var bnbResult batchPayoutResult
+	assert.NoError(t, types.UnmarshalMap(callResponse.Result, &bnbResult))
+	assert.Len(t, bnbResult.Transactions, 1)
+	assert.Len(t, bnbResult.Transactions[0].Recipients, 12)
+
+	// Large batches grow the selection of each
+	// transaction with its recipients.
+	manyCoins := make([]*types.Coin, 100)
+	for i := range manyCoins {
+		manyCoins[i] = &types.Coin{
+			CoinIdentifier: &types.CoinIdentifier{
+				Identifier: fmt.Sprintf("b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:%d", i),
+			},
+			Amount: &types.Amount{
+				Value:    "3000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		}
+	}
+	mockIndexer.On("GetCoins", ctx, sender).Return(manyCoins, nil, nil).Once()
+	mockIndexer.On("GetScriptPubKeys", ctx, mock.Anything).Return(scripts, nil)
+	callResponse, err = servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            BatchPayoutMethod,
+		Parameters: forceMarshalMap(t, &batchPayoutParams{
+			From:       sender,
+			Recipients: manyRecipients,
+			FeeRate:    dogecoin.RecommendedMinFeeRate,
+			MaxSize:    10000,
+		}),
+	})
+	assert.Nil(t, err)
+
+	var manyResult batchPayoutResult
+	assert.NoError(t, types.UnmarshalMap(callResponse.Result, &manyResult))
+	paid := 0
+	for _, transaction := range manyResult.Transactions {
+		assert.LessOrEqual(t, transaction.Size, int64(10000))
+		for _, index := range transaction.Recipients {
+			assert.Equal(t, paid, index)
+			paid++
+		}
+	}
+	assert.Equal(t, len(manyRecipients), paid)
+
+	// Transactions of more than 252 outputs are charged
+	// for the larger output count: 10 + 612 * 34 + 148 +
+	// 34 bytes, plus 2 bytes for the count, start a kB.
+	largeCoin := &types.Coin{
+		CoinIdentifier: &types.CoinIdentifier{
+			Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:0",
+		},
+		Amount: &types.Amount{
+			Value:    "200000000000",
+			Currency: dogecoin.TestnetCurrency,
+		},
+	}
+	largeRecipients := make([]*batchRecipient, 612)
+	for i := range largeRecipients {
+		largeRecipients[i] = &batchRecipient{Address: recipient, Amount: "200000000"}
+	}
+	mockIndexer.On("GetCoins", ctx, sender).Return([]*types.Coin{largeCoin}, nil, nil).Once()
+	callResponse, err = servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            BatchPayoutMethod,
+		Parameters: forceMarshalMap(t, &batchPayoutParams{
+			From:       sender,
+			Recipients: largeRecipients,
+			FeeRate:    dogecoin.RecommendedMinFeeRate,
+		}),
+	})
+	assert.Nil(t, err)
+
+	var largeResult batchPayoutResult
+	assert.NoError(t, types.UnmarshalMap(callResponse.Result, &largeResult))
+	assert.Len(t, largeResult.Transactions, 1)
+	assert.Equal(t, int64(21002), largeResult.Transactions[0].Size)
+	assert.Equal(t, int64(22*dogecoin.RecommendedMinFeeRate), largeResult.Transactions[0].Fee)
+
+	tests := map[string]struct {
+		params *batchPayoutParams
+		coins  bool
+
+		expectedErr *types.Error
+	}{
+		"insufficient funds": {
+			params: &batchPayoutParams{
+				From:       sender,
+				Recipients: append(recipients, recipients...),
+				FeeRate:    dogecoin.RecommendedMinFeeRate,
+			},
+			coins:       true,
+			expectedErr: ErrInsufficientFunds,
+		},
+		"recipient larger than max size": {
+			params: &batchPayoutParams{
+				From:       sender,
+				Recipients: recipients,
+				FeeRate:    dogecoin.RecommendedMinFeeRate,
+				MaxSize:    200,
+			},
+			coins:       true,
+			expectedErr: ErrUnclearIntent,
+		},
+		"max size above standard size": {
+			params: &batchPayoutParams{
+				From:       sender,
+				Recipients: recipients,
+				MaxSize:    dogecoin.MaxStandardTxSize + 1,
+			},
+			expectedErr: ErrUnclearIntent,
+		},
+		"dust": {
+			params: &batchPayoutParams{
+				From:       sender,
+				Recipients: []*batchRecipient{{Address: recipient, Amount: "1000"}},
+			},
+			expectedErr: ErrOutputBelowDustLimit,
+		},
+		"no recipients": {
+			params: &batchPayoutParams{
+				From: sender,
+			},
+			expectedErr: ErrUnclearIntent,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if test.coins {
+				mockIndexer.On("GetCoins", ctx, sender).Return(accountCoins, nil, nil).Once()
+			}
+
+			callResponse, err := servicer.Call(ctx, &types.CallRequest{
+				NetworkIdentifier: networkIdentifier,
+				Method:            BatchPayoutMethod,
+				Parameters:        forceMarshalMap(t, test.params),
+			})
+			assert.Nil(t, callResponse)
+			assert.Equal(t, test.expectedErr.Code, err.Code)
+		})
+	}
+
+	// Coins cannot be selected offline
+	cfg.Mode = configuration.Offline
+	callResponse, err = servicer.Call(ctx, &types.CallRequest{
+		NetworkIdentifier: networkIdentifier,
+		Method:            BatchPayoutMethod,
+		Parameters: forceMarshalMap(t, &batchPayoutParams{
+			From:       sender,
+			Recipients: recipients,
+		}),
+	})
+	assert.Nil(t, callResponse)
+	assert.Equal(t, ErrUnavailableOffline.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
@@ -0,0 +1,289 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// LargestFirst selects the largest coins of the
+	// account until the outputs and the fee are funded.
+	LargestFirst = "largest_first"
+
+	// BranchAndBound searches for a set of coins that
+	// funds the outputs and the fee without a change
+	// output, like Dogecoin Core. It falls back to
+	// LargestFirst when no such set is found.
+	BranchAndBound = "branch_and_bound"
+
+	// maxBranchAndBoundTries is the number of branches
+	// visited before BranchAndBound gives up.
+	maxBranchAndBoundTries = 100000 // nolint:gomnd
+)
+
+var (
+	// errInsufficientFunds is returned when the coins of
+	// an account cannot fund the outputs and the fee.
+	errInsufficientFunds = errors.New("insufficient funds")
+)
+
+// coinSelector contains the sizes and the fee policy
+// used to select the coins funding a transaction.
+type coinSelector struct {
+	feePolicy FeePolicy
+	feeRate   int64
+
+	// baseSize is the size of the transaction without
+	// inputs or change output.
+	baseSize int64
+
+	// inputSize is the size of each selected input.
+	inputSize int64
+
+	// changeSize is the size of the change output.
+	changeSize int64
+
+	// minChange is the smallest change output created.
+	// Smaller change is left to the miners.
+	minChange int64
+
+	outputs []int64
+}
+
+// selectedCoins is the result of a coin selection.
+type selectedCoins struct {
+	// Indexes of the selected coins.
+	Indexes []int
+	Change  int64
+	Fee     int64
+}
+
+// validCoinSelection returns an error if strategy is
+// not a supported coin selection strategy.
+func validCoinSelection(strategy string) error {
+	switch strategy {
+	case "", LargestFirst, BranchAndBound:
+		return nil
+	default:
+		return fmt.Errorf("coin selection strategy %s is not supported", strategy)
+	}
+}
+
+// Select selects coins from amounts using strategy.
+func (c *coinSelector) Select(strategy string, amounts []int64) (*selectedCoins, error) {
+	if err := validCoinSelection(strategy); err != nil {
+		return nil, err
+	}
+
+	// Coins are visited from the largest to the smallest.
+	order := make([]int, len(amounts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return amounts[order[i]] > amounts[order[j]]
+	})
+
+	if strategy == BranchAndBound {
+		if selection := c.branchAndBound(order, amounts); selection != nil {
+			return selection, nil
+		}
+	}
+
+	return c.largestFirst(order, amounts)
+}
+
+// target is the sum of the outputs.
+func (c *coinSelector) target() int64 {
+	target := int64(0)
+	for _, output := range c.outputs {
+		target += output
+	}
+
+	return target
+}
+
+// size is the estimated size of spending inputs coins
+// with the provided change. A change of 0 adds no change
+// output. The base size counts one byte for the number of
+// inputs and of outputs, so only larger counts add bytes.
+func (c *coinSelector) size(inputs int, change int64) int64 {
+	size := c.baseSize + int64(inputs)*c.inputSize
+	outputCount := len(c.outputs)
+	if change > 0 {
+		outputCount++
+		size += c.changeSize
+	}
+
+	size += int64(wire.VarIntSerializeSize(uint64(inputs)) - 1)
+	size += int64(wire.VarIntSerializeSize(uint64(outputCount)) - 1)
+
+	return size
+}
+
+// fee is the fee of spending inputs coins with the
+// provided change. A change of 0 adds no change output.
+func (c *coinSelector) fee(inputs int, change int64) int64 {
+	outputs := c.outputs
+	if change > 0 {
+		outputs = append(append([]int64{}, c.outputs...), change)
+	}
+
+	return c.feePolicy.Fee(c.feeRate, c.size(inputs, change), outputs)
+}
+
+// settle determines the change and the fee of spending
+// inputs coins worth total. It returns false if total
+// does not fund the outputs and the fee.
+func (c *coinSelector) settle(inputs int, total int64) (int64, int64, bool) {
+	available := total - c.target()
+	if available < c.fee(inputs, 0) {
+		return 0, 0, false
+	}
+
+	// The fee of a change output may depend on its
+	// amount, so it is computed for the actual change.
+	change := available - c.fee(inputs, c.minChange)
+	if change >= c.minChange {
+		fee := c.fee(inputs, change)
+		change = available - fee
+		if change >= c.minChange {
+			return change, fee, true
+		}
+	}
+
+	return 0, available, true
+}
+
+// largestFirst selects the largest coins until the
+// outputs and the fee are funded.
+func (c *coinSelector) largestFirst(order []int, amounts []int64) (*selectedCoins, error) {
+	total := int64(0)
+	for i, index := range order {
+		total += amounts[index]
+		change, fee, ok := c.settle(i+1, total)
+		if !ok {
+			continue
+		}
+
+		indexes := append([]int{}, order[:i+1]...)
+		sort.Ints(indexes)
+
+		return &selectedCoins{Indexes: indexes, Change: change, Fee: fee}, nil
+	}
+
+	return nil, fmt.Errorf(
+		"%w: %d coins cannot fund %d",
+		errInsufficientFunds,
+		len(amounts),
+		c.target(),
+	)
+}
+
+// branchAndBound searches depth-first for the set of coins
+// wasting the least amount without a change output. Any
+// amount above the fee is wasted, so sets wasting more than
+// a change output would cost are not considered. It returns
+// nil if no set is found.
+func (c *coinSelector) branchAndBound(order []int, amounts []int64) *selectedCoins {
+	target := c.target()
+	perInput := c.feeRate * c.inputSize / bytesPerKB
+
+	// Coins costing more to spend than they are
+	// worth are never selected.
+	candidates := []int{}
+	for _, index := range order {
+		if amounts[index] > perInput {
+			candidates = append(candidates, index)
+		}
+	}
+
+	remaining := make([]int64, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + amounts[candidates[i]]
+	}
+
+	var best []int
+	bestWaste := int64(-1)
+	selected := []int{}
+	tries := 0
+
+	var search func(depth int, total int64)
+	search = func(depth int, total int64) {
+		tries++
+		if tries > maxBranchAndBoundTries || total+remaining[depth] < target {
+			return
+		}
+
+		if len(selected) > 0 {
+			needed := target + c.fee(len(selected), 0)
+			costOfChange := c.fee(len(selected), c.minChange) - c.fee(len(selected), 0) + c.minChange
+			if total >= needed {
+				if waste := total - needed; waste <= costOfChange &&
+					(bestWaste < 0 || waste < bestWaste) {
+					best = append([]int{}, selected...)
+					bestWaste = waste
+				}
+
+				// Selecting more coins only wastes more.
+				return
+			}
+		}
+
+		if depth == len(candidates) {
+			return
+		}
+
+		selected = append(selected, candidates[depth])
+		search(depth+1, total+amounts[candidates[depth]])
+		selected = selected[:len(selected)-1]
+
+		search(depth+1, total)
+	}
+	search(0, 0)
+
+	if best == nil {
+		return nil
+	}
+
+	sort.Ints(best)
+	total := int64(0)
+	for _, index := range best {
+		total += amounts[index]
+	}
+
+	return &selectedCoins{Indexes: best, Fee: total - target}
+}
+
+// parseAmounts parses amounts in Satoshis.
+func parseAmounts(values []string) ([]int64, error) {
+	amounts := make([]int64, len(values))
+	for i, value := range values {
+		amount, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, fmt.Errorf("unable to parse amount %s", value)
+		}
+
+		amounts[i] = amount.Int64()
+	}
+
+	return amounts, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoinSelector_Select(t *testing.T) {
+	selector := &coinSelector{
+		feePolicy:  NewDogecoinFeePolicy(),
+		feeRate:    1000000,
+		baseSize:   10 + 34,
+		inputSize:  148,
+		changeSize: 34,
+		minChange:  1000000,
+		outputs:    []int64{300000000},
+	}
+
+	tests := map[string]struct {
+		strategy string
+		amounts  []int64
+
+		expected    *selectedCoins
+		expectedErr error
+	}{
+		"largest first single coin": {
+			strategy: LargestFirst,
+			amounts:  []int64{200000000, 500000000, 100000000},
+			expected: &selectedCoins{
+				Indexes: []int{1},
+				Change:  199000000,
+				Fee:     1000000,
+			},
+		},
+		"largest first multiple coins": {
+			strategy: LargestFirst,
+			amounts:  []int64{100000000, 200000000, 150000000},
+			expected: &selectedCoins{
+				Indexes: []int{1, 2},
+				Change:  49000000,
+				Fee:     1000000,
+			},
+		},
+		"default strategy": {
+			amounts: []int64{100000000, 200000000, 150000000},
+			expected: &selectedCoins{
+				Indexes: []int{1, 2},
+				Change:  49000000,
+				Fee:     1000000,
+			},
+		},
+		"change below minimum is left to miners": {
+			strategy: LargestFirst,
+			amounts:  []int64{301500000},
+			expected: &selectedCoins{
+				Indexes: []int{0},
+				Fee:     1500000,
+			},
+		},
+		"branch and bound avoids change": {
+			strategy: BranchAndBound,
+			amounts:  []int64{500000000, 200000000, 101000000},
+			expected: &selectedCoins{
+				Indexes: []int{1, 2},
+				Fee:     1000000,
+			},
+		},
+		"branch and bound falls back to largest first": {
+			strategy: BranchAndBound,
+			amounts:  []int64{500000000, 200000000},
+			expected: &selectedCoins{
+				Indexes: []int{0},
+				Change:  199000000,
+				Fee:     1000000,
+			},
+		},
+		"insufficient funds": {
+			strategy:    LargestFirst,
+			amounts:     []int64{100000000, 200000000},
+			expectedErr: errInsufficientFunds,
+		},
+		"fee not covered": {
+			strategy:    BranchAndBound,
+			amounts:     []int64{300500000},
+			expectedErr: errInsufficientFunds,
+		},
+		"unsupported strategy": {
+			strategy:    "random",
+			amounts:     []int64{500000000},
+			expectedErr: errors.New("coin selection strategy random is not supported"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			selection, err := selector.Select(test.strategy, test.amounts)
+			if test.expectedErr != nil {
+				assert.Nil(t, selection)
+				if errors.Is(test.expectedErr, errInsufficientFunds) {
+					assert.True(t, errors.Is(err, errInsufficientFunds))
+				} else {
+					assert.EqualError(t, err, test.expectedErr.Error())
+				}
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, selection)
+		})
+	}
+}
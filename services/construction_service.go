@@ -18,18 +18,22 @@ package services
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/configuration"
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
@@ -39,20 +43,33 @@ import (
 )
 
 const (
-	// bytesInKB is the number of bytes in a KB. In Bitcoin, this is
-	// considered to be 1000.
-	bytesInKb = float64(1000) // nolint:gomnd
-
 	// defaultConfirmationTarget is the number of blocks we would
 	// like our transaction to be included by.
 	defaultConfirmationTarget = int64(2) // nolint:gomnd
+
+	// htlcClaim is the branch of a hash time locked
+	// contract spent with the preimage.
+	htlcClaim = "claim"
+
+	// htlcRefund is the branch of a hash time locked
+	// contract spent after its lock time.
+	htlcRefund = "refund"
+
+	// publicKeyCompressed is the format of 33-byte
+	// public keys.
+	publicKeyCompressed = "compressed"
+
+	// publicKeyUncompressed is the format of 65-byte
+	// public keys held by legacy wallets.
+	publicKeyUncompressed = "uncompressed"
 )
 
 // ConstructionAPIService implements the server.ConstructionAPIServicer interface.
 type ConstructionAPIService struct {
-	config *configuration.Configuration
-	client Client
-	i      Indexer
+	config    *configuration.Configuration
+	client    Client
+	i         Indexer
+	feePolicy FeePolicy
 }
 
 // NewConstructionAPIService creates a new instance of a ConstructionAPIService.
@@ -60,11 +77,13 @@ func NewConstructionAPIService(
 	config *configuration.Configuration,
 	client Client,
 	i Indexer,
+	feePolicy FeePolicy,
 ) server.ConstructionAPIServicer {
 	return &ConstructionAPIService{
-		config: config,
-		client: client,
-		i:      i,
+		config:    config,
+		client:    client,
+		i:         i,
+		feePolicy: feePolicy,
 	}
 }
 
@@ -73,7 +92,41 @@ func (s *ConstructionAPIService) ConstructionDerive(
 	ctx context.Context,
 	request *types.ConstructionDeriveRequest,
 ) (*types.ConstructionDeriveResponse, *types.Error) {
-	addr, err := btcutil.NewAddressWitnessPubKeyHash(
+	// Unlike preprocess and payloads, derive does not decode
+	// addresses with dogecoin.DecodeAddress: its metadata holds
+	// public keys and script parameters but no address, and
+	// the derived address is encoded with the configured
+	// params, so it cannot belong to a foreign network.
+	var metadata deriveMetadata
+	if err := types.UnmarshalMap(request.Metadata, &metadata); err != nil {
+		return nil, wrapErr(ErrUnableToDerive, err)
+	}
+
+	format, err := publicKeyFormat(request.PublicKey)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToDerive, err)
+	}
+
+	if len(metadata.PublicKeys) > 0 && metadata.LockTime > 0 {
+		return nil, wrapErr(
+			ErrUnableToDerive,
+			errors.New("multisig addresses cannot have a lock time"),
+		)
+	}
+
+	if len(metadata.PublicKeys) > 0 {
+		return s.deriveMultiSig(request.PublicKey, &metadata)
+	}
+
+	if len(metadata.PaymentHash) > 0 {
+		return s.deriveHTLC(request.PublicKey, &metadata)
+	}
+
+	if metadata.LockTime > 0 {
+		return s.deriveLockTime(request.PublicKey, metadata.LockTime)
+	}
+
+	addr, err := btcutil.NewAddressPubKeyHash(
 		btcutil.Hash160(request.PublicKey.Bytes),
 		s.config.Params,
 	)
@@ -81,284 +134,1484 @@ func (s *ConstructionAPIService) ConstructionDerive(
 		return nil, wrapErr(ErrUnableToDerive, err)
 	}
 
-	return &types.ConstructionDeriveResponse{
+	response := &types.ConstructionDeriveResponse{
 		AccountIdentifier: &types.AccountIdentifier{
 			Address: addr.EncodeAddress(),
 		},
-	}, nil
+	}
+
+	if format == publicKeyUncompressed {
+		response.Metadata, err = types.MarshalMap(&deriveResponseMetadata{
+			PublicKeyFormat: format,
+		})
+		if err != nil {
+			return nil, wrapErr(ErrUnableToDerive, err)
+		}
+	}
+
+	return response, nil
 }
 
-// estimateSize returns the estimated size of a transaction in vBytes.
-func (s *ConstructionAPIService) estimateSize(operations []*types.Operation) float64 {
-	size := bitcoin.TransactionOverhead
-	for _, operation := range operations {
-		switch operation.Type {
-		case bitcoin.InputOpType:
-			size += bitcoin.InputSize
-		case bitcoin.OutputOpType:
-			size += bitcoin.OutputOverhead
-			addr, err := btcutil.DecodeAddress(operation.Account.Address, s.config.Params)
-			if err != nil {
-				size += bitcoin.P2PKHScriptPubkeySize
-				continue
-			}
+// publicKeyFormat returns the format of a secp256k1
+// public key, compressed or uncompressed. Hybrid
+// public keys are not standard.
+func publicKeyFormat(publicKey *types.PublicKey) (string, error) {
+	if publicKey == nil {
+		return "", errors.New("public key cannot be nil")
+	}
 
-			script, err := txscript.PayToAddrScript(addr)
-			if err != nil {
-				size += bitcoin.P2PKHScriptPubkeySize
-				continue
-			}
+	if publicKey.CurveType != types.Secp256k1 {
+		return "", fmt.Errorf("unsupported curve type %s", publicKey.CurveType)
+	}
 
-			size += len(script)
-		}
+	if _, err := btcec.ParsePubKey(publicKey.Bytes, btcec.S256()); err != nil {
+		return "", fmt.Errorf("%w unable to parse public key", err)
 	}
 
-	return float64(size)
+	switch {
+	case len(publicKey.Bytes) == btcec.PubKeyBytesLenCompressed:
+		return publicKeyCompressed, nil
+	case len(publicKey.Bytes) == btcec.PubKeyBytesLenUncompressed && publicKey.Bytes[0] == 0x04:
+		return publicKeyUncompressed, nil
+	default:
+		return "", errors.New("hybrid public keys are not supported")
+	}
 }
 
-// ConstructionPreprocess implements the /construction/preprocess
-// endpoint.
-func (s *ConstructionAPIService) ConstructionPreprocess(
-	ctx context.Context,
-	request *types.ConstructionPreprocessRequest,
-) (*types.ConstructionPreprocessResponse, *types.Error) {
-	descriptions := &parser.Descriptions{
-		OperationDescriptions: []*parser.OperationDescription{
-			{
-				Type: bitcoin.InputOpType,
-				Account: &parser.AccountDescription{
-					Exists: true,
-				},
-				Amount: &parser.AmountDescription{
-					Exists:   true,
-					Sign:     parser.NegativeAmountSign,
-					Currency: s.config.Currency,
-				},
-				CoinAction:   types.CoinSpent,
-				AllowRepeats: true,
-			},
-		},
+// inputPublicKeyFormat returns the format of the public
+// key hashed by an INPUT operation of class.
+func inputPublicKeyFormat(operation *types.Operation, class txscript.ScriptClass) (string, error) {
+	var metadata inputMetadata
+	if err := types.UnmarshalMap(operation.Metadata, &metadata); err != nil {
+		return "", fmt.Errorf("%w unable to unmarshal input metadata", err)
+	}
+
+	switch metadata.PublicKeyFormat {
+	case "", publicKeyCompressed:
+		return publicKeyCompressed, nil
+	case publicKeyUncompressed:
+		// Uncompressed keys are not standard
+		// in witness programs.
+		if class != txscript.PubKeyHashTy {
+			return "", fmt.Errorf("uncompressed public keys cannot spend %s inputs", class)
+		}
+
+		return publicKeyUncompressed, nil
+	default:
+		return "", fmt.Errorf("unknown public key format %s", metadata.PublicKeyFormat)
+	}
+}
+
+// serializePublicKey serializes the public key of a
+// signature in format, so the public key matches the
+// hash of the input even when a signer returns the
+// other format.
+func serializePublicKey(publicKey *types.PublicKey, format string) ([]byte, error) {
+	if publicKey == nil {
+		return nil, errors.New("public key cannot be nil")
 	}
 
-	matches, err := parser.MatchOperations(descriptions, request.Operations)
+	pubKey, err := btcec.ParsePubKey(publicKey.Bytes, btcec.S256())
 	if err != nil {
-		return nil, wrapErr(ErrUnclearIntent, err)
+		return nil, fmt.Errorf("%w unable to parse public key", err)
 	}
 
-	coins := make([]*types.Coin, len(matches[0].Operations))
-	for i, input := range matches[0].Operations {
-		if input.CoinChange == nil {
-			return nil, wrapErr(ErrUnclearIntent, errors.New("CoinChange cannot be nil"))
+	if format == publicKeyUncompressed {
+		return pubKey.SerializeUncompressed(), nil
+	}
+
+	return pubKey.SerializeCompressed(), nil
+}
+
+// deriveMultiSig returns the P2SH address of an m-of-n
+// multisig redeem script. The public key of the request
+// must be one of the keys of the redeem script.
+func (s *ConstructionAPIService) deriveMultiSig(
+	publicKey *types.PublicKey,
+	metadata *deriveMetadata,
+) (*types.ConstructionDeriveResponse, *types.Error) {
+	found := false
+	pubKeys := make([][]byte, len(metadata.PublicKeys))
+	for i, rawKey := range metadata.PublicKeys {
+		pubKey, err := hex.DecodeString(rawKey)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToDerive, fmt.Errorf("%w unable to decode public key %d", err, i))
 		}
 
-		coins[i] = &types.Coin{
-			CoinIdentifier: input.CoinChange.CoinIdentifier,
-			Amount:         input.Amount,
+		if bytes.Equal(pubKey, publicKey.Bytes) {
+			found = true
 		}
+
+		pubKeys[i] = pubKey
 	}
 
-	options, err := types.MarshalMap(&preprocessOptions{
-		Coins:         coins,
-		EstimatedSize: s.estimateSize(request.Operations),
-		FeeMultiplier: request.SuggestedFeeMultiplier,
-	})
+	if !found {
+		return nil, wrapErr(
+			ErrUnableToDerive,
+			errors.New("public key is not one of the multisig public keys"),
+		)
+	}
+
+	redeemScript, err := bitcoin.MultiSigScript(s.config.Params, pubKeys, metadata.Threshold)
 	if err != nil {
-		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		return nil, wrapErr(ErrUnableToDerive, err)
 	}
 
-	return &types.ConstructionPreprocessResponse{
-		Options: options,
-	}, nil
+	return s.deriveScriptHash(redeemScript)
 }
 
-// ConstructionMetadata implements the /construction/metadata endpoint.
-func (s *ConstructionAPIService) ConstructionMetadata(
-	ctx context.Context,
-	request *types.ConstructionMetadataRequest,
-) (*types.ConstructionMetadataResponse, *types.Error) {
-	if s.config.Mode != configuration.Online {
-		return nil, wrapErr(ErrUnavailableOffline, nil)
+// deriveLockTime returns the P2SH address of a redeem
+// script locking coins to the public key until lockTime.
+func (s *ConstructionAPIService) deriveLockTime(
+	publicKey *types.PublicKey,
+	lockTime int64,
+) (*types.ConstructionDeriveResponse, *types.Error) {
+	if lockTime > math.MaxUint32 {
+		return nil, wrapErr(ErrUnableToDerive, fmt.Errorf("lock time %d is too large", lockTime))
 	}
 
-	var options preprocessOptions
-	if err := types.UnmarshalMap(request.Options, &options); err != nil {
-		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	if err := s.validateLockTime(uint32(lockTime)); err != nil {
+		return nil, wrapErr(ErrUnableToDerive, err)
+	}
+
+	redeemScript, err := bitcoin.LockTimeScript(s.config.Params, publicKey.Bytes, lockTime)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToDerive, err)
+	}
+
+	return s.deriveScriptHash(redeemScript)
+}
+
+// deriveHTLC returns the P2SH address of a hash time
+// locked contract claimed by the public key.
+func (s *ConstructionAPIService) deriveHTLC(
+	publicKey *types.PublicKey,
+	metadata *deriveMetadata,
+) (*types.ConstructionDeriveResponse, *types.Error) {
+	paymentHash, err := hex.DecodeString(metadata.PaymentHash)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToDerive, fmt.Errorf("%w unable to decode payment hash", err))
 	}
 
-	// Determine feePerKB and ensure it is not below the minimum fee
-	// relay rate.
-	feePerKB, err := s.client.SuggestedFeeRate(ctx, defaultConfirmationTarget)
+	refundKey, err := hex.DecodeString(metadata.RefundPublicKey)
 	if err != nil {
-		return nil, wrapErr(ErrCouldNotGetFeeRate, err)
+		return nil, wrapErr(ErrUnableToDerive, fmt.Errorf("%w unable to decode refund public key", err))
 	}
-	if options.FeeMultiplier != nil {
-		feePerKB *= *options.FeeMultiplier
+
+	if metadata.LockTime > math.MaxUint32 {
+		return nil, wrapErr(ErrUnableToDerive, fmt.Errorf("lock time %d is too large", metadata.LockTime))
 	}
-	if feePerKB < bitcoin.MinFeeRate {
-		feePerKB = bitcoin.MinFeeRate
+
+	if err := s.validateLockTime(uint32(metadata.LockTime)); err != nil {
+		return nil, wrapErr(ErrUnableToDerive, err)
 	}
 
-	// Calculated the estimated fee in Satoshis
-	satoshisPerB := (feePerKB * float64(dogecoin.SatoshisInBitcoin)) / bytesInKb
-	estimatedFee := satoshisPerB * options.EstimatedSize
-	suggestedFee := &types.Amount{
-		Value:    fmt.Sprintf("%d", int64(estimatedFee)),
-		Currency: s.config.Currency,
+	redeemScript, err := bitcoin.HTLCScript(
+		s.config.Params,
+		paymentHash,
+		publicKey.Bytes,
+		refundKey,
+		metadata.LockTime,
+	)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToDerive, err)
 	}
 
-	scripts, err := s.i.GetScriptPubKeys(ctx, options.Coins)
+	return s.deriveScriptHash(redeemScript)
+}
+
+// deriveScriptHash returns the P2SH address of a
+// redeem script with the redeem script in metadata.
+func (s *ConstructionAPIService) deriveScriptHash(
+	redeemScript []byte,
+) (*types.ConstructionDeriveResponse, *types.Error) {
+	addr, err := btcutil.NewAddressScriptHash(redeemScript, s.config.Params)
 	if err != nil {
-		return nil, wrapErr(ErrScriptPubKeysMissing, err)
+		return nil, wrapErr(ErrUnableToDerive, err)
 	}
 
-	metadata, err := types.MarshalMap(&constructionMetadata{ScriptPubKeys: scripts})
+	responseMetadata, err := types.MarshalMap(&deriveResponseMetadata{
+		RedeemScript: hex.EncodeToString(redeemScript),
+	})
 	if err != nil {
 		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
-	return &types.ConstructionMetadataResponse{
-		Metadata:     metadata,
-		SuggestedFee: []*types.Amount{suggestedFee},
+	return &types.ConstructionDeriveResponse{
+		AccountIdentifier: &types.AccountIdentifier{
+			Address: addr.EncodeAddress(),
+		},
+		Metadata: responseMetadata,
 	}, nil
 }
 
-// ConstructionPayloads implements the /construction/payloads endpoint.
-func (s *ConstructionAPIService) ConstructionPayloads(
-	ctx context.Context,
-	request *types.ConstructionPayloadsRequest,
-) (*types.ConstructionPayloadsResponse, *types.Error) {
-	descriptions := &parser.Descriptions{
-		OperationDescriptions: []*parser.OperationDescription{
-			{
-				Type: bitcoin.InputOpType,
-				Account: &parser.AccountDescription{
-					Exists: true,
-				},
-				Amount: &parser.AmountDescription{
-					Exists:   true,
-					Sign:     parser.NegativeAmountSign,
-					Currency: s.config.Currency,
-				},
-				AllowRepeats: true,
-				CoinAction:   types.CoinSpent,
-			},
-			{
-				Type: bitcoin.OutputOpType,
-				Account: &parser.AccountDescription{
-					Exists: true,
-				},
-				Amount: &parser.AmountDescription{
-					Exists:   true,
-					Sign:     parser.PositiveAmountSign,
-					Currency: s.config.Currency,
-				},
-				AllowRepeats: true,
-			},
-		},
-		ErrUnmatched: true,
+// estimateSize returns the estimated size of a transaction in vBytes
+// and the indexes of the inputs whose script class is not known from
+// their address. Those inputs are not part of the estimate and are
+// estimated in ConstructionMetadata once their ScriptPubKeys are known.
+func (s *ConstructionAPIService) estimateSize(operations []*types.Operation) (float64, []int) {
+	size := bitcoin.LegacyTransactionOverhead
+	hasWitness := false
+	unestimated := []int{}
+	inputIndex := 0
+	for _, operation := range operations {
+		switch operation.Type {
+		case bitcoin.InputOpType:
+			inputSize, class, ok := s.estimateInputSize(operation)
+			if !ok {
+				unestimated = append(unestimated, inputIndex)
+			}
+
+			if class == txscript.WitnessV0PubKeyHashTy {
+				hasWitness = true
+			}
+
+			size += inputSize
+			inputIndex++
+		case bitcoin.OutputOpType:
+			size += s.outputSize(operation)
+		}
 	}
 
-	matches, err := parser.MatchOperations(descriptions, request.Operations)
-	if err != nil {
-		return nil, wrapErr(ErrUnclearIntent, err)
+	if hasWitness {
+		size += bitcoin.TransactionOverhead - bitcoin.LegacyTransactionOverhead
 	}
 
-	tx := wire.NewMsgTx(wire.TxVersion)
-	for _, input := range matches[0].Operations {
-		if input.CoinChange == nil {
-			return nil, wrapErr(ErrUnclearIntent, errors.New("CoinChange cannot be nil"))
-		}
+	return float64(size), unestimated
+}
 
-		transactionHash, index, err := bitcoin.ParseCoinIdentifier(input.CoinChange.CoinIdentifier)
-		if err != nil {
-			return nil, wrapErr(ErrInvalidCoin, err)
+// outputSize returns the size of an OUTPUT operation.
+// Outputs with an unknown script are assumed to be P2PKH.
+func (s *ConstructionAPIService) outputSize(operation *types.Operation) int {
+	size := bitcoin.OutputOverhead
+	data, err := nullData(operation)
+	if err == nil && data != nil {
+		script, err := txscript.NullDataScript(data)
+		if err == nil {
+			return size + len(script)
 		}
+	}
 
-		tx.AddTxIn(&wire.TxIn{
-			PreviousOutPoint: wire.OutPoint{
-				Hash:  *transactionHash,
-				Index: index,
-			},
-			SignatureScript: nil,
-			Sequence:        wire.MaxTxInSequenceNum,
-		})
+	if operation.Account == nil {
+		return size + bitcoin.P2PKHScriptPubkeySize
 	}
 
-	for i, output := range matches[1].Operations {
-		addr, err := btcutil.DecodeAddress(output.Account.Address, s.config.Params)
-		if err != nil {
-			return nil, wrapErr(ErrUnableToDecodeAddress, fmt.Errorf(
-				"%w unable to decode address %s",
-				err,
-				output.Account.Address,
-			),
-			)
-		}
+	addr, rErr := s.decodeAddress(operation.Account.Address)
+	if rErr != nil {
+		return size + bitcoin.P2PKHScriptPubkeySize
+	}
 
-		pkScript, err := txscript.PayToAddrScript(addr)
-		if err != nil {
-			return nil, wrapErr(
-				ErrUnableToDecodeAddress,
-				fmt.Errorf("%w unable to construct payToAddrScript", err),
-			)
-		}
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return size + bitcoin.P2PKHScriptPubkeySize
+	}
 
-		tx.AddTxOut(&wire.TxOut{
-			Value:    matches[1].Amounts[i].Int64(),
-			PkScript: pkScript,
-		})
+	return size + len(script)
+}
+
+// addressInputSize returns the size of an input spending
+// a coin of address. Coins of addresses whose input size
+// cannot be estimated are assumed to be P2PKH.
+func (s *ConstructionAPIService) addressInputSize(address string) int {
+	size, _, ok := s.estimateInputSize(&types.Operation{
+		Type:    bitcoin.InputOpType,
+		Account: &types.AccountIdentifier{Address: address},
+	})
+	if !ok {
+		return bitcoin.EstimateInputSize(s.config.Params, txscript.PubKeyHashTy, nil, true)
 	}
 
-	// Create Signing Payloads (must be done after entire tx is constructed
-	// or hash will not be correct).
-	inputAmounts := make([]string, len(tx.TxIn))
-	inputAddresses := make([]string, len(tx.TxIn))
-	payloads := make([]*types.SigningPayload, len(tx.TxIn))
-	var metadata constructionMetadata
-	if err := types.UnmarshalMap(request.Metadata, &metadata); err != nil {
-		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	return size
+}
+
+// accountCoins returns the coins of account in the
+// currency of the network and their amounts.
+func (s *ConstructionAPIService) accountCoins(
+	ctx context.Context,
+	account *types.AccountIdentifier,
+) ([]*types.Coin, []int64, *types.Error) {
+	accountCoins, _, err := s.i.GetCoins(ctx, account)
+	if err != nil {
+		return nil, nil, wrapErr(ErrUnableToGetCoins, err)
 	}
 
-	for i := range tx.TxIn {
-		address := matches[0].Operations[i].Account.Address
-		script, err := hex.DecodeString(metadata.ScriptPubKeys[i].Hex)
-		if err != nil {
-			return nil, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+	coins := []*types.Coin{}
+	values := []string{}
+	for _, coin := range accountCoins {
+		if types.Hash(coin.Amount.Currency) != types.Hash(s.config.Currency) {
+			continue
 		}
 
-		class, _, err := bitcoin.ParseSingleAddress(s.config.Params, script)
-		if err != nil {
-			return nil, wrapErr(
-				ErrUnableToDecodeAddress,
-				fmt.Errorf("%w unable to parse address for utxo %d", err, i),
-			)
-		}
+		coins = append(coins, coin)
+		values = append(values, coin.Amount.Value)
+	}
 
-		inputAddresses[i] = address
-		inputAmounts[i] = matches[0].Amounts[i].String()
-		absAmount := new(big.Int).Abs(matches[0].Amounts[i]).Int64()
+	amounts, err := parseAmounts(values)
+	if err != nil {
+		return nil, nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
 
-		switch class {
-		case txscript.WitnessV0PubKeyHashTy:
-			hash, err := txscript.CalcWitnessSigHash(
-				script,
-				txscript.NewTxSigHashes(tx),
-				txscript.SigHashAll,
-				tx,
-				i,
-				absAmount,
-			)
-			if err != nil {
-				return nil, wrapErr(ErrUnableToCalculateSignatureHash, err)
-			}
+	return coins, amounts, nil
+}
 
-			payloads[i] = &types.SigningPayload{
-				AccountIdentifier: &types.AccountIdentifier{
-					Address: address,
-				},
+// spendCoins adds the coins at indexes to options and
+// metadata. Spent coins have negated amounts like the
+// amounts of INPUT operations.
+func spendCoins(
+	coins []*types.Coin,
+	amounts []int64,
+	indexes []int,
+	options *preprocessOptions,
+	metadata *constructionMetadata,
+) {
+	options.Coins = make([]*types.Coin, len(indexes))
+	for i, index := range indexes {
+		options.Coins[i] = &types.Coin{
+			CoinIdentifier: coins[index].CoinIdentifier,
+			Amount: &types.Amount{
+				Value:    strconv.FormatInt(-amounts[index], 10),
+				Currency: coins[index].Amount.Currency,
+			},
+		}
+	}
+
+	metadata.Coins = options.Coins
+	metadata.Account = options.From
+}
+
+// changeOperation returns the OUTPUT operation paying
+// amount to options.ChangeAddress after outputs.
+func (s *ConstructionAPIService) changeOperation(
+	options *preprocessOptions,
+	outputs int,
+	amount int64,
+) *types.Operation {
+	return &types.Operation{
+		OperationIdentifier: &types.OperationIdentifier{
+			Index: int64(len(options.Coins) + outputs),
+		},
+		Type: bitcoin.OutputOpType,
+		Account: &types.AccountIdentifier{
+			Address: options.ChangeAddress,
+		},
+		Amount: &types.Amount{
+			Value:    strconv.FormatInt(amount, 10),
+			Currency: s.config.Currency,
+		},
+	}
+}
+
+// minChange returns the smallest change output created
+// when coins are selected. Smaller change is left to the
+// miners. Without a construction configuration, any
+// positive change gets a change output.
+func (s *ConstructionAPIService) minChange() int64 {
+	if s.config.Construction == nil {
+		return 1
+	}
+
+	if s.config.Construction.DustLimit > s.config.Construction.MinChange {
+		return s.config.Construction.DustLimit
+	}
+
+	return s.config.Construction.MinChange
+}
+
+// selectCoins selects the coins of options.From funding
+// outputs at feeRate. The selected coins and the change
+// output are added to options and metadata. It returns
+// the fee of the transaction.
+func (s *ConstructionAPIService) selectCoins(
+	ctx context.Context,
+	options *preprocessOptions,
+	feeRate int64,
+	outputs []int64,
+	metadata *constructionMetadata,
+) (int64, *types.Error) {
+	coins, amounts, rErr := s.accountCoins(ctx, options.From)
+	if rErr != nil {
+		return 0, rErr
+	}
+
+	selector := &coinSelector{
+		feePolicy:  s.feePolicy,
+		feeRate:    feeRate,
+		baseSize:   int64(math.Ceil(options.EstimatedSize)),
+		inputSize:  options.InputSize,
+		changeSize: options.ChangeSize,
+		minChange:  s.minChange(),
+		outputs:    outputs,
+	}
+	selection, err := selector.Select(options.CoinSelection, amounts)
+	if errors.Is(err, errInsufficientFunds) {
+		return 0, wrapErr(ErrInsufficientFunds, err)
+	}
+	if err != nil {
+		return 0, wrapErr(ErrUnclearIntent, err)
+	}
+
+	spendCoins(coins, amounts, selection.Indexes, options, metadata)
+	if selection.Change > 0 {
+		metadata.Change = s.changeOperation(options, len(outputs), selection.Change)
+	}
+
+	return selection.Fee, nil
+}
+
+// sweepCoins spends the coins of options.From, from the
+// largest and up to the caps of options, to a single output
+// paying their balance minus the fee at feeRate. The coins
+// and the output are added to options and metadata. It
+// returns the fee of the transaction.
+func (s *ConstructionAPIService) sweepCoins(
+	ctx context.Context,
+	options *preprocessOptions,
+	feeRate int64,
+	metadata *constructionMetadata,
+) (int64, *types.Error) {
+	coins, amounts, rErr := s.accountCoins(ctx, options.From)
+	if rErr != nil {
+		return 0, rErr
+	}
+
+	order := make([]int, len(amounts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return amounts[order[i]] > amounts[order[j]]
+	})
+
+	indexes := []int{}
+	balance := int64(0)
+	for _, index := range order {
+		if options.MaxCoins > 0 && len(indexes) >= options.MaxCoins {
+			break
+		}
+
+		if options.MaxInputSize > 0 && int64(len(indexes)+1)*options.InputSize > options.MaxInputSize {
+			break
+		}
+
+		indexes = append(indexes, index)
+		balance += amounts[index]
+	}
+
+	if len(indexes) == 0 {
+		return 0, wrapErr(
+			ErrInsufficientFunds,
+			fmt.Errorf("account %s has no coins to sweep", options.From.Address),
+		)
+	}
+
+	// The fee of the output may depend on its
+	// amount, so it is computed for the actual amount.
+	size := int64(math.Ceil(options.EstimatedSize)) + int64(len(indexes))*options.InputSize
+	fee := s.feePolicy.Fee(feeRate, size, []int64{balance})
+	fee = s.feePolicy.Fee(feeRate, size, []int64{balance - fee})
+	if balance-fee <= 0 {
+		return 0, wrapErr(
+			ErrInsufficientFunds,
+			fmt.Errorf("%w: %d coins worth %d cannot pay a fee of %d", errInsufficientFunds, len(indexes), balance, fee),
+		)
+	}
+
+	sort.Ints(indexes)
+	spendCoins(coins, amounts, indexes, options, metadata)
+	metadata.Change = s.changeOperation(options, 0, balance-fee)
+	metadata.Sweep = true
+
+	if rErr := s.validateOutputs(selectedOperations(nil, metadata)); rErr != nil {
+		return 0, rErr
+	}
+
+	return fee, nil
+}
+
+// selectedOperations returns the INPUT operations spending
+// the coins selected in ConstructionMetadata followed by
+// outputs and the change output.
+func selectedOperations(
+	outputs []*types.Operation,
+	metadata *constructionMetadata,
+) []*types.Operation {
+	operations := []*types.Operation{}
+	for _, coin := range metadata.Coins {
+		operations = append(operations, &types.Operation{
+			Type:    bitcoin.InputOpType,
+			Account: metadata.Account,
+			Amount:  coin.Amount,
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: coin.CoinIdentifier,
+				CoinAction:     types.CoinSpent,
+			},
+		})
+	}
+
+	// The OUTPUT operation of a sweep has no
+	// amount and is replaced by Change.
+	if !metadata.Sweep {
+		operations = append(operations, outputs...)
+	}
+
+	if metadata.Change != nil {
+		operations = append(operations, metadata.Change)
+	}
+
+	indexed := make([]*types.Operation, len(operations))
+	for i, operation := range operations {
+		copied := *operation
+		copied.OperationIdentifier = &types.OperationIdentifier{Index: int64(i)}
+		indexed[i] = &copied
+	}
+
+	return indexed
+}
+
+// validateCoinSelection returns an error if the coins
+// funding operations cannot be selected from the account
+// in metadata.
+func (s *ConstructionAPIService) validateCoinSelection(
+	operations []*types.Operation,
+	metadata *preprocessMetadata,
+) *types.Error {
+	for _, operation := range operations {
+		if operation.Type != bitcoin.OutputOpType {
+			return wrapErr(
+				ErrUnclearIntent,
+				fmt.Errorf(
+					"operation %d must be an output when coins are selected",
+					operation.OperationIdentifier.Index,
+				),
+			)
+		}
+	}
+
+	if err := validCoinSelection(metadata.CoinSelection); err != nil {
+		return wrapErr(ErrUnclearIntent, err)
+	}
+
+	if metadata.Sweep {
+		if len(operations) != 1 || operations[0].Account == nil || operations[0].Amount != nil {
+			return wrapErr(
+				ErrUnclearIntent,
+				errors.New("a sweep has a single output with an account and no amount"),
+			)
+		}
+
+		if len(metadata.ChangeAddress) > 0 {
+			return wrapErr(ErrUnclearIntent, errors.New("a sweep has no change address"))
+		}
+
+		if _, rErr := s.decodeAddress(operations[0].Account.Address); rErr != nil {
+			return rErr
+		}
+	}
+
+	if _, rErr := s.decodeAddress(metadata.From.Address); rErr != nil {
+		return rErr
+	}
+
+	if len(metadata.ChangeAddress) > 0 {
+		if _, rErr := s.decodeAddress(metadata.ChangeAddress); rErr != nil {
+			return rErr
+		}
+	}
+
+	return nil
+}
+
+// estimateInputSize returns the estimated size of an INPUT
+// operation based on the script class of its address. It
+// returns false if the class or redeem script is unknown.
+func (s *ConstructionAPIService) estimateInputSize(
+	operation *types.Operation,
+) (int, txscript.ScriptClass, bool) {
+	if operation.Account == nil {
+		return 0, txscript.NonStandardTy, false
+	}
+
+	addr, rErr := s.decodeAddress(operation.Account.Address)
+	if rErr != nil {
+		return 0, txscript.NonStandardTy, false
+	}
+
+	switch addr.(type) {
+	case *btcutil.AddressPubKeyHash:
+		format, err := inputPublicKeyFormat(operation, txscript.PubKeyHashTy)
+		if err != nil {
+			return 0, txscript.PubKeyHashTy, false
+		}
+
+		return bitcoin.EstimateInputSize(
+			s.config.Params,
+			txscript.PubKeyHashTy,
+			nil,
+			format == publicKeyCompressed,
+		), txscript.PubKeyHashTy, true
+	case *btcutil.AddressWitnessPubKeyHash:
+		return bitcoin.EstimateInputSize(
+			s.config.Params,
+			txscript.WitnessV0PubKeyHashTy,
+			nil,
+			true,
+		), txscript.WitnessV0PubKeyHashTy, true
+	case *btcutil.AddressScriptHash:
+		var metadata inputMetadata
+		if err := types.UnmarshalMap(operation.Metadata, &metadata); err != nil {
+			return 0, txscript.ScriptHashTy, false
+		}
+
+		redeemScript, err := hex.DecodeString(metadata.RedeemScript)
+		if err != nil || len(redeemScript) == 0 {
+			return 0, txscript.ScriptHashTy, false
+		}
+
+		return bitcoin.EstimateInputSize(
+			s.config.Params,
+			txscript.ScriptHashTy,
+			redeemScript,
+			true,
+		), txscript.ScriptHashTy, true
+	default:
+		return 0, txscript.NonStandardTy, false
+	}
+}
+
+// decodeAddress decodes an address of the configured
+// network. Addresses of other networks are refused with
+// ErrForeignAddress naming the networks detected.
+func (s *ConstructionAPIService) decodeAddress(address string) (btcutil.Address, *types.Error) {
+	return decodeNetworkAddress(address, s.config.Params)
+}
+
+// decodeNetworkAddress decodes an address of the network
+// of params, refusing addresses of other networks with
+// ErrForeignAddress.
+func decodeNetworkAddress(address string, params *chaincfg.Params) (btcutil.Address, *types.Error) {
+	addr, err := dogecoin.DecodeAddress(address, params)
+	var foreignErr *dogecoin.ForeignAddressError
+	if errors.As(err, &foreignErr) {
+		rErr := wrapErr(ErrForeignAddress, err)
+		rErr.Details["address"] = address
+		rErr.Details["networks"] = foreignErr.Networks
+
+		return nil, rErr
+	}
+
+	if err != nil {
+		return nil, wrapErr(
+			ErrUnableToDecodeAddress,
+			fmt.Errorf("%w unable to decode address %s", err, address),
+		)
+	}
+
+	return addr, nil
+}
+
+// validateAddresses ensures the account of every operation
+// is an address of the configured network, so sizes are not
+// estimated for addresses that cannot be paid or spent.
+func (s *ConstructionAPIService) validateAddresses(operations []*types.Operation) *types.Error {
+	for _, operation := range operations {
+		if operation.Account == nil {
+			continue
+		}
+
+		if _, rErr := s.decodeAddress(operation.Account.Address); rErr != nil {
+			rErr.Details["operation_index"] = operation.OperationIdentifier.Index
+
+			return rErr
+		}
+	}
+
+	return nil
+}
+
+// validateOutputs ensures no OUTPUT operation pays less than
+// the dust limit and no change output, paying back to an input
+// address, is below the minimum change.
+func (s *ConstructionAPIService) validateOutputs(operations []*types.Operation) *types.Error {
+	if s.config.Construction == nil {
+		return nil
+	}
+
+	inputAddresses := map[string]struct{}{}
+	for _, operation := range operations {
+		if operation.Type == bitcoin.InputOpType && operation.Account != nil {
+			inputAddresses[operation.Account.Address] = struct{}{}
+		}
+	}
+
+	for _, operation := range operations {
+		// Data outputs have no account and are
+		// exempt from the dust limit.
+		if operation.Type != bitcoin.OutputOpType ||
+			operation.Account == nil ||
+			operation.Amount == nil {
+			continue
+		}
+
+		amount, err := types.AmountValue(operation.Amount)
+		if err != nil {
+			return wrapErr(ErrUnclearIntent, err)
+		}
+
+		limit := s.config.Construction.DustLimit
+		reason := "dust limit"
+		if _, ok := inputAddresses[operation.Account.Address]; ok &&
+			s.config.Construction.MinChange > limit {
+			limit = s.config.Construction.MinChange
+			reason = "minimum change"
+		}
+
+		if amount.Int64() >= limit {
+			continue
+		}
+
+		index := operation.OperationIdentifier.Index
+		rErr := wrapErr(ErrOutputBelowDustLimit, fmt.Errorf(
+			"operation %d amount %s is below the %s of %d",
+			index,
+			amount.String(),
+			reason,
+			limit,
+		))
+		rErr.Details["operation_index"] = index
+
+		return rErr
+	}
+
+	return nil
+}
+
+// ConstructionPreprocess implements the /construction/preprocess
+// endpoint.
+func (s *ConstructionAPIService) ConstructionPreprocess(
+	ctx context.Context,
+	request *types.ConstructionPreprocessRequest,
+) (*types.ConstructionPreprocessResponse, *types.Error) {
+	descriptions := &parser.Descriptions{
+		OperationDescriptions: []*parser.OperationDescription{
+			{
+				Type: bitcoin.InputOpType,
+				Account: &parser.AccountDescription{
+					Exists: true,
+				},
+				Amount: &parser.AmountDescription{
+					Exists:   true,
+					Sign:     parser.NegativeAmountSign,
+					Currency: s.config.Currency,
+				},
+				CoinAction:   types.CoinSpent,
+				AllowRepeats: true,
+			},
+		},
+	}
+
+	var metadata preprocessMetadata
+	if err := types.UnmarshalMap(request.Metadata, &metadata); err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	if metadata.FeeRate < 0 {
+		return nil, wrapErr(ErrUnclearIntent, fmt.Errorf("fee rate %d is negative", metadata.FeeRate))
+	}
+
+	// When an account is provided, ConstructionMetadata
+	// selects the coins funding the OUTPUT operations.
+	coins := []*types.Coin{}
+	if metadata.BumpCoin != nil {
+		if rErr := s.validateFeeBump(request.Operations, &metadata); rErr != nil {
+			return nil, rErr
+		}
+	} else if metadata.From == nil {
+		if metadata.Sweep {
+			return nil, wrapErr(ErrUnclearIntent, errors.New("a sweep requires an account"))
+		}
+
+		matches, err := parser.MatchOperations(descriptions, request.Operations)
+		if err != nil {
+			return nil, wrapErr(ErrUnclearIntent, err)
+		}
+
+		if len(metadata.ScriptPubKeys) > 0 && len(metadata.ScriptPubKeys) != len(matches[0].Operations) {
+			return nil, wrapErr(ErrUnclearIntent, fmt.Errorf(
+				"expected %d script pub keys, got %d",
+				len(matches[0].Operations),
+				len(metadata.ScriptPubKeys),
+			))
+		}
+
+		coins = make([]*types.Coin, len(matches[0].Operations))
+		for i, input := range matches[0].Operations {
+			if input.CoinChange == nil {
+				return nil, wrapErr(ErrUnclearIntent, errors.New("CoinChange cannot be nil"))
+			}
+
+			coins[i] = &types.Coin{
+				CoinIdentifier: input.CoinChange.CoinIdentifier,
+				Amount:         input.Amount,
+			}
+		}
+	} else if rErr := s.validateCoinSelection(request.Operations, &metadata); rErr != nil {
+		return nil, rErr
+	} else if len(metadata.ScriptPubKeys) > 0 {
+		return nil, wrapErr(
+			ErrUnclearIntent,
+			errors.New("script pub keys cannot be provided for selected coins"),
+		)
+	}
+
+	if rErr := s.validateAddresses(request.Operations); rErr != nil {
+		return nil, rErr
+	}
+
+	if rErr := s.validateOutputs(request.Operations); rErr != nil {
+		return nil, rErr
+	}
+
+	if _, rErr := s.inputSequences(request.Operations, metadata.LockTime); rErr != nil {
+		return nil, rErr
+	}
+
+	// Data outputs are unspendable, so they are not
+	// dust and are left out of the fee calculation.
+	outputAmounts := []string{}
+	for _, operation := range request.Operations {
+		if operation.Type != bitcoin.OutputOpType || operation.Amount == nil {
+			continue
+		}
+
+		if data, err := nullData(operation); err == nil && data != nil {
+			continue
+		}
+
+		outputAmounts = append(outputAmounts, operation.Amount.Value)
+	}
+
+	estimatedSize, unestimatedInputs := s.estimateSize(request.Operations)
+	preprocess := &preprocessOptions{
+		Coins:             coins,
+		EstimatedSize:     estimatedSize,
+		FeeMultiplier:     request.SuggestedFeeMultiplier,
+		OutputAmounts:     outputAmounts,
+		UnestimatedInputs: unestimatedInputs,
+		PSBT:              metadata.PSBT,
+		LockTime:          metadata.LockTime,
+		AllowHighFee:      metadata.AllowHighFee,
+		BIP69:             metadata.BIP69,
+		FeeRate:           metadata.FeeRate,
+		ScriptPubKeys:     metadata.ScriptPubKeys,
+	}
+
+	// The scripts of the coins are checked against
+	// or derived from their addresses when they are
+	// not recorded by the indexer.
+	if metadata.From == nil &&
+		metadata.BumpCoin == nil &&
+		(s.config.Mode != configuration.Online || len(metadata.ScriptPubKeys) > 0) {
+		for _, operation := range request.Operations {
+			if operation.Type == bitcoin.InputOpType {
+				preprocess.InputAddresses = append(preprocess.InputAddresses, operation.Account.Address)
+			}
+		}
+	}
+
+	if metadata.From != nil {
+		preprocess.From = metadata.From
+		preprocess.CoinSelection = metadata.CoinSelection
+		preprocess.ChangeAddress = metadata.ChangeAddress
+		if len(preprocess.ChangeAddress) == 0 {
+			preprocess.ChangeAddress = metadata.From.Address
+		}
+
+		// Selected coins and the change output are
+		// assumed to be of the class of their address.
+		preprocess.InputSize = int64(s.addressInputSize(metadata.From.Address))
+		preprocess.ChangeSize = int64(s.outputSize(&types.Operation{
+			Type:    bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{Address: preprocess.ChangeAddress},
+		}))
+
+		// The output of a sweep is part of
+		// EstimatedSize and paid as change.
+		if metadata.Sweep {
+			preprocess.Sweep = true
+			preprocess.MaxCoins = metadata.MaxCoins
+			preprocess.MaxInputSize = metadata.MaxInputSize
+			preprocess.ChangeAddress = request.Operations[0].Account.Address
+			preprocess.ChangeSize = 0
+		}
+	}
+
+	// The output of a fee bump is part of
+	// EstimatedSize and paid as change.
+	if metadata.BumpCoin != nil {
+		preprocess.BumpCoin = metadata.BumpCoin
+		preprocess.ChangeAddress = request.Operations[0].Account.Address
+	}
+
+	options, err := types.MarshalMap(preprocess)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	return &types.ConstructionPreprocessResponse{
+		Options: options,
+	}, nil
+}
+
+// ConstructionMetadata implements the /construction/metadata endpoint.
+func (s *ConstructionAPIService) ConstructionMetadata(
+	ctx context.Context,
+	request *types.ConstructionMetadataRequest,
+) (*types.ConstructionMetadataResponse, *types.Error) {
+	var options preprocessOptions
+	if err := types.UnmarshalMap(request.Options, &options); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	// Offline, the coins must be provided and
+	// their previous transactions are unknown.
+	if s.config.Mode != configuration.Online {
+		if options.From != nil {
+			return nil, wrapErr(ErrUnavailableOffline, errors.New("coins cannot be selected offline"))
+		}
+
+		if options.PSBT {
+			return nil, wrapErr(ErrUnavailableOffline, errors.New("psbts cannot be built offline"))
+		}
+
+		if options.BumpCoin != nil {
+			return nil, wrapErr(ErrUnavailableOffline, errors.New("fees cannot be bumped offline"))
+		}
+	}
+
+	feeRate, rErr := s.feeRate(ctx, &options)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	outputs, err := parseAmounts(options.OutputAmounts)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	var metadata constructionMetadata
+	var estimatedFee int64
+	switch {
+	case options.BumpCoin != nil:
+		fee, rErr := s.bumpCoin(ctx, &options, feeRate, &metadata)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		estimatedFee = fee
+	case options.Sweep:
+		fee, rErr := s.sweepCoins(ctx, &options, feeRate, &metadata)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		estimatedFee = fee
+	case options.From != nil:
+		fee, rErr := s.selectCoins(ctx, &options, feeRate, outputs, &metadata)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		estimatedFee = fee
+	}
+
+	scripts, rErr := s.scriptPubKeys(ctx, &options)
+	if rErr != nil {
+		return nil, rErr
+	}
+	metadata.ScriptPubKeys = scripts
+	metadata.LockTime = options.LockTime
+
+	// The previous transaction of a bumped
+	// coin is provided by the node.
+	if options.PSBT && options.BumpCoin == nil {
+		metadata.PreviousTransactions, err = s.i.GetRawTransactions(ctx, options.Coins)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToGetRawTransactions, err)
+		}
+	}
+
+	if options.From == nil && options.BumpCoin == nil {
+		// Inputs whose script class was not known during preprocess
+		// are estimated from the ScriptPubKeys they spend.
+		estimatedSize := options.EstimatedSize
+		for _, index := range options.UnestimatedInputs {
+			if index < 0 || index >= len(scripts) {
+				return nil, wrapErr(
+					ErrUnableToParseIntermediateResult,
+					fmt.Errorf("unestimated input %d is out of range", index),
+				)
+			}
+
+			script, err := hex.DecodeString(scripts[index].Hex)
+			if err != nil {
+				return nil, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+			}
+
+			// Redeem scripts recorded by the indexer
+			// refine the estimate of P2SH inputs.
+			redeemScript, _ := hex.DecodeString(scripts[index].RedeemScript)
+			estimatedSize += float64(bitcoin.EstimateInputSize(
+				s.config.Params,
+				txscript.GetScriptClass(script),
+				redeemScript,
+				true,
+			))
+		}
+
+		// Calculated the estimated fee in Satoshis
+		estimatedFee = s.feePolicy.Fee(feeRate, int64(math.Ceil(estimatedSize)), outputs)
+	}
+
+	suggestedFee := &types.Amount{
+		Value:    strconv.FormatInt(estimatedFee, 10),
+		Currency: s.config.Currency,
+	}
+	metadata.SuggestedFee = estimatedFee
+	metadata.AllowHighFee = options.AllowHighFee
+	metadata.BIP69 = options.BIP69
+
+	metadataMap, err := types.MarshalMap(&metadata)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	return &types.ConstructionMetadataResponse{
+		Metadata:     metadataMap,
+		SuggestedFee: []*types.Amount{suggestedFee},
+	}, nil
+}
+
+// feeRate returns the fee rate in Satoshis per kB of the
+// transaction of options. A fee rate provided by the caller
+// is used as is. Otherwise, the fee rate is determined by the
+// fee policy. Dogecoin Core often cannot estimate a fee rate,
+// in which case the fee policy falls back to its default.
+func (s *ConstructionAPIService) feeRate(
+	ctx context.Context,
+	options *preprocessOptions,
+) (int64, *types.Error) {
+	if options.FeeRate == 0 {
+		estimate := float64(-1)
+		if s.config.Mode == configuration.Online {
+			var err error
+			estimate, err = s.client.SuggestedFeeRate(ctx, defaultConfirmationTarget)
+			if err != nil {
+				estimate = -1
+			}
+		}
+
+		return s.feePolicy.FeeRate(estimate, options.FeeMultiplier), nil
+	}
+
+	if minRelayFeeRate := s.feePolicy.MinRelayFee(bytesPerKB, nil); options.FeeRate < minRelayFeeRate {
+		return 0, wrapErr(ErrInsufficientFee, fmt.Errorf(
+			"fee rate of %d per kB is below the minimum relay fee rate of %d per kB",
+			options.FeeRate,
+			minRelayFeeRate,
+		))
+	}
+
+	if s.config.Construction != nil &&
+		s.config.Construction.MaxFeeRate > 0 &&
+		options.FeeRate > s.config.Construction.MaxFeeRate {
+		return 0, wrapErr(ErrFeeRateTooHigh, fmt.Errorf(
+			"fee rate of %d per kB exceeds the maximum fee rate of %d per kB",
+			options.FeeRate,
+			s.config.Construction.MaxFeeRate,
+		))
+	}
+
+	return options.FeeRate, nil
+}
+
+// scriptPubKeys returns the ScriptPubKeys of the coins of
+// options. Scripts provided by the caller are checked against
+// the addresses of the coins. Offline, the scripts are derived
+// from the addresses of the coins. Otherwise, they are the
+// scripts recorded by the indexer.
+func (s *ConstructionAPIService) scriptPubKeys(
+	ctx context.Context,
+	options *preprocessOptions,
+) ([]*bitcoin.ScriptPubKey, *types.Error) {
+	if len(options.ScriptPubKeys) == 0 && s.config.Mode == configuration.Online {
+		scripts, err := s.i.GetScriptPubKeys(ctx, options.Coins)
+		if err != nil {
+			return nil, wrapErr(ErrScriptPubKeysMissing, err)
+		}
+
+		return scripts, nil
+	}
+
+	if len(options.InputAddresses) != len(options.Coins) {
+		return nil, wrapErr(ErrScriptPubKeysMissing, fmt.Errorf(
+			"expected %d input addresses, got %d",
+			len(options.Coins),
+			len(options.InputAddresses),
+		))
+	}
+
+	scripts := make([]*bitcoin.ScriptPubKey, len(options.Coins))
+	for i, address := range options.InputAddresses {
+		addr, rErr := s.decodeAddress(address)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		expected, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToDecodeAddress, err)
+		}
+
+		script := expected
+		if len(options.ScriptPubKeys) > 0 {
+			script, err = hex.DecodeString(options.ScriptPubKeys[i])
+			if err != nil {
+				return nil, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+			}
+
+			if !bytes.Equal(script, expected) {
+				return nil, wrapErr(
+					ErrUnableToDecodeScriptPubKey,
+					fmt.Errorf("script pub key %d does not pay to %s", i, address),
+				)
+			}
+		}
+
+		scripts[i], rErr = s.scriptPubKey(script)
+		if rErr != nil {
+			return nil, rErr
+		}
+	}
+
+	return scripts, nil
+}
+
+// scriptPubKey returns the *bitcoin.ScriptPubKey of
+// script, like the one returned by bitcoind.
+func (s *ConstructionAPIService) scriptPubKey(script []byte) (*bitcoin.ScriptPubKey, *types.Error) {
+	asm, err := txscript.DisasmString(script)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+	}
+
+	class, addrs, requiredSigs, err := txscript.ExtractPkScriptAddrs(script, s.config.Params)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+	}
+
+	var addresses []string
+	for _, addr := range addrs {
+		addresses = append(addresses, addr.EncodeAddress())
+	}
+
+	return &bitcoin.ScriptPubKey{
+		ASM:          asm,
+		Hex:          hex.EncodeToString(script),
+		RequiredSigs: int64(requiredSigs),
+		Type:         class.String(),
+		Addresses:    addresses,
+	}, nil
+}
+
+// ConstructionPayloads implements the /construction/payloads endpoint.
+func (s *ConstructionAPIService) ConstructionPayloads(
+	ctx context.Context,
+	request *types.ConstructionPayloadsRequest,
+) (*types.ConstructionPayloadsResponse, *types.Error) {
+	descriptions := &parser.Descriptions{
+		OperationDescriptions: []*parser.OperationDescription{
+			{
+				Type: bitcoin.InputOpType,
+				Account: &parser.AccountDescription{
+					Exists: true,
+				},
+				Amount: &parser.AmountDescription{
+					Exists:   true,
+					Sign:     parser.NegativeAmountSign,
+					Currency: s.config.Currency,
+				},
+				AllowRepeats: true,
+				CoinAction:   types.CoinSpent,
+			},
+			{
+				// Data outputs have no account, so we validate
+				// accounts and amounts in outputScript.
+				Type: bitcoin.OutputOpType,
+				Amount: &parser.AmountDescription{
+					Exists:   true,
+					Sign:     parser.PositiveOrZeroAmountSign,
+					Currency: s.config.Currency,
+				},
+				AllowRepeats: true,
+			},
+		},
+		ErrUnmatched: true,
+	}
+
+	var metadata constructionMetadata
+	if err := types.UnmarshalMap(request.Metadata, &metadata); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	// Coins selected in ConstructionMetadata are
+	// spent by the OUTPUT only intent.
+	operations := request.Operations
+	if metadata.Account != nil {
+		operations = selectedOperations(operations, &metadata)
+	}
+
+	matches, err := parser.MatchOperations(descriptions, operations)
+	if err != nil {
+		return nil, wrapErr(ErrUnclearIntent, err)
+	}
+
+	if rErr := s.validateAddresses(operations); rErr != nil {
+		return nil, rErr
+	}
+
+	if rErr := s.validateOutputs(operations); rErr != nil {
+		return nil, rErr
+	}
+
+	if metadata.BIP69 {
+		if err := sortInputs(matches[0].Operations, matches[0].Amounts, &metadata); err != nil {
+			return nil, wrapErr(ErrUnclearIntent, fmt.Errorf("%w unable to sort inputs", err))
+		}
+	}
+
+	spends, lockTime, rErr := s.scriptHashInputs(
+		matches[0].Operations,
+		metadata.ScriptPubKeys,
+		metadata.LockTime,
+	)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	sequences, rErr := s.inputSequences(matches[0].Operations, lockTime)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	// OP_CHECKLOCKTIMEVERIFY fails when the
+	// sequence of the input is final.
+	for _, index := range spends.LockedInputs {
+		if sequences[index] == wire.MaxTxInSequenceNum {
+			return nil, wrapErr(
+				ErrInvalidLockTime,
+				fmt.Errorf("input %d spends a lock time script with a final sequence", index),
+			)
+		}
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.LockTime = lockTime
+	for i, input := range matches[0].Operations {
+		if input.CoinChange == nil {
+			return nil, wrapErr(ErrUnclearIntent, errors.New("CoinChange cannot be nil"))
+		}
+
+		transactionHash, index, err := bitcoin.ParseCoinIdentifier(input.CoinChange.CoinIdentifier)
+		if err != nil {
+			return nil, wrapErr(ErrInvalidCoin, err)
+		}
+
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{
+				Hash:  *transactionHash,
+				Index: index,
+			},
+			SignatureScript: nil,
+			Sequence:        sequences[i],
+		})
+	}
+
+	dataOutputs := 0
+	outputRedeemScripts := make([]string, len(matches[1].Operations))
+	hasOutputRedeemScripts := false
+	for i, output := range matches[1].Operations {
+		pkScript, isData, rErr := s.outputScript(output, matches[1].Amounts[i])
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		outputRedeemScripts[i], rErr = outputRedeemScript(output, pkScript)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		if len(outputRedeemScripts[i]) > 0 {
+			hasOutputRedeemScripts = true
+		}
+
+		if isData {
+			dataOutputs++
+		}
+
+		if dataOutputs > 1 {
+			return nil, wrapErr(
+				ErrUnclearIntent,
+				errors.New("only one data output is allowed per transaction"),
+			)
+		}
+
+		tx.AddTxOut(&wire.TxOut{
+			Value:    matches[1].Amounts[i].Int64(),
+			PkScript: pkScript,
+		})
+	}
+
+	// The operations of the sorted inputs and outputs
+	// are recorded for ConstructionParse.
+	var inputIndexes, outputIndexes []int64
+	if metadata.BIP69 {
+		inputIndexes = make([]int64, len(matches[0].Operations))
+		for i, input := range matches[0].Operations {
+			inputIndexes[i] = input.OperationIdentifier.Index
+		}
+
+		order := bip69OutputOrder(tx.TxOut)
+		txOuts := make([]*wire.TxOut, len(order))
+		redeemScripts := make([]string, len(order))
+		outputIndexes = make([]int64, len(order))
+		for i, index := range order {
+			txOuts[i] = tx.TxOut[index]
+			redeemScripts[i] = outputRedeemScripts[index]
+			outputIndexes[i] = matches[1].Operations[index].OperationIdentifier.Index
+		}
+		tx.TxOut = txOuts
+		outputRedeemScripts = redeemScripts
+	}
+
+	if rErr := s.checkImpliedFee(matches[0].Amounts, tx, &metadata); rErr != nil {
+		return nil, rErr
+	}
+
+	// Create Signing Payloads (must be done after entire tx is constructed
+	// or hash will not be correct).
+	inputAmounts := make([]string, len(tx.TxIn))
+	inputAddresses := make([]string, len(tx.TxIn))
+	scripts := make([][]byte, len(tx.TxIn))
+	sigHashTypes := make([]txscript.SigHashType, len(tx.TxIn))
+	hasSigHashTypes := false
+	publicKeyFormats := make([]string, len(tx.TxIn))
+	hasUncompressed := false
+	payloads := []*types.SigningPayload{}
+
+	for i := range tx.TxIn {
+		address := matches[0].Operations[i].Account.Address
+		script, err := hex.DecodeString(metadata.ScriptPubKeys[i].Hex)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+		}
+		scripts[i] = script
+
+		class, _, err := bitcoin.ParseSingleAddress(s.config.Params, script)
+		if err != nil {
+			return nil, wrapErr(
+				ErrUnableToDecodeAddress,
+				fmt.Errorf("%w unable to parse address for utxo %d", err, i),
+			)
+		}
+
+		inputAddresses[i] = address
+		inputAmounts[i] = matches[0].Amounts[i].String()
+		absAmount := new(big.Int).Abs(matches[0].Amounts[i]).Int64()
+
+		hashType, err := inputSigHashType(matches[0].Operations[i])
+		if err != nil {
+			return nil, wrapErr(ErrUnclearIntent, fmt.Errorf("%w for input %d", err, i))
+		}
+
+		if hashType != txscript.SigHashAll {
+			hasSigHashTypes = true
+		}
+		sigHashTypes[i] = hashType
+
+		publicKeyFormats[i], err = inputPublicKeyFormat(matches[0].Operations[i], class)
+		if err != nil {
+			return nil, wrapErr(ErrUnclearIntent, fmt.Errorf("%w for input %d", err, i))
+		}
+
+		if publicKeyFormats[i] == publicKeyUncompressed {
+			hasUncompressed = true
+		}
+
+		switch class {
+		case txscript.PubKeyHashTy:
+			hash, err := signatureHash(tx, i, class, script, absAmount, hashType)
+			if err != nil {
+				return nil, wrapErr(ErrUnableToCalculateSignatureHash, err)
+			}
+
+			payloads = append(payloads, &types.SigningPayload{
+				AccountIdentifier: &types.AccountIdentifier{
+					Address: address,
+				},
+				Bytes:         hash,
+				SignatureType: types.Ecdsa,
+			})
+		case txscript.ScriptHashTy:
+			redeemScript, err := hex.DecodeString(spends.RedeemScripts[i])
+			if err != nil {
+				return nil, wrapErr(ErrUnableToDecodeRedeemScript, err)
+			}
+
+			preimage, err := hex.DecodeString(spends.Preimages[i])
+			if err != nil {
+				return nil, wrapErr(ErrInvalidPreimage, err)
+			}
+
+			signers, err := s.scriptHashSigners(
+				redeemScript,
+				preimage,
+				matches[0].Operations[i].Metadata,
+			)
+			if err != nil {
+				return nil, wrapErr(
+					ErrUnableToDecodeRedeemScript,
+					fmt.Errorf("%w for utxo %d", err, i),
+				)
+			}
+
+			hash, err := signatureHash(tx, i, class, redeemScript, absAmount, hashType)
+			if err != nil {
+				return nil, wrapErr(ErrUnableToCalculateSignatureHash, err)
+			}
+
+			for _, signer := range signers {
+				payloads = append(payloads, &types.SigningPayload{
+					AccountIdentifier: &types.AccountIdentifier{
+						Address: signer.AddressPubKeyHash().EncodeAddress(),
+					},
+					Bytes:         hash,
+					SignatureType: types.Ecdsa,
+				})
+			}
+
+		case txscript.WitnessV0PubKeyHashTy:
+			hash, err := signatureHash(tx, i, class, script, absAmount, hashType)
+			if err != nil {
+				return nil, wrapErr(ErrUnableToCalculateSignatureHash, err)
+			}
+
+			payloads = append(payloads, &types.SigningPayload{
+				AccountIdentifier: &types.AccountIdentifier{
+					Address: address,
+				},
 				Bytes:         hash,
 				SignatureType: types.Ecdsa,
-			}
+			})
 		default:
 			return nil, wrapErr(
 				ErrUnsupportedScriptType,
@@ -367,34 +1620,730 @@ func (s *ConstructionAPIService) ConstructionPayloads(
 		}
 	}
 
-	buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
-	if err := tx.Serialize(buf); err != nil {
-		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+	if err := tx.Serialize(buf); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	unsigned := &unsignedTransaction{
+		Transaction:    hex.EncodeToString(buf.Bytes()),
+		ScriptPubKeys:  metadata.ScriptPubKeys,
+		InputAmounts:   inputAmounts,
+		InputAddresses: inputAddresses,
+		InputIndexes:   inputIndexes,
+		OutputIndexes:  outputIndexes,
+	}
+	for _, redeemScript := range spends.RedeemScripts {
+		if len(redeemScript) > 0 {
+			unsigned.RedeemScripts = spends.RedeemScripts
+			break
+		}
+	}
+
+	for _, preimage := range spends.Preimages {
+		if len(preimage) > 0 {
+			unsigned.Preimages = spends.Preimages
+			break
+		}
+	}
+
+	if hasOutputRedeemScripts {
+		unsigned.OutputRedeemScripts = outputRedeemScripts
+	}
+
+	if hasSigHashTypes {
+		unsigned.SigHashTypes = sigHashTypes
+	}
+
+	if hasUncompressed {
+		unsigned.PublicKeyFormats = publicKeyFormats
+	}
+
+	if len(metadata.PreviousTransactions) > 0 {
+		unsigned.PSBT, err = buildPSBT(
+			tx,
+			s.config.Network,
+			unsigned,
+			metadata.PreviousTransactions,
+			scripts,
+		)
+		if err != nil {
+			return nil, wrapErr(
+				ErrUnableToParseIntermediateResult,
+				fmt.Errorf("%w unable to build psbt", err),
+			)
+		}
+	}
+
+	rawTx, rErr := encodeEnvelope(unsignedEnvelope, s.config.Network, unsigned)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	return &types.ConstructionPayloadsResponse{
+		UnsignedTransaction: rawTx,
+		Payloads:            payloads,
+	}, nil
+}
+
+// checkImpliedFee returns ErrFeeTooHigh if the fee implied
+// by the input amounts and the outputs of tx exceeds the
+// configured maximum fee or multiple of the suggested fee.
+func (s *ConstructionAPIService) checkImpliedFee(
+	inputs []*big.Int,
+	tx *wire.MsgTx,
+	metadata *constructionMetadata,
+) *types.Error {
+	if s.config.Construction == nil || metadata.AllowHighFee {
+		return nil
+	}
+
+	// Input amounts are negative.
+	fee := int64(0)
+	for _, input := range inputs {
+		fee -= input.Int64()
+	}
+	for _, output := range tx.TxOut {
+		fee -= output.Value
+	}
+
+	var reason error
+	maxFee := s.config.Construction.MaxFee
+	maxMultiple := s.config.Construction.MaxFeeMultiple
+	switch {
+	case maxFee > 0 && fee > maxFee:
+		reason = fmt.Errorf("implied fee of %d exceeds the maximum fee of %d", fee, maxFee)
+	case maxMultiple > 0 && metadata.SuggestedFee > 0 &&
+		float64(fee) > maxMultiple*float64(metadata.SuggestedFee):
+		reason = fmt.Errorf(
+			"implied fee of %d exceeds %g times the suggested fee of %d",
+			fee,
+			maxMultiple,
+			metadata.SuggestedFee,
+		)
+	default:
+		return nil
+	}
+
+	rErr := wrapErr(ErrFeeTooHigh, reason)
+	rErr.Details["fee"] = fee
+	rErr.Details["suggested_fee"] = metadata.SuggestedFee
+
+	return rErr
+}
+
+// inputSigHashType returns the signature hash type
+// of an INPUT operation.
+func inputSigHashType(operation *types.Operation) (txscript.SigHashType, error) {
+	var metadata inputMetadata
+	if err := types.UnmarshalMap(operation.Metadata, &metadata); err != nil {
+		return 0, fmt.Errorf("%w unable to unmarshal input metadata", err)
+	}
+
+	return bitcoin.ParseSigHashType(metadata.SigHashType)
+}
+
+// validateLockTime returns an error if lockTime is a
+// height below the BIP65 activation height. Such heights
+// are in the past, so they are most likely a mistake.
+func (s *ConstructionAPIService) validateLockTime(lockTime uint32) error {
+	if lockTime < txscript.LockTimeThreshold && int64(lockTime) < int64(s.config.Params.BIP0065Height) {
+		return fmt.Errorf(
+			"lock time height %d is below the BIP65 activation height %d",
+			lockTime,
+			s.config.Params.BIP0065Height,
+		)
+	}
+
+	return nil
+}
+
+// inputSequences returns the sequence numbers of the INPUT
+// operations. Inputs without a sequence are final, unless
+// the transaction has a lock time. It returns an error if
+// lockTime cannot be enforced.
+func (s *ConstructionAPIService) inputSequences(
+	operations []*types.Operation,
+	lockTime uint32,
+) ([]uint32, *types.Error) {
+	defaultSequence := uint32(wire.MaxTxInSequenceNum)
+	if lockTime > 0 {
+		defaultSequence = wire.MaxTxInSequenceNum - 1
+	}
+
+	sequences := []uint32{}
+	final := true
+	for _, operation := range operations {
+		if operation.Type != bitcoin.InputOpType {
+			continue
+		}
+
+		var metadata inputMetadata
+		if err := types.UnmarshalMap(operation.Metadata, &metadata); err != nil {
+			return nil, wrapErr(
+				ErrUnclearIntent,
+				fmt.Errorf("%w unable to unmarshal input metadata", err),
+			)
+		}
+
+		sequence := defaultSequence
+		if metadata.Sequence != nil {
+			sequence = *metadata.Sequence
+		}
+
+		final = final && sequence == wire.MaxTxInSequenceNum
+		sequences = append(sequences, sequence)
+	}
+
+	if lockTime == 0 {
+		return sequences, nil
+	}
+
+	if err := s.validateLockTime(lockTime); err != nil {
+		return nil, wrapErr(ErrInvalidLockTime, err)
+	}
+
+	// The lock time is ignored when every input is final.
+	if len(sequences) > 0 && final {
+		return nil, wrapErr(
+			ErrInvalidLockTime,
+			fmt.Errorf("lock time %d is not enforced when every input sequence is final", lockTime),
+		)
+	}
+
+	return sequences, nil
+}
+
+// signatureHash returns the hash signed for input index
+// of tx, which spends script of class. The script of P2SH
+// inputs is their redeem script.
+func signatureHash(
+	tx *wire.MsgTx,
+	index int,
+	class txscript.ScriptClass,
+	script []byte,
+	amount int64,
+	hashType txscript.SigHashType,
+) ([]byte, error) {
+	// Without a matching output, SIGHASH_SINGLE signs
+	// a constant instead of the transaction.
+	if hashType&^txscript.SigHashAnyOneCanPay == txscript.SigHashSingle && index >= len(tx.TxOut) {
+		return nil, fmt.Errorf("input %d has no output to sign with SIGHASH_SINGLE", index)
+	}
+
+	if class == txscript.WitnessV0PubKeyHashTy {
+		return txscript.CalcWitnessSigHash(
+			script,
+			txscript.NewTxSigHashes(tx),
+			hashType,
+			tx,
+			index,
+			amount,
+		)
+	}
+
+	return txscript.CalcSignatureHash(script, hashType, tx, index)
+}
+
+// nullData returns the data an OUTPUT operation carries
+// in its metadata or nil if it pays to an address.
+func nullData(operation *types.Operation) ([]byte, error) {
+	var metadata outputMetadata
+	if err := types.UnmarshalMap(operation.Metadata, &metadata); err != nil {
+		return nil, fmt.Errorf("%w unable to unmarshal output metadata", err)
+	}
+
+	if len(metadata.Data) == 0 {
+		return nil, nil
+	}
+
+	data, err := hex.DecodeString(metadata.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w unable to decode output data", err)
+	}
+
+	return data, nil
+}
+
+// outputScript returns the locking script of an OUTPUT
+// operation and whether it is an OP_RETURN data output.
+func (s *ConstructionAPIService) outputScript(
+	output *types.Operation,
+	amount *big.Int,
+) ([]byte, bool, *types.Error) {
+	data, err := nullData(output)
+	if err != nil {
+		return nil, false, wrapErr(ErrUnclearIntent, err)
+	}
+
+	if data != nil {
+		if output.Account != nil {
+			return nil, false, wrapErr(
+				ErrUnclearIntent,
+				errors.New("data output cannot have an account"),
+			)
+		}
+
+		if amount.Sign() != 0 {
+			return nil, false, wrapErr(
+				ErrUnclearIntent,
+				errors.New("data output must have a zero amount"),
+			)
+		}
+
+		pkScript, err := txscript.NullDataScript(data)
+		if err != nil {
+			return nil, false, wrapErr(
+				ErrUnclearIntent,
+				fmt.Errorf("%w unable to construct nullDataScript", err),
+			)
+		}
+
+		return pkScript, true, nil
+	}
+
+	if output.Account == nil {
+		return nil, false, wrapErr(
+			ErrUnclearIntent,
+			errors.New("output must have an account or data"),
+		)
+	}
+
+	if amount.Sign() <= 0 {
+		return nil, false, wrapErr(
+			ErrUnclearIntent,
+			fmt.Errorf("output to %s must have a positive amount", output.Account.Address),
+		)
+	}
+
+	addr, rErr := s.decodeAddress(output.Account.Address)
+	if rErr != nil {
+		return nil, false, rErr
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, false, wrapErr(
+			ErrUnableToDecodeAddress,
+			fmt.Errorf("%w unable to construct payToAddrScript", err),
+		)
+	}
+
+	return pkScript, false, nil
+}
+
+// inputRedeemScript returns the redeem script of a P2SH
+// input spending script. The redeem script is provided in
+// the metadata of the input or recorded by the indexer.
+func inputRedeemScript(
+	script []byte,
+	scriptPubKey *bitcoin.ScriptPubKey,
+	rawMetadata map[string]interface{},
+) ([]byte, error) {
+	var metadata inputMetadata
+	if err := types.UnmarshalMap(rawMetadata, &metadata); err != nil {
+		return nil, fmt.Errorf("%w unable to unmarshal input metadata", err)
+	}
+
+	rawRedeemScript := metadata.RedeemScript
+	if len(rawRedeemScript) == 0 {
+		rawRedeemScript = scriptPubKey.RedeemScript
+	}
+
+	if len(rawRedeemScript) == 0 {
+		return nil, errors.New("redeem script missing")
+	}
+
+	redeemScript, err := hex.DecodeString(rawRedeemScript)
+	if err != nil {
+		return nil, fmt.Errorf("%w unable to decode redeem script", err)
+	}
+
+	pushes, err := txscript.PushedData(script)
+	if err != nil || len(pushes) != 1 || !bytes.Equal(pushes[0], btcutil.Hash160(redeemScript)) {
+		return nil, errors.New("redeem script does not match script hash")
+	}
+
+	return redeemScript, nil
+}
+
+// scriptHashInputs returns the redeem scripts and the
+// preimages of the P2SH INPUT operations and the lock
+// time of the transaction spending them. Inputs spending
+// a lock time script or refunding a hash time locked
+// contract require a lock time of the same kind and not
+// below their own, which is the default lock time.
+func (s *ConstructionAPIService) scriptHashInputs(
+	inputs []*types.Operation,
+	scriptPubKeys []*bitcoin.ScriptPubKey,
+	lockTime uint32,
+) (*scriptHashSpends, uint32, *types.Error) {
+	spends := &scriptHashSpends{
+		RedeemScripts: make([]string, len(inputs)),
+		Preimages:     make([]string, len(inputs)),
+		LockedInputs:  []int{},
+	}
+	lockTimes := map[int]uint32{}
+	for i, input := range inputs {
+		if i >= len(scriptPubKeys) {
+			return nil, 0, wrapErr(
+				ErrScriptPubKeysMissing,
+				fmt.Errorf("script pub key missing for utxo %d", i),
+			)
+		}
+
+		script, err := hex.DecodeString(scriptPubKeys[i].Hex)
+		if err != nil {
+			return nil, 0, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+		}
+
+		if txscript.GetScriptClass(script) != txscript.ScriptHashTy {
+			continue
+		}
+
+		redeemScript, err := inputRedeemScript(script, scriptPubKeys[i], input.Metadata)
+		if err != nil {
+			return nil, 0, wrapErr(
+				ErrUnableToDecodeRedeemScript,
+				fmt.Errorf("%w for utxo %d", err, i),
+			)
+		}
+		spends.RedeemScripts[i] = hex.EncodeToString(redeemScript)
+
+		if _, inputLockTime, err := bitcoin.ParseLockTimeScript(s.config.Params, redeemScript); err == nil {
+			lockTimes[i] = uint32(inputLockTime)
+			spends.LockedInputs = append(spends.LockedInputs, i)
+			continue
+		}
+
+		htlc, err := bitcoin.ParseHTLCScript(s.config.Params, redeemScript)
+		if err != nil {
+			continue
+		}
+
+		var metadata inputMetadata
+		if err := types.UnmarshalMap(input.Metadata, &metadata); err != nil {
+			return nil, 0, wrapErr(
+				ErrUnclearIntent,
+				fmt.Errorf("%w unable to unmarshal input metadata", err),
+			)
+		}
+
+		if len(metadata.Preimage) == 0 {
+			lockTimes[i] = uint32(htlc.LockTime)
+			spends.LockedInputs = append(spends.LockedInputs, i)
+			continue
+		}
+
+		preimage, err := hex.DecodeString(metadata.Preimage)
+		if err != nil {
+			return nil, 0, wrapErr(ErrInvalidPreimage, err)
+		}
+
+		if hash := sha256.Sum256(preimage); !bytes.Equal(hash[:], htlc.PaymentHash) {
+			return nil, 0, wrapErr(
+				ErrInvalidPreimage,
+				fmt.Errorf("preimage of utxo %d does not match payment hash", i),
+			)
+		}
+		spends.Preimages[i] = metadata.Preimage
+	}
+
+	if lockTime == 0 {
+		for _, inputLockTime := range lockTimes {
+			if inputLockTime > lockTime {
+				lockTime = inputLockTime
+			}
+		}
+	}
+
+	for _, index := range spends.LockedInputs {
+		inputLockTime := lockTimes[index]
+		sameKind := (lockTime < txscript.LockTimeThreshold) == (inputLockTime < txscript.LockTimeThreshold)
+		if !sameKind || lockTime < inputLockTime {
+			return nil, 0, wrapErr(ErrInvalidLockTime, fmt.Errorf(
+				"lock time %d does not satisfy the lock time %d of input %d",
+				lockTime,
+				inputLockTime,
+				index,
+			))
+		}
+	}
+
+	return spends, lockTime, nil
+}
+
+// scriptHashSpend returns the public key signing a P2SH
+// input spending redeemScript and the data pushed between
+// its signature and the redeem script. Hash time locked
+// contracts are claimed when preimage is provided and
+// refunded otherwise. It returns an error if redeemScript
+// is not signed by a single public key.
+func (s *ConstructionAPIService) scriptHashSpend(
+	redeemScript []byte,
+	preimage []byte,
+) (*btcutil.AddressPubKey, [][]byte, error) {
+	if pubKey, _, err := bitcoin.ParseLockTimeScript(s.config.Params, redeemScript); err == nil {
+		return pubKey, nil, nil
+	}
+
+	htlc, err := bitcoin.ParseHTLCScript(s.config.Params, redeemScript)
+	if err != nil {
+		return nil, nil, errors.New("redeem script is not signed by a single public key")
+	}
+
+	// OP_TRUE selects the claim branch
+	// and OP_FALSE the refund branch.
+	if len(preimage) > 0 {
+		return htlc.Recipient, [][]byte{preimage, {1}}, nil
+	}
+
+	return htlc.Refund, [][]byte{{}}, nil
+}
+
+// scriptHashSigners returns the public keys expected to
+// sign a P2SH input spending redeemScript. Lock time
+// scripts and hash time locked contracts are signed by a
+// single public key and multisig scripts by the signers
+// of the input metadata.
+func (s *ConstructionAPIService) scriptHashSigners(
+	redeemScript []byte,
+	preimage []byte,
+	rawMetadata map[string]interface{},
+) ([]*btcutil.AddressPubKey, error) {
+	if pubKey, _, err := s.scriptHashSpend(redeemScript, preimage); err == nil {
+		return []*btcutil.AddressPubKey{pubKey}, nil
+	}
+
+	var metadata inputMetadata
+	if err := types.UnmarshalMap(rawMetadata, &metadata); err != nil {
+		return nil, fmt.Errorf("%w unable to unmarshal input metadata", err)
+	}
+
+	pubKeys, nRequired, err := bitcoin.ParseMultiSigScript(s.config.Params, redeemScript)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metadata.Signers) == 0 {
+		return pubKeys[:nRequired], nil
+	}
+
+	if len(metadata.Signers) != nRequired {
+		return nil, fmt.Errorf(
+			"expecting %d signers, got %d",
+			nRequired,
+			len(metadata.Signers),
+		)
+	}
+
+	signers := make([]*btcutil.AddressPubKey, len(metadata.Signers))
+	for i, signer := range metadata.Signers {
+		signerKey, err := hex.DecodeString(signer)
+		if err != nil {
+			return nil, fmt.Errorf("%w unable to decode signer %d", err, i)
+		}
+
+		index := pubKeyIndex(pubKeys, signerKey)
+		if index < 0 {
+			return nil, fmt.Errorf("signer %d is not in redeem script", i)
+		}
+
+		signers[i] = pubKeys[index]
+	}
+
+	return signers, nil
+}
+
+// outputRedeemScript returns the hex-encoded redeem script
+// in the metadata of an OUTPUT operation paying to pkScript,
+// or an empty string if there is none.
+func outputRedeemScript(output *types.Operation, pkScript []byte) (string, *types.Error) {
+	var metadata outputMetadata
+	if err := types.UnmarshalMap(output.Metadata, &metadata); err != nil {
+		return "", wrapErr(
+			ErrUnclearIntent,
+			fmt.Errorf("%w unable to unmarshal output metadata", err),
+		)
+	}
+
+	if len(metadata.RedeemScript) == 0 {
+		return "", nil
+	}
+
+	redeemScript, err := hex.DecodeString(metadata.RedeemScript)
+	if err != nil {
+		return "", wrapErr(ErrUnableToDecodeRedeemScript, err)
+	}
+
+	pushes, err := txscript.PushedData(pkScript)
+	if txscript.GetScriptClass(pkScript) != txscript.ScriptHashTy ||
+		err != nil ||
+		!bytes.Equal(pushes[0], btcutil.Hash160(redeemScript)) {
+		return "", wrapErr(
+			ErrUnableToDecodeRedeemScript,
+			fmt.Errorf(
+				"redeem script does not match the address of output %d",
+				output.OperationIdentifier.Index,
+			),
+		)
+	}
+
+	return metadata.RedeemScript, nil
+}
+
+// pubKeyIndex returns the position of a serialized
+// public key in a multisig redeem script or -1.
+func pubKeyIndex(pubKeys []*btcutil.AddressPubKey, pubKey []byte) int {
+	for i, key := range pubKeys {
+		if bytes.Equal(key.ScriptAddress(), pubKey) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func normalizeSignature(signature []byte, hashType txscript.SigHashType) []byte {
+	sig := btcec.Signature{ // signature is in form of R || S
+		R: new(big.Int).SetBytes(signature[:32]),
+		S: new(big.Int).SetBytes(signature[32:64]),
+	}
+
+	return append(sig.Serialize(), byte(hashType))
+}
+
+// inputSignature returns signature in the format of a
+// signature script for input index. Signatures may end
+// with their signature hash type, which must match
+// hashType, and must be for hash.
+func inputSignature(
+	signature *types.Signature,
+	index int,
+	hash []byte,
+	hashType txscript.SigHashType,
+) ([]byte, *types.Error) {
+	sig := signature.Bytes
+	switch len(sig) {
+	case 64: // nolint:gomnd
+	case 65: // nolint:gomnd
+		if txscript.SigHashType(sig[64]) != hashType {
+			return nil, wrapErr(
+				ErrInvalidSignatures,
+				fmt.Errorf(
+					"signature for input %d has sighash type %s, expected %s",
+					index,
+					bitcoin.SigHashTypeName(txscript.SigHashType(sig[64])),
+					bitcoin.SigHashTypeName(hashType),
+				),
+			)
+		}
+
+		sig = sig[:64]
+	default:
+		return nil, wrapErr(
+			ErrInvalidSignatures,
+			fmt.Errorf("signature for input %d has invalid length %d", index, len(sig)),
+		)
+	}
+
+	if signature.SigningPayload != nil &&
+		len(signature.SigningPayload.Bytes) > 0 &&
+		!bytes.Equal(signature.SigningPayload.Bytes, hash) {
+		return nil, wrapErr(
+			ErrInvalidSignatures,
+			fmt.Errorf(
+				"signing payload of input %d is not the %s signature hash",
+				index,
+				bitcoin.SigHashTypeName(hashType),
+			),
+		)
+	}
+
+	return normalizeSignature(sig, hashType), nil
+}
+
+// inputPublicKey returns the public key of a signature
+// for input index serialized in format. It returns
+// ErrPublicKeyMismatch if the key does not hash to the
+// public key hash of script.
+func (s *ConstructionAPIService) inputPublicKey(
+	publicKey *types.PublicKey,
+	format string,
+	index int,
+	script []byte,
+) ([]byte, *types.Error) {
+	pkData, err := serializePublicKey(publicKey, format)
+	if err != nil {
+		return nil, wrapErr(ErrInvalidSignatures, fmt.Errorf("%w for input %d", err, index))
+	}
+
+	_, addr, err := bitcoin.ParseSingleAddress(s.config.Params, script)
+	if err != nil {
+		return nil, wrapErr(
+			ErrUnableToDecodeAddress,
+			fmt.Errorf("%w unable to parse address for input %d", err, index),
+		)
+	}
+
+	if !bytes.Equal(btcutil.Hash160(pkData), addr.ScriptAddress()) {
+		rErr := wrapErr(ErrPublicKeyMismatch, fmt.Errorf(
+			"input %d: %s public key %x does not match its public key hash",
+			index,
+			format,
+			pkData,
+		))
+		rErr.Details["input_index"] = index
+
+		return nil, rErr
 	}
 
-	rawTx, err := json.Marshal(&unsignedTransaction{
-		Transaction:    hex.EncodeToString(buf.Bytes()),
-		ScriptPubKeys:  metadata.ScriptPubKeys,
-		InputAmounts:   inputAmounts,
-		InputAddresses: inputAddresses,
-	})
-	if err != nil {
-		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	return pkData, nil
+}
+
+// verifySignatures executes the scripts of every input
+// of tx against the output it spends, so signatures that
+// the node would reject are caught before submission.
+func verifySignatures(tx *wire.MsgTx, prevOuts []*wire.TxOut) *types.Error {
+	sigHashes := txscript.NewTxSigHashes(tx)
+	for i, prevOut := range prevOuts {
+		vm, err := txscript.NewEngine(
+			prevOut.PkScript,
+			tx,
+			i,
+			txscript.StandardVerifyFlags,
+			nil,
+			sigHashes,
+			prevOut.Value,
+		)
+		if err == nil {
+			err = vm.Execute()
+		}
+
+		if err != nil {
+			rErr := wrapErr(ErrSignatureVerificationFailed, fmt.Errorf("input %d: %w", i, err))
+			rErr.Details["input_index"] = i
+
+			return rErr
+		}
 	}
 
-	return &types.ConstructionPayloadsResponse{
-		UnsignedTransaction: hex.EncodeToString(rawTx),
-		Payloads:            payloads,
-	}, nil
+	return nil
 }
 
-func normalizeSignature(signature []byte) []byte {
-	sig := btcec.Signature{ // signature is in form of R || S
-		R: new(big.Int).SetBytes(signature[:32]),
-		S: new(big.Int).SetBytes(signature[32:64]),
+// inputAmount returns the absolute amount of
+// input index of unsigned.
+func inputAmount(unsigned *unsignedTransaction, index int) (int64, error) {
+	if index >= len(unsigned.InputAmounts) {
+		return 0, fmt.Errorf("amount missing for input %d", index)
 	}
 
-	return append(sig.Serialize(), byte(txscript.SigHashAll))
+	amount, ok := new(big.Int).SetString(unsigned.InputAmounts[index], 10)
+	if !ok {
+		return 0, fmt.Errorf("unable to parse amount %s of input %d", unsigned.InputAmounts[index], index)
+	}
+
+	return new(big.Int).Abs(amount).Int64(), nil
 }
 
 // ConstructionCombine implements the /construction/combine
@@ -403,20 +2352,28 @@ func (s *ConstructionAPIService) ConstructionCombine(
 	ctx context.Context,
 	request *types.ConstructionCombineRequest,
 ) (*types.ConstructionCombineResponse, *types.Error) {
-	decodedTx, err := hex.DecodeString(request.UnsignedTransaction)
+	// Partially or fully signed PSBTs are finalized
+	// with any additional signatures.
+	packet, isPSBT, err := decodePSBT(request.UnsignedTransaction)
 	if err != nil {
 		return nil, wrapErr(
 			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w transaction cannot be decoded", err),
+			fmt.Errorf("%w unable to decode psbt", err),
 		)
 	}
 
+	if isPSBT {
+		return s.combinePSBT(packet, request.Signatures)
+	}
+
 	var unsigned unsignedTransaction
-	if err := json.Unmarshal(decodedTx, &unsigned); err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to unmarshal bitcoin transaction", err),
-		)
+	if rErr := decodeEnvelope(
+		request.UnsignedTransaction,
+		unsignedEnvelope,
+		s.config.Network,
+		&unsigned,
+	); rErr != nil {
+		return nil, rErr
 	}
 
 	decodedCoreTx, err := hex.DecodeString(unsigned.Transaction)
@@ -435,6 +2392,8 @@ func (s *ConstructionAPIService) ConstructionCombine(
 		)
 	}
 
+	sigIndex := 0
+	prevOuts := make([]*wire.TxOut, len(tx.TxIn))
 	for i := range tx.TxIn {
 		decodedScript, err := hex.DecodeString(unsigned.ScriptPubKeys[i].Hex)
 		if err != nil {
@@ -449,102 +2408,574 @@ func (s *ConstructionAPIService) ConstructionCombine(
 			)
 		}
 
-		pkData := request.Signatures[i].PublicKey.Bytes
-		fullsig := normalizeSignature(request.Signatures[i].Bytes)
+		amount, err := inputAmount(&unsigned, i)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+		prevOuts[i] = &wire.TxOut{Value: amount, PkScript: decodedScript}
+
+		hashType := unsigned.sigHashType(i)
+		if class == txscript.ScriptHashTy {
+			sigScript, consumed, err := s.scriptHashScript(
+				&tx,
+				&unsigned,
+				i,
+				amount,
+				request.Signatures[sigIndex:],
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			tx.TxIn[i].SignatureScript = sigScript
+			sigIndex += consumed
+			continue
+		}
+
+		if sigIndex >= len(request.Signatures) {
+			return nil, wrapErr(
+				ErrInvalidSignatures,
+				fmt.Errorf("missing signature for input %d", i),
+			)
+		}
+
+		hash, err := signatureHash(&tx, i, class, decodedScript, amount, hashType)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToCalculateSignatureHash, err)
+		}
+
+		pkData, rErr := s.inputPublicKey(
+			request.Signatures[sigIndex].PublicKey,
+			unsigned.publicKeyFormat(i),
+			i,
+			decodedScript,
+		)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		fullsig, rErr := inputSignature(request.Signatures[sigIndex], i, hash, hashType)
+		if rErr != nil {
+			return nil, rErr
+		}
+		sigIndex++
+
+		switch class {
+		case txscript.PubKeyHashTy:
+			sigScript, err := txscript.NewScriptBuilder().
+				AddData(fullsig).
+				AddData(pkData).
+				Script()
+			if err != nil {
+				return nil, wrapErr(
+					ErrUnableToParseIntermediateResult,
+					fmt.Errorf("%w unable to build signature script", err),
+				)
+			}
+
+			tx.TxIn[i].SignatureScript = sigScript
+		case txscript.WitnessV0PubKeyHashTy:
+			tx.TxIn[i].Witness = wire.TxWitness{fullsig, pkData}
+		default:
+			return nil, wrapErr(
+				ErrUnsupportedScriptType,
+				fmt.Errorf("unupported script type: %s", class),
+			)
+		}
+	}
+
+	if sigIndex != len(request.Signatures) {
+		return nil, wrapErr(
+			ErrInvalidSignatures,
+			fmt.Errorf("expected %d signatures, got %d", sigIndex, len(request.Signatures)),
+		)
+	}
+
+	if rErr := verifySignatures(&tx, prevOuts); rErr != nil {
+		return nil, rErr
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+	if err := tx.Serialize(buf); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, fmt.Errorf("%w serialize tx", err))
+	}
+
+	rawTx, rErr := encodeEnvelope(signedEnvelope, s.config.Network, &signedTransaction{
+		Transaction:         hex.EncodeToString(buf.Bytes()),
+		InputAmounts:        unsigned.InputAmounts,
+		OutputRedeemScripts: unsigned.OutputRedeemScripts,
+		InputIndexes:        unsigned.InputIndexes,
+		OutputIndexes:       unsigned.OutputIndexes,
+	})
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	return &types.ConstructionCombineResponse{
+		SignedTransaction: rawTx,
+	}, nil
+}
+
+// scriptHashScript assembles the signature script of a
+// P2SH input from the next signatures. It returns the
+// number of signatures consumed.
+func (s *ConstructionAPIService) scriptHashScript(
+	tx *wire.MsgTx,
+	unsigned *unsignedTransaction,
+	index int,
+	amount int64,
+	signatures []*types.Signature,
+) ([]byte, int, *types.Error) {
+	if index >= len(unsigned.RedeemScripts) || len(unsigned.RedeemScripts[index]) == 0 {
+		return nil, 0, wrapErr(
+			ErrUnableToDecodeRedeemScript,
+			fmt.Errorf("redeem script missing for input %d", index),
+		)
+	}
+
+	redeemScript, err := hex.DecodeString(unsigned.RedeemScripts[index])
+	if err != nil {
+		return nil, 0, wrapErr(ErrUnableToDecodeRedeemScript, err)
+	}
+
+	preimage, err := hex.DecodeString(unsigned.preimage(index))
+	if err != nil {
+		return nil, 0, wrapErr(ErrInvalidPreimage, err)
+	}
+
+	pubKey, pushes, err := s.scriptHashSpend(redeemScript, preimage)
+	if err != nil {
+		return s.multiSigScript(tx, unsigned, index, amount, redeemScript, signatures)
+	}
+
+	if len(signatures) == 0 {
+		return nil, 0, wrapErr(
+			ErrInvalidSignatures,
+			fmt.Errorf("missing signature for input %d", index),
+		)
+	}
+
+	if !bytes.Equal(signatures[0].PublicKey.Bytes, pubKey.ScriptAddress()) {
+		return nil, 0, wrapErr(
+			ErrInvalidSignatures,
+			fmt.Errorf("public key %x is not in redeem script of input %d", signatures[0].PublicKey.Bytes, index),
+		)
+	}
+
+	hashType := unsigned.sigHashType(index)
+	hash, err := signatureHash(tx, index, txscript.ScriptHashTy, redeemScript, amount, hashType)
+	if err != nil {
+		return nil, 0, wrapErr(ErrUnableToCalculateSignatureHash, err)
+	}
+
+	sig, rErr := inputSignature(signatures[0], index, hash, hashType)
+	if rErr != nil {
+		return nil, 0, rErr
+	}
+
+	builder := txscript.NewScriptBuilder().AddData(sig)
+	for _, push := range pushes {
+		builder.AddData(push)
+	}
+
+	sigScript, err := builder.AddData(redeemScript).Script()
+	if err != nil {
+		return nil, 0, wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to build signature script", err),
+		)
+	}
+
+	return sigScript, 1, nil
+}
+
+// multiSigScript assembles the OP_0 <sigs> <redeemScript>
+// signature script of a P2SH multisig input from the next
+// m signatures. Signatures are ordered by the position of
+// their public key in the redeem script. It returns the
+// number of signatures consumed.
+func (s *ConstructionAPIService) multiSigScript(
+	tx *wire.MsgTx,
+	unsigned *unsignedTransaction,
+	index int,
+	amount int64,
+	redeemScript []byte,
+	signatures []*types.Signature,
+) ([]byte, int, *types.Error) {
+	pubKeys, nRequired, err := bitcoin.ParseMultiSigScript(s.config.Params, redeemScript)
+	if err != nil {
+		return nil, 0, wrapErr(ErrUnableToDecodeRedeemScript, err)
+	}
+
+	if len(signatures) < nRequired {
+		return nil, 0, wrapErr(
+			ErrInvalidSignatures,
+			fmt.Errorf("expected %d signatures for input %d, got %d", nRequired, index, len(signatures)),
+		)
+	}
+
+	hashType := unsigned.sigHashType(index)
+	hash, err := signatureHash(tx, index, txscript.ScriptHashTy, redeemScript, amount, hashType)
+	if err != nil {
+		return nil, 0, wrapErr(ErrUnableToCalculateSignatureHash, err)
+	}
+
+	ordered := make([][]byte, len(pubKeys))
+	for _, signature := range signatures[:nRequired] {
+		position := pubKeyIndex(pubKeys, signature.PublicKey.Bytes)
+		if position < 0 {
+			return nil, 0, wrapErr(
+				ErrInvalidSignatures,
+				fmt.Errorf("public key %x is not in redeem script of input %d", signature.PublicKey.Bytes, index),
+			)
+		}
+
+		if ordered[position] != nil {
+			return nil, 0, wrapErr(
+				ErrInvalidSignatures,
+				fmt.Errorf("duplicate signature for public key %x in input %d", signature.PublicKey.Bytes, index),
+			)
+		}
+
+		sig, rErr := inputSignature(signature, index, hash, hashType)
+		if rErr != nil {
+			return nil, 0, rErr
+		}
+
+		ordered[position] = sig
+	}
+
+	// OP_0 is required because of the off-by-one bug
+	// in OP_CHECKMULTISIG.
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+	for _, sig := range ordered {
+		if sig != nil {
+			builder.AddData(sig)
+		}
+	}
+
+	sigScript, err := builder.AddData(redeemScript).Script()
+	if err != nil {
+		return nil, 0, wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to build signature script", err),
+		)
+	}
+
+	return sigScript, nRequired, nil
+}
+
+// ConstructionHash implements the /construction/hash endpoint.
+func (s *ConstructionAPIService) ConstructionHash(
+	ctx context.Context,
+	request *types.ConstructionHashRequest,
+) (*types.TransactionIdentifierResponse, *types.Error) {
+	var signed signedTransaction
+	if rErr := decodeEnvelope(
+		request.SignedTransaction,
+		signedEnvelope,
+		s.config.Network,
+		&signed,
+	); rErr != nil {
+		return nil, rErr
+	}
+
+	bytesTx, err := hex.DecodeString(signed.Transaction)
+	if err != nil {
+		return nil, wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to decode hex transaction", err),
+		)
+	}
+
+	tx, err := btcutil.NewTxFromBytes(bytesTx)
+	if err != nil {
+		return nil, wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to parse transaction", err),
+		)
+	}
+
+	return &types.TransactionIdentifierResponse{
+		TransactionIdentifier: &types.TransactionIdentifier{
+			Hash: tx.Hash().String(),
+		},
+	}, nil
+}
+
+// parseOutput returns the OUTPUT operation of a transaction
+// output. OP_RETURN outputs are returned without an account
+// and with their data in the operation metadata.
+func (s *ConstructionAPIService) parseOutput(
+	output *wire.TxOut,
+	index int64,
+	networkIndex int64,
+	redeemScript string,
+) (*types.Operation, *types.Error) {
+	op := &types.Operation{
+		OperationIdentifier: &types.OperationIdentifier{
+			Index:        index,
+			NetworkIndex: &networkIndex,
+		},
+		Type: bitcoin.OutputOpType,
+		Amount: &types.Amount{
+			Value:    strconv.FormatInt(output.Value, 10),
+			Currency: s.config.Currency,
+		},
+	}
+	metadata := &outputMetadata{RedeemScript: redeemScript}
+	if txscript.GetScriptClass(output.PkScript) == txscript.NullDataTy {
+		pushes, err := txscript.PushedData(output.PkScript)
+		if err != nil {
+			return nil, wrapErr(
+				ErrUnableToDecodeScriptPubKey,
+				fmt.Errorf("%w unable to parse output data", err),
+			)
+		}
+
+		metadata = &outputMetadata{
+			Data: hex.EncodeToString(bytes.Join(pushes, nil)),
+		}
+	} else {
+		_, addr, err := bitcoin.ParseSingleAddress(s.config.Params, output.PkScript)
+		if err != nil {
+			return nil, wrapErr(
+				ErrUnableToDecodeAddress,
+				fmt.Errorf("%w unable to parse output address", err),
+			)
+		}
+
+		op.Account = &types.AccountIdentifier{
+			Address: addr.String(),
+		}
+	}
+
+	rawMetadata, err := types.MarshalMap(metadata)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	scriptPubKey, rErr := s.scriptPubKey(output.PkScript)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	op.Metadata, rErr = withParseOperationMetadata(rawMetadata, scriptPubKey)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	return op, nil
+}
+
+// withParseOperationMetadata adds the ParseOperationMetadata
+// of scriptPubKey to the metadata of an operation.
+func withParseOperationMetadata(
+	metadata map[string]interface{},
+	scriptPubKey *bitcoin.ScriptPubKey,
+) (map[string]interface{}, *types.Error) {
+	parseMetadata, err := types.MarshalMap(&ParseOperationMetadata{ScriptPubKey: scriptPubKey})
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	if metadata == nil {
+		return parseMetadata, nil
+	}
+
+	for key, value := range parseMetadata {
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
+
+// outputRedeemScriptAt returns the redeem script of
+// output index in redeemScripts, if any.
+func outputRedeemScriptAt(redeemScripts []string, index int) string {
+	if index >= len(redeemScripts) {
+		return ""
+	}
+
+	return redeemScripts[index]
+}
+
+// parseTransactionMetadata returns the metadata of tx
+// of size bytes returned from ConstructionParse.
+func parseTransactionMetadata(
+	tx *wire.MsgTx,
+	inputAmounts []string,
+	size int64,
+) (map[string]interface{}, *types.Error) {
+	fee, rErr := transactionFee(tx, inputAmounts)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	metadata, err := types.MarshalMap(&parseMetadata{
+		Fee:      fee,
+		Size:     size,
+		FeeRate:  fee * bytesPerKB / size,
+		LockTime: tx.LockTime,
+	})
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	return metadata, nil
+}
+
+// transactionFee returns the fee of tx implied
+// by the amounts of its inputs and its outputs.
+func transactionFee(tx *wire.MsgTx, inputAmounts []string) (int64, *types.Error) {
+	if len(inputAmounts) != len(tx.TxIn) {
+		return 0, wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf(
+				"expected %d input amounts, got %d",
+				len(tx.TxIn),
+				len(inputAmounts),
+			),
+		)
+	}
+
+	inputs, err := parseAmounts(inputAmounts)
+	if err != nil {
+		return 0, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	// Input amounts are negative.
+	fee := int64(0)
+	for _, input := range inputs {
+		fee -= input
+	}
+	for _, output := range tx.TxOut {
+		fee -= output.Value
+	}
+
+	return fee, nil
+}
+
+// estimateSignedSize returns the estimated serialized
+// size of the unsigned transaction tx once its inputs
+// are signed, like the size of a signed transaction
+// returned from ConstructionParse.
+func (s *ConstructionAPIService) estimateSignedSize(
+	tx *wire.MsgTx,
+	unsigned *unsignedTransaction,
+) (int64, *types.Error) {
+	if len(unsigned.ScriptPubKeys) != len(tx.TxIn) {
+		return 0, wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf(
+				"expected %d script pub keys, got %d",
+				len(tx.TxIn),
+				len(unsigned.ScriptPubKeys),
+			),
+		)
+	}
+
+	size := int64(tx.SerializeSize())
+	witness := false
+	for i, input := range tx.TxIn {
+		script, err := hex.DecodeString(unsigned.ScriptPubKeys[i].Hex)
+		if err != nil {
+			return 0, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+		}
+
+		var redeemScript []byte
+		if i < len(unsigned.RedeemScripts) {
+			redeemScript, err = hex.DecodeString(unsigned.RedeemScripts[i])
+			if err != nil {
+				return 0, wrapErr(ErrUnableToDecodeRedeemScript, err)
+			}
+		}
 
-		switch class {
-		case txscript.WitnessV0PubKeyHashTy:
-			tx.TxIn[i].Witness = wire.TxWitness{fullsig, pkData}
-		default:
-			return nil, wrapErr(
-				ErrUnsupportedScriptType,
-				fmt.Errorf("unupported script type: %s", class),
-			)
+		class := txscript.GetScriptClass(script)
+
+		// bitcoin.EstimateInputSize discounts witnesses,
+		// which are serialized in full: <sig> <pubkey>.
+		if class == txscript.WitnessV0PubKeyHashTy {
+			witness = true
+			size += 1 + bitcoin.SignatureSize + bitcoin.CompressedPubKeySize
+			continue
 		}
-	}
 
-	buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
-	if err := tx.Serialize(buf); err != nil {
-		return nil, wrapErr(ErrUnableToParseIntermediateResult, fmt.Errorf("%w serialize tx", err))
+		signedSize := bitcoin.EstimateInputSize(
+			s.config.Params,
+			class,
+			redeemScript,
+			unsigned.publicKeyFormat(i) == publicKeyCompressed,
+		)
+		size += int64(signedSize - input.SerializeSize())
 	}
 
-	rawTx, err := json.Marshal(&signedTransaction{
-		Transaction:  hex.EncodeToString(buf.Bytes()),
-		InputAmounts: unsigned.InputAmounts,
-	})
-	if err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to serialize signed tx", err),
-		)
+	// The marker and flag of a transaction with witnesses.
+	if witness {
+		size += 2 // nolint:gomnd
 	}
 
-	return &types.ConstructionCombineResponse{
-		SignedTransaction: hex.EncodeToString(rawTx),
-	}, nil
+	return size, nil
 }
 
-// ConstructionHash implements the /construction/hash endpoint.
-func (s *ConstructionAPIService) ConstructionHash(
-	ctx context.Context,
-	request *types.ConstructionHashRequest,
-) (*types.TransactionIdentifierResponse, *types.Error) {
-	decodedTx, err := hex.DecodeString(request.SignedTransaction)
-	if err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w signed transaction cannot be decoded", err),
-		)
+// parseInputMetadata returns the metadata of an INPUT
+// operation spending a P2SH input with redeemScript, if
+// any. The sequence number is only included when it
+// differs from the default of a transaction with lockTime.
+func (s *ConstructionAPIService) parseInputMetadata(
+	input *wire.TxIn,
+	lockTime uint32,
+	redeemScript []byte,
+	preimage []byte,
+	format string,
+) (map[string]interface{}, *types.Error) {
+	metadata := &inputMetadata{}
+	if format == publicKeyUncompressed {
+		metadata.PublicKeyFormat = format
 	}
 
-	var signed signedTransaction
-	if err := json.Unmarshal(decodedTx, &signed); err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to unmarshal signed bitcoin transaction", err),
-		)
+	if htlc, err := bitcoin.ParseHTLCScript(s.config.Params, redeemScript); err == nil {
+		metadata.HTLC = &htlcMetadata{
+			PaymentHash:        hex.EncodeToString(htlc.PaymentHash),
+			RecipientPublicKey: hex.EncodeToString(htlc.Recipient.ScriptAddress()),
+			RefundPublicKey:    hex.EncodeToString(htlc.Refund.ScriptAddress()),
+			LockTime:           htlc.LockTime,
+			Branch:             htlcRefund,
+		}
+
+		if len(preimage) > 0 {
+			metadata.Preimage = hex.EncodeToString(preimage)
+			metadata.HTLC.Branch = htlcClaim
+		}
 	}
 
-	bytesTx, err := hex.DecodeString(signed.Transaction)
-	if err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to decode hex transaction", err),
-		)
+	defaultSequence := uint32(wire.MaxTxInSequenceNum)
+	if lockTime > 0 {
+		defaultSequence = wire.MaxTxInSequenceNum - 1
 	}
 
-	tx, err := btcutil.NewTxFromBytes(bytesTx)
+	if input.Sequence != defaultSequence {
+		metadata.Sequence = &input.Sequence
+	}
+
+	if metadata.HTLC == nil && metadata.Sequence == nil && len(metadata.PublicKeyFormat) == 0 {
+		return nil, nil
+	}
+
+	rawMetadata, err := types.MarshalMap(metadata)
 	if err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to parse transaction", err),
-		)
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
 	}
 
-	return &types.TransactionIdentifierResponse{
-		TransactionIdentifier: &types.TransactionIdentifier{
-			Hash: tx.Hash().String(),
-		},
-	}, nil
+	return rawMetadata, nil
 }
 
 func (s *ConstructionAPIService) parseUnsignedTransaction(
 	request *types.ConstructionParseRequest,
 ) (*types.ConstructionParseResponse, *types.Error) {
-	decodedTx, err := hex.DecodeString(request.Transaction)
-	if err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w transaction cannot be decoded", err),
-		)
-	}
-
 	var unsigned unsignedTransaction
-	if err := json.Unmarshal(decodedTx, &unsigned); err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to unmarshal bitcoin transaction", err),
-		)
+	if rErr := decodeEnvelope(request.Transaction, unsignedEnvelope, s.config.Network, &unsigned); rErr != nil {
+		return nil, rErr
 	}
 
 	decodedCoreTx, err := hex.DecodeString(unsigned.Transaction)
@@ -565,6 +2996,35 @@ func (s *ConstructionAPIService) parseUnsignedTransaction(
 
 	ops := []*types.Operation{}
 	for i, input := range tx.TxIn {
+		var redeemScript []byte
+		if i < len(unsigned.RedeemScripts) {
+			redeemScript, err = hex.DecodeString(unsigned.RedeemScripts[i])
+			if err != nil {
+				return nil, wrapErr(ErrUnableToDecodeRedeemScript, err)
+			}
+		}
+
+		preimage, err := hex.DecodeString(unsigned.preimage(i))
+		if err != nil {
+			return nil, wrapErr(ErrInvalidPreimage, err)
+		}
+
+		metadata, rErr := s.parseInputMetadata(
+			input,
+			tx.LockTime,
+			redeemScript,
+			preimage,
+			unsigned.publicKeyFormat(i),
+		)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		metadata, rErr = withParseOperationMetadata(metadata, unsigned.ScriptPubKeys[i])
+		if rErr != nil {
+			return nil, rErr
+		}
+
 		networkIndex := int64(i)
 		ops = append(ops, &types.Operation{
 			OperationIdentifier: &types.OperationIdentifier{
@@ -589,58 +3049,85 @@ func (s *ConstructionAPIService) parseUnsignedTransaction(
 					),
 				},
 			},
+			Metadata: metadata,
 		})
 	}
 
 	for i, output := range tx.TxOut {
-		networkIndex := int64(i)
-		_, addr, err := bitcoin.ParseSingleAddress(s.config.Params, output.PkScript)
-		if err != nil {
-			return nil, wrapErr(
-				ErrUnableToDecodeAddress,
-				fmt.Errorf("%w unable to parse output address", err),
-			)
+		op, rErr := s.parseOutput(
+			output,
+			int64(len(ops)),
+			int64(i),
+			outputRedeemScriptAt(unsigned.OutputRedeemScripts, i),
+		)
+		if rErr != nil {
+			return nil, rErr
 		}
 
-		ops = append(ops, &types.Operation{
-			OperationIdentifier: &types.OperationIdentifier{
-				Index:        int64(len(ops)),
-				NetworkIndex: &networkIndex,
-			},
-			Type: bitcoin.OutputOpType,
-			Account: &types.AccountIdentifier{
-				Address: addr.String(),
-			},
-			Amount: &types.Amount{
-				Value:    strconv.FormatInt(output.Value, 10),
-				Currency: s.config.Currency,
-			},
-		})
+		ops = append(ops, op)
+	}
+
+	// Operations of a transaction sorted per BIP-69
+	// are returned in the order of the intent.
+	if len(unsigned.InputIndexes)+len(unsigned.OutputIndexes) > 0 {
+		if err := restoreOperationOrder(ops, unsigned.InputIndexes, unsigned.OutputIndexes); err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+	}
+
+	size, rErr := s.estimateSignedSize(&tx, &unsigned)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	metadata, rErr := parseTransactionMetadata(&tx, unsigned.InputAmounts, size)
+	if rErr != nil {
+		return nil, rErr
 	}
 
 	return &types.ConstructionParseResponse{
 		Operations:               ops,
 		AccountIdentifierSigners: []*types.AccountIdentifier{},
+		Metadata:                 metadata,
 	}, nil
 }
 
-func (s *ConstructionAPIService) parseSignedTransaction(
-	request *types.ConstructionParseRequest,
-) (*types.ConstructionParseResponse, *types.Error) {
-	decodedTx, err := hex.DecodeString(request.Transaction)
+// computePkScript returns the script spent by a signature script
+// and witness. Unlike txscript.ComputePkScript, it recognizes P2PKH
+// inputs pushing an uncompressed public key.
+func (s *ConstructionAPIService) computePkScript(
+	sigScript []byte,
+	witness wire.TxWitness,
+) (txscript.PkScript, error) {
+	pushes, err := txscript.PushedData(sigScript)
+	if len(witness) > 0 || err != nil || len(pushes) != 2 || // nolint:gomnd
+		len(pushes[1]) != btcec.PubKeyBytesLenUncompressed || pushes[1][0] != 0x04 {
+		return txscript.ComputePkScript(sigScript, witness)
+	}
+
+	if _, err := btcec.ParsePubKey(pushes[1], btcec.S256()); err != nil {
+		return txscript.ComputePkScript(sigScript, witness)
+	}
+
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pushes[1]), s.config.Params)
 	if err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w signed transaction cannot be decoded", err),
-		)
+		return txscript.PkScript{}, err
 	}
 
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return txscript.PkScript{}, err
+	}
+
+	return txscript.ParsePkScript(script)
+}
+
+func (s *ConstructionAPIService) parseSignedTransaction(
+	request *types.ConstructionParseRequest,
+) (*types.ConstructionParseResponse, *types.Error) {
 	var signed signedTransaction
-	if err := json.Unmarshal(decodedTx, &signed); err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to unmarshal signed bitcoin transaction", err),
-		)
+	if rErr := decodeEnvelope(request.Transaction, signedEnvelope, s.config.Network, &signed); rErr != nil {
+		return nil, rErr
 	}
 
 	serializedTx, err := hex.DecodeString(signed.Transaction)
@@ -662,7 +3149,7 @@ func (s *ConstructionAPIService) parseSignedTransaction(
 	ops := []*types.Operation{}
 	signers := []*types.AccountIdentifier{}
 	for i, input := range tx.TxIn {
-		pkScript, err := txscript.ComputePkScript(input.SignatureScript, input.Witness)
+		pkScript, err := s.computePkScript(input.SignatureScript, input.Witness)
 		if err != nil {
 			return nil, wrapErr(
 				ErrUnableToComputePkScript,
@@ -670,6 +3157,50 @@ func (s *ConstructionAPIService) parseSignedTransaction(
 			)
 		}
 
+		var redeemScript, preimage []byte
+		if pkScript.Class() == txscript.ScriptHashTy {
+			pushes, err := txscript.PushedData(input.SignatureScript)
+			if err != nil || len(pushes) == 0 {
+				return nil, wrapErr(
+					ErrUnableToComputePkScript,
+					fmt.Errorf("unable to parse signature script of input %d", i),
+				)
+			}
+
+			redeemScript, preimage = scriptHashRedeem(pushes)
+		}
+
+		// P2PKH inputs push the public key last.
+		format := publicKeyCompressed
+		if pkScript.Class() == txscript.PubKeyHashTy {
+			pushes, err := txscript.PushedData(input.SignatureScript)
+			if err == nil && len(pushes) > 0 &&
+				len(pushes[len(pushes)-1]) == btcec.PubKeyBytesLenUncompressed {
+				format = publicKeyUncompressed
+			}
+		}
+
+		metadata, rErr := s.parseInputMetadata(
+			input,
+			tx.LockTime,
+			redeemScript,
+			preimage,
+			format,
+		)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		scriptPubKey, rErr := s.scriptPubKey(pkScript.Script())
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		metadata, rErr = withParseOperationMetadata(metadata, scriptPubKey)
+		if rErr != nil {
+			return nil, rErr
+		}
+
 		_, addr, err := bitcoin.ParseSingleAddress(s.config.Params, pkScript.Script())
 		if err != nil {
 			return nil, wrapErr(
@@ -679,9 +3210,19 @@ func (s *ConstructionAPIService) parseSignedTransaction(
 		}
 
 		networkIndex := int64(i)
-		signers = append(signers, &types.AccountIdentifier{
-			Address: addr.EncodeAddress(),
-		})
+		inputSigners, err := s.inputSigners(&tx, i, pkScript.Class(), addr)
+		if err != nil {
+			return nil, wrapErr(
+				ErrUnableToDecodeRedeemScript,
+				fmt.Errorf("%w unable to determine signers of input %d", err, i),
+			)
+		}
+
+		for _, signer := range inputSigners {
+			signers = append(signers, &types.AccountIdentifier{
+				Address: signer.EncodeAddress(),
+			})
+		}
 		ops = append(ops, &types.Operation{
 			OperationIdentifier: &types.OperationIdentifier{
 				Index:        int64(len(ops)),
@@ -705,41 +3246,119 @@ func (s *ConstructionAPIService) parseSignedTransaction(
 					),
 				},
 			},
+			Metadata: metadata,
 		})
 	}
 
 	for i, output := range tx.TxOut {
-		networkIndex := int64(i)
-		_, addr, err := bitcoin.ParseSingleAddress(s.config.Params, output.PkScript)
-		if err != nil {
-			return nil, wrapErr(
-				ErrUnableToDecodeAddress,
-				fmt.Errorf("%w unable to parse output address", err),
-			)
+		op, rErr := s.parseOutput(
+			output,
+			int64(len(ops)),
+			int64(i),
+			outputRedeemScriptAt(signed.OutputRedeemScripts, i),
+		)
+		if rErr != nil {
+			return nil, rErr
 		}
 
-		ops = append(ops, &types.Operation{
-			OperationIdentifier: &types.OperationIdentifier{
-				Index:        int64(len(ops)),
-				NetworkIndex: &networkIndex,
-			},
-			Type: bitcoin.OutputOpType,
-			Account: &types.AccountIdentifier{
-				Address: addr.String(),
-			},
-			Amount: &types.Amount{
-				Value:    strconv.FormatInt(output.Value, 10),
-				Currency: s.config.Currency,
-			},
-		})
+		ops = append(ops, op)
+	}
+
+	// Operations of a transaction sorted per BIP-69
+	// are returned in the order of the intent.
+	if len(signed.InputIndexes)+len(signed.OutputIndexes) > 0 {
+		if err := restoreOperationOrder(ops, signed.InputIndexes, signed.OutputIndexes); err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+	}
+
+	metadata, rErr := parseTransactionMetadata(&tx, signed.InputAmounts, int64(tx.SerializeSize()))
+	if rErr != nil {
+		return nil, rErr
 	}
 
 	return &types.ConstructionParseResponse{
 		Operations:               ops,
 		AccountIdentifierSigners: signers,
+		Metadata:                 metadata,
 	}, nil
 }
 
+// scriptHashRedeem returns the redeem script and the preimage,
+// if any, of the data pushed by a P2SH signature script.
+func scriptHashRedeem(pushes [][]byte) ([]byte, []byte) {
+	redeemScript := pushes[len(pushes)-1]
+
+	// <sig> <preimage> OP_TRUE <redeemScript> claims a
+	// hash time locked contract. OP_TRUE is not data.
+	if len(pushes) == 3 && len(pushes[1]) == bitcoin.PaymentHashSize { // nolint:gomnd
+		return redeemScript, pushes[1]
+	}
+
+	return redeemScript, nil
+}
+
+// inputSigners returns the addresses that signed an input. For
+// P2SH multisig inputs, these are the P2PKH addresses of the
+// public keys with a valid signature. For other P2SH inputs,
+// this is the P2PKH address of the public key signing them.
+func (s *ConstructionAPIService) inputSigners(
+	tx *wire.MsgTx,
+	index int,
+	class txscript.ScriptClass,
+	addr btcutil.Address,
+) ([]btcutil.Address, error) {
+	if class != txscript.ScriptHashTy {
+		return []btcutil.Address{addr}, nil
+	}
+
+	pushes, err := txscript.PushedData(tx.TxIn[index].SignatureScript)
+	if err != nil || len(pushes) == 0 {
+		return nil, errors.New("unable to parse signature script")
+	}
+
+	redeemScript, preimage := scriptHashRedeem(pushes)
+	if pubKey, _, err := s.scriptHashSpend(redeemScript, preimage); err == nil {
+		return []btcutil.Address{pubKey.AddressPubKeyHash()}, nil
+	}
+
+	pubKeys, _, err := bitcoin.ParseMultiSigScript(s.config.Params, redeemScript)
+	if err != nil {
+		return nil, err
+	}
+
+	signers := []btcutil.Address{}
+	for _, rawSig := range pushes[:len(pushes)-1] {
+		if len(rawSig) == 0 {
+			continue
+		}
+
+		sig, err := btcec.ParseDERSignature(rawSig[:len(rawSig)-1], btcec.S256())
+		if err != nil {
+			return nil, fmt.Errorf("%w unable to parse signature", err)
+		}
+
+		hash, err := txscript.CalcSignatureHash(
+			redeemScript,
+			txscript.SigHashType(rawSig[len(rawSig)-1]),
+			tx,
+			index,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%w unable to calculate signature hash", err)
+		}
+
+		for _, pubKey := range pubKeys {
+			if sig.Verify(hash, pubKey.PubKey()) {
+				signers = append(signers, pubKey.AddressPubKeyHash())
+				break
+			}
+		}
+	}
+
+	return signers, nil
+}
+
 // ConstructionParse implements the /construction/parse endpoint.
 func (s *ConstructionAPIService) ConstructionParse(
 	ctx context.Context,
@@ -752,6 +3371,99 @@ func (s *ConstructionAPIService) ConstructionParse(
 	return s.parseUnsignedTransaction(request)
 }
 
+// submitRejections are the reject reasons returned by
+// bitcoind when a transaction is refused, in the order
+// they are matched against the error message.
+var submitRejections = []struct {
+	reason string
+	rErr   *types.Error
+}{
+	{reason: "missing inputs", rErr: ErrMissingInputs},
+	{reason: "missing-inputs", rErr: ErrMissingInputs},
+	{reason: "inputs-missingorspent", rErr: ErrMissingInputs},
+	{reason: "insufficient fee", rErr: ErrInsufficientFee},
+	{reason: "insufficient priority", rErr: ErrInsufficientFee},
+	{reason: "min relay fee not met", rErr: ErrInsufficientFee},
+	{reason: "mempool min fee not met", rErr: ErrInsufficientFee},
+	{reason: "absurdly-high-fee", rErr: ErrFeeRateTooHigh},
+	{reason: "dust", rErr: ErrOutputBelowDustLimit},
+	{reason: "non-final", rErr: ErrNonFinal},
+	{reason: "non-bip68-final", rErr: ErrNonFinal},
+}
+
+// submitError returns the *types.Error describing why
+// bitcoind refused to submit a transaction. Errors that
+// are not recognized are returned as ErrBitcoind.
+func submitError(err error) *types.Error {
+	wrapped := fmt.Errorf("%w unable to submit transaction", err)
+
+	var rpcErr *bitcoin.RPCError
+	if !errors.As(err, &rpcErr) {
+		return wrapErr(ErrBitcoind, wrapped)
+	}
+
+	if rpcErr.Code == bitcoin.TransactionAlreadyInChainErrCode {
+		return wrapErr(ErrTransactionAlreadyInChain, wrapped)
+	}
+
+	if rpcErr.Code == bitcoin.TransactionErrCode || rpcErr.Code == bitcoin.TransactionRejectedErrCode {
+		message := strings.ToLower(rpcErr.Message)
+		for _, rejection := range submitRejections {
+			if strings.Contains(message, rejection.reason) {
+				return wrapErr(rejection.rErr, wrapped)
+			}
+		}
+	}
+
+	return wrapErr(ErrBitcoind, wrapped)
+}
+
+// checkFeeRate returns ErrFeeRateTooHigh if the fee rate
+// of signed exceeds the configured maximum fee rate.
+func (s *ConstructionAPIService) checkFeeRate(signed *signedTransaction) *types.Error {
+	if s.config.Construction == nil || s.config.Construction.MaxFeeRate == 0 {
+		return nil
+	}
+
+	rawTx, err := hex.DecodeString(signed.Transaction)
+	if err != nil {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to decode signed transaction", err),
+		)
+	}
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to deserialize signed transaction", err),
+		)
+	}
+
+	fee, rErr := transactionFee(&tx, signed.InputAmounts)
+	if rErr != nil {
+		return rErr
+	}
+
+	feeRate := fee * bytesPerKB / int64(tx.SerializeSize())
+	if feeRate <= s.config.Construction.MaxFeeRate {
+		return nil
+	}
+
+	rErr = wrapErr(ErrFeeRateTooHigh, fmt.Errorf(
+		"fee of %d at %d per kB exceeds the maximum fee rate of %d per kB",
+		fee,
+		feeRate,
+		s.config.Construction.MaxFeeRate,
+	))
+	rErr.Details["fee"] = fee
+	rErr.Details["fee_rate"] = feeRate
+	rErr.Details["max_fee_rate"] = s.config.Construction.MaxFeeRate
+
+	return rErr
+}
+
 // ConstructionSubmit implements the /construction/submit endpoint.
 func (s *ConstructionAPIService) ConstructionSubmit(
 	ctx context.Context,
@@ -761,25 +3473,45 @@ func (s *ConstructionAPIService) ConstructionSubmit(
 		return nil, wrapErr(ErrUnavailableOffline, nil)
 	}
 
-	decodedTx, err := hex.DecodeString(request.SignedTransaction)
-	if err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w signed transaction cannot be decoded", err),
-		)
+	var signed signedTransaction
+	if rErr := decodeEnvelope(
+		request.SignedTransaction,
+		signedEnvelope,
+		s.config.Network,
+		&signed,
+	); rErr != nil {
+		return nil, rErr
 	}
 
-	var signed signedTransaction
-	if err := json.Unmarshal(decodedTx, &signed); err != nil {
-		return nil, wrapErr(
-			ErrUnableToParseIntermediateResult,
-			fmt.Errorf("%w unable to unmarshal signed bitcoin transaction", err),
-		)
+	if rErr := s.checkFeeRate(&signed); rErr != nil {
+		return nil, rErr
+	}
+
+	// Redeem scripts of the outputs are recorded before
+	// broadcasting so the outputs can always be spent.
+	redeemScripts := [][]byte{}
+	for _, rawRedeemScript := range signed.OutputRedeemScripts {
+		if len(rawRedeemScript) == 0 {
+			continue
+		}
+
+		redeemScript, err := hex.DecodeString(rawRedeemScript)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToDecodeRedeemScript, err)
+		}
+
+		redeemScripts = append(redeemScripts, redeemScript)
+	}
+
+	if len(redeemScripts) > 0 {
+		if err := s.i.AddRedeemScripts(ctx, redeemScripts); err != nil {
+			return nil, wrapErr(ErrUnableToSaveRedeemScripts, err)
+		}
 	}
 
 	txHash, err := s.client.SendRawTransaction(ctx, signed.Transaction)
 	if err != nil {
-		return nil, wrapErr(ErrBitcoind, fmt.Errorf("%w unable to submit transaction", err))
+		return nil, submitError(err)
 	}
 
 	return &types.TransactionIdentifierResponse{
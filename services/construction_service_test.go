@@ -16,8 +16,15 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
 	"testing"
 
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
@@ -25,6 +32,11 @@ import (
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
 	mocks "github.com/rosetta-dogecoin/rosetta-dogecoin/mocks/services"
 
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
 	"github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -47,6 +59,91 @@ func forceMarshalMap(t *testing.T, i interface{}) map[string]interface{} {
 	return m
 }
 
+// forcePrivateKey returns the secp256k1 key pair for a
+// small scalar, which keeps signing fixtures deterministic.
+func forcePrivateKey(scalar int64) (*btcec.PrivateKey, *types.PublicKey) {
+	privKey, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), big.NewInt(scalar).Bytes())
+
+	return privKey, &types.PublicKey{
+		Bytes:     pubKey.SerializeCompressed(),
+		CurveType: types.Secp256k1,
+	}
+}
+
+// signPayload signs a *types.SigningPayload and returns
+// the signature in the R || S form expected by
+// ConstructionCombine.
+func signPayload(t *testing.T, privKey *btcec.PrivateKey, payload *types.SigningPayload) []byte {
+	sig, err := privKey.Sign(payload.Bytes)
+	if err != nil {
+		t.Fatalf("could not sign payload %x", payload.Bytes)
+	}
+
+	r := sig.R.Bytes()
+	s := sig.S.Bytes()
+	out := make([]byte, 64) // nolint:gomnd
+	copy(out[32-len(r):32], r)
+	copy(out[64-len(s):], s)
+
+	return out
+}
+
+// forceEnvelopePayload returns the payload of a hex-encoded
+// envelope returned by the Construction API.
+func forceEnvelopePayload(t *testing.T, raw string) []byte {
+	var e envelope
+	if err := json.Unmarshal(forceHexDecode(t, raw), &e); err != nil {
+		t.Fatalf("could not unmarshal envelope %s", raw)
+	}
+
+	return e.Payload
+}
+
+// withoutScriptPubKeys removes the ParseOperationMetadata
+// from operations returned by ConstructionParse.
+func withoutScriptPubKeys(ops []*types.Operation) []*types.Operation {
+	for _, op := range ops {
+		delete(op.Metadata, "scriptPubKey")
+		if len(op.Metadata) == 0 {
+			op.Metadata = nil
+		}
+	}
+
+	return ops
+}
+
+// forceSignedTx decodes the wire.MsgTx wrapped in a signed
+// transaction returned by ConstructionCombine.
+func forceSignedTx(t *testing.T, signedRaw string) *wire.MsgTx {
+	var signed signedTransaction
+	if err := json.Unmarshal(forceEnvelopePayload(t, signedRaw), &signed); err != nil {
+		t.Fatalf("could not unmarshal signed transaction %s", signedRaw)
+	}
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(forceHexDecode(t, signed.Transaction))); err != nil {
+		t.Fatalf("could not deserialize signed transaction %s", signed.Transaction)
+	}
+
+	return &tx
+}
+
+// assertValidInput executes the script engine for a single
+// input of a signed transaction.
+func assertValidInput(t *testing.T, tx *wire.MsgTx, index int, pkScript []byte, amount int64) {
+	vm, err := txscript.NewEngine(
+		pkScript,
+		tx,
+		index,
+		txscript.StandardVerifyFlags,
+		nil,
+		nil,
+		amount,
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, vm.Execute())
+}
+
 func TestConstructionService(t *testing.T) {
 	networkIdentifier = &types.NetworkIdentifier{
 		Network:    dogecoin.TestnetNetwork,
@@ -62,7 +159,7 @@ func TestConstructionService(t *testing.T) {
 
 	mockIndexer := &mocks.Indexer{}
 	mockClient := &mocks.Client{}
-	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer)
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
 	ctx := context.Background()
 
 	// Test Derive
@@ -80,7 +177,7 @@ func TestConstructionService(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, &types.ConstructionDeriveResponse{
 		AccountIdentifier: &types.AccountIdentifier{
-			Address: "tdge1qcqzmqzkswhfshzd8kedhmtvgnxax48z4qjht0z",
+			Address: "nmhUpHgsFjsnBygSwfbh5KDbPZx2Eesq9b",
 		},
 	}, deriveResponse)
 
@@ -156,6 +253,7 @@ func TestConstructionService(t *testing.T) {
 		},
 		EstimatedSize: 142,
 		FeeMultiplier: &feeMultiplier,
+		OutputAmounts: []string{"954843", "44657"},
 	}
 	assert.Equal(t, &types.ConstructionPreprocessResponse{
 		Options: forceMarshalMap(t, options),
@@ -190,7 +288,7 @@ func TestConstructionService(t *testing.T) {
 		ctx,
 		defaultConfirmationTarget,
 	).Return(
-		bitcoin.MinFeeRate*10,
+		float64(0.02),
 		nil,
 	).Once()
 	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
@@ -198,11 +296,12 @@ func TestConstructionService(t *testing.T) {
 		Options:           forceMarshalMap(t, options),
 	})
 	assert.Nil(t, err)
+	metadata.SuggestedFee = 4500000
 	assert.Equal(t, &types.ConstructionMetadataResponse{
 		Metadata: forceMarshalMap(t, metadata),
 		SuggestedFee: []*types.Amount{
 			{
-				Value:    "1065", // 1,420 * 0.75
+				Value:    "4500000", // (1 kB + 2 dust outputs) * 2,000,000 * 0.75
 				Currency: dogecoin.TestnetCurrency,
 			},
 		},
@@ -230,11 +329,44 @@ func TestConstructionService(t *testing.T) {
 		Options:           forceMarshalMap(t, options),
 	})
 	assert.Nil(t, err)
+	metadata.SuggestedFee = 3000000
+	assert.Equal(t, &types.ConstructionMetadataResponse{
+		Metadata: forceMarshalMap(t, metadata),
+		SuggestedFee: []*types.Amount{
+			{
+				Value:    "3000000", // we don't go below minimum fee rate
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}, metadataResponse)
+
+	// Fee rate cannot be estimated
+	mockIndexer.On(
+		"GetScriptPubKeys",
+		ctx,
+		options.Coins,
+	).Return(
+		metadata.ScriptPubKeys,
+		nil,
+	).Once()
+	mockClient.On(
+		"SuggestedFeeRate",
+		ctx,
+		defaultConfirmationTarget,
+	).Return(
+		float64(-1),
+		errors.New("insufficient data or no feerate found"),
+	).Once()
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           forceMarshalMap(t, options),
+	})
+	assert.Nil(t, err)
 	assert.Equal(t, &types.ConstructionMetadataResponse{
 		Metadata: forceMarshalMap(t, metadata),
 		SuggestedFee: []*types.Amount{
 			{
-				Value:    "142", // we don't go below minimum fee rate
+				Value:    "3000000", // fallback fee rate
 				Currency: dogecoin.TestnetCurrency,
 			},
 		},
@@ -269,6 +401,15 @@ func TestConstructionService(t *testing.T) {
 				},
 				CoinAction: types.CoinSpent,
 			},
+			Metadata: forceMarshalMap(t, &ParseOperationMetadata{
+				ScriptPubKey: &bitcoin.ScriptPubKey{
+					ASM:          "0 c005b00ad075d30b89a7b65b7dad8899ba6a9c55",
+					Hex:          "0014c005b00ad075d30b89a7b65b7dad8899ba6a9c55",
+					RequiredSigs: 1,
+					Type:         "witness_v0_keyhash",
+					Addresses:    []string{"tdge1qcqzmqzkswhfshzd8kedhmtvgnxax48z4qjht0z"},
+				},
+			}),
 		},
 		{
 			OperationIdentifier: &types.OperationIdentifier{
@@ -283,6 +424,15 @@ func TestConstructionService(t *testing.T) {
 				Value:    "954843",
 				Currency: dogecoin.TestnetCurrency,
 			},
+			Metadata: forceMarshalMap(t, &ParseOperationMetadata{
+				ScriptPubKey: &bitcoin.ScriptPubKey{
+					ASM:          "0 88ce6925f8513a234c05c922ee933f2213230520",
+					Hex:          "001488ce6925f8513a234c05c922ee933f2213230520",
+					RequiredSigs: 1,
+					Type:         "witness_v0_keyhash",
+					Addresses:    []string{"tdge1q3r8xjf0c2yazxnq9ey3wayelygfjxpfqmg6g08"},
+				},
+			}),
 		},
 		{
 			OperationIdentifier: &types.OperationIdentifier{
@@ -297,6 +447,15 @@ func TestConstructionService(t *testing.T) {
 				Value:    "44657",
 				Currency: dogecoin.TestnetCurrency,
 			},
+			Metadata: forceMarshalMap(t, &ParseOperationMetadata{
+				ScriptPubKey: &bitcoin.ScriptPubKey{
+					ASM:          "0 940726595c41fca0b4810c62991ad9d289eeb828",
+					Hex:          "0014940726595c41fca0b4810c62991ad9d289eeb828",
+					RequiredSigs: 1,
+					Type:         "witness_v0_keyhash",
+					Addresses:    []string{"tdge1qjsrjvk2ug872pdypp33fjxke62y7awpgsdtxly"},
+				},
+			}),
 		},
 	}
 
@@ -311,12 +470,14 @@ func TestConstructionService(t *testing.T) {
 		},
 		SignatureType: types.Ecdsa,
 	}
-	assert.Equal(t, &types.ConstructionPayloadsResponse{
-		UnsignedTransaction: unsignedRaw,
-		Payloads:            []*types.SigningPayload{signingPayload},
-	}, payloadsResponse)
+	assert.Equal(t, []*types.SigningPayload{signingPayload}, payloadsResponse.Payloads)
+	assert.Equal(
+		t,
+		forceHexDecode(t, unsignedRaw),
+		[]byte(forceEnvelopePayload(t, payloadsResponse.UnsignedTransaction)),
+	)
 
-	// Test Parse Unsigned
+	// Test Parse Unsigned (unsignedRaw predates envelopes)
 	parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
 		NetworkIdentifier: networkIdentifier,
 		Signed:            false,
@@ -326,6 +487,11 @@ func TestConstructionService(t *testing.T) {
 	assert.Equal(t, &types.ConstructionParseResponse{
 		Operations:               parseOps,
 		AccountIdentifierSigners: []*types.AccountIdentifier{},
+		Metadata: forceMarshalMap(t, &parseMetadata{
+			Fee:     500,
+			Size:    223, // estimated
+			FeeRate: 2242,
+		}),
 	}, parseUnsignedResponse)
 
 	// Test Combine
@@ -346,11 +512,13 @@ func TestConstructionService(t *testing.T) {
 		},
 	})
 	assert.Nil(t, err)
-	assert.Equal(t, &types.ConstructionCombineResponse{
-		SignedTransaction: signedRaw,
-	}, combineResponse)
+	assert.Equal(
+		t,
+		forceHexDecode(t, signedRaw),
+		[]byte(forceEnvelopePayload(t, combineResponse.SignedTransaction)),
+	)
 
-	// Test Parse Signed
+	// Test Parse Signed (signedRaw predates envelopes)
 	parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
 		NetworkIdentifier: networkIdentifier,
 		Signed:            true,
@@ -362,20 +530,27 @@ func TestConstructionService(t *testing.T) {
 		AccountIdentifierSigners: []*types.AccountIdentifier{
 			{Address: "tdge1qcqzmqzkswhfshzd8kedhmtvgnxax48z4qjht0z"},
 		},
+		Metadata: forceMarshalMap(t, &parseMetadata{
+			Fee:     500,
+			Size:    222,
+			FeeRate: 2252,
+		}),
 	}, parseSignedResponse)
 
 	// Test Hash
 	transactionIdentifier := &types.TransactionIdentifier{
 		Hash: "6d87ad0e26025128f5a8357fa423b340cbcffb9703f79f432f5520fca59cd20b",
 	}
-	hashResponse, err := servicer.ConstructionHash(ctx, &types.ConstructionHashRequest{
-		NetworkIdentifier: networkIdentifier,
-		SignedTransaction: signedRaw,
-	})
-	assert.Nil(t, err)
-	assert.Equal(t, &types.TransactionIdentifierResponse{
-		TransactionIdentifier: transactionIdentifier,
-	}, hashResponse)
+	for _, signed := range []string{signedRaw, combineResponse.SignedTransaction} {
+		hashResponse, err := servicer.ConstructionHash(ctx, &types.ConstructionHashRequest{
+			NetworkIdentifier: networkIdentifier,
+			SignedTransaction: signed,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, &types.TransactionIdentifierResponse{
+			TransactionIdentifier: transactionIdentifier,
+		}, hashResponse)
+	}
 
 	// Test Submit
 	bitcoinTransaction := "010000000001017f9cf50b02dd5258f80cd5c3437302e027dd1336172a20cdc80305c5a55741b10100000000ffffffff02db910e000000000016001488ce6925f8513a234c05c922ee933f221323052071ae000000000000160014940726595c41fca0b4810c62991ad9d289eeb82802473044022025876ec8b9f51d343a5a56ac549c0c828005ef45ebe9da166db645c09157223f02204cd08b7278a8889a81135915bce10d1ef3bb92b217f81a0de7e79ffb3dfd6ac501210325c9a4252789b31dbb3454ec647e9516e7c596bcde2bd5da71a60fab8644e43800000000" // nolint
@@ -399,3 +574,3544 @@ func TestConstructionService(t *testing.T) {
 	mockClient.AssertExpectations(t)
 	mockIndexer.AssertExpectations(t)
 }
+
+func TestConstructionService_P2PKH(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	deriveResponse, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         publicKey,
+	})
+	assert.Nil(t, err)
+	sender := deriveResponse.AccountIdentifier.Address
+	assert.Equal(t, "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2", sender)
+
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "pubkeyhash",
+				Addresses:    []string{sender},
+			},
+		},
+	}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, payloadsResponse.Payloads, 1)
+	assert.Equal(t, sender, payloadsResponse.Payloads[0].AccountIdentifier.Address)
+
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assert.Nil(t, signedTx.TxIn[0].Witness)
+	assertValidInput(t, signedTx, 0, senderScript, 1000000000)
+
+	parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.AccountIdentifier{
+		{Address: sender},
+	}, parseSignedResponse.AccountIdentifierSigners)
+	assert.Equal(t, sender, parseSignedResponse.Operations[0].Account.Address)
+
+	hashResponse, err := servicer.ConstructionHash(ctx, &types.ConstructionHashRequest{
+		NetworkIdentifier: networkIdentifier,
+		SignedTransaction: combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, signedTx.TxHash().String(), hashResponse.TransactionIdentifier.Hash)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_UncompressedP2PKH(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	uncompressedKey := &types.PublicKey{
+		Bytes:     privKey.PubKey().SerializeUncompressed(),
+		CurveType: types.Secp256k1,
+	}
+
+	// Invalid public keys
+	_, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey: &types.PublicKey{
+			Bytes:     publicKey.Bytes,
+			CurveType: types.Edwards25519,
+		},
+	})
+	assert.Equal(t, ErrUnableToDerive.Code, err.Code)
+
+	_, err = servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey: &types.PublicKey{
+			Bytes:     publicKey.Bytes[1:],
+			CurveType: types.Secp256k1,
+		},
+	})
+	assert.Equal(t, ErrUnableToDerive.Code, err.Code)
+
+	hybridKey := append([]byte{0x06 | publicKey.Bytes[0]&1}, uncompressedKey.Bytes[1:]...)
+	_, err = servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey: &types.PublicKey{
+			Bytes:     hybridKey,
+			CurveType: types.Secp256k1,
+		},
+	})
+	assert.Equal(t, ErrUnableToDerive.Code, err.Code)
+
+	deriveResponse, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         uncompressedKey,
+	})
+	assert.Nil(t, err)
+	sender := deriveResponse.AccountIdentifier.Address
+	expectedAddr, _ := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(uncompressedKey.Bytes),
+		dogecoin.TestnetParams,
+	)
+	assert.Equal(t, expectedAddr.EncodeAddress(), sender)
+	assert.Equal(t, map[string]interface{}{
+		"public_key_format": "uncompressed",
+	}, deriveResponse.Metadata)
+
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "pubkeyhash",
+				Addresses:    []string{sender},
+			},
+		},
+	}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+			Metadata: deriveResponse.Metadata,
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	// Uncompressed public keys are 32 bytes larger
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(bitcoin.LegacyTransactionOverhead+
+		bitcoin.EstimateInputSize(dogecoin.TestnetParams, txscript.PubKeyHashTy, nil, false)+
+		bitcoin.OutputOverhead+bitcoin.P2PKHScriptPubkeySize,
+	), preprocessResponse.Options["estimated_size"])
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, payloadsResponse.Payloads, 1)
+
+	parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            false,
+		Transaction:       payloadsResponse.UnsignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, deriveResponse.Metadata, withoutScriptPubKeys(parseUnsignedResponse.Operations)[0].Metadata)
+
+	// A public key of another account is rejected
+	_, otherKey := forcePrivateKey(2)
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      otherKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, combineResponse)
+	assert.Equal(t, ErrPublicKeyMismatch.Code, err.Code)
+
+	// Signers returning the compressed public key are
+	// combined with the uncompressed public key
+	combineResponse, err = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assertValidInput(t, signedTx, 0, senderScript, 1000000000)
+
+	parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.AccountIdentifier{
+		{Address: sender},
+	}, parseSignedResponse.AccountIdentifierSigners)
+	assert.Equal(t, deriveResponse.Metadata, withoutScriptPubKeys(parseSignedResponse.Operations)[0].Metadata)
+
+	// Uncompressed public keys cannot spend witness programs
+	ops[0].Account.Address = "tdge1qcqzmqzkswhfshzd8kedhmtvgnxax48z4qjht0z"
+	witnessScript, _ := hex.DecodeString("0014c005b00ad075d30b89a7b65b7dad8899ba6a9c55")
+	metadata.ScriptPubKeys[0].Hex = hex.EncodeToString(witnessScript)
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_MultiSig(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey1, publicKey1 := forcePrivateKey(1)
+	privKey2, publicKey2 := forcePrivateKey(2)
+	_, publicKey3 := forcePrivateKey(3)
+	deriveMetadata := map[string]interface{}{
+		"public_keys": []interface{}{
+			hex.EncodeToString(publicKey1.Bytes),
+			hex.EncodeToString(publicKey2.Bytes),
+			hex.EncodeToString(publicKey3.Bytes),
+		},
+		"threshold": 2,
+	}
+
+	// Public key must be part of the multisig
+	_, missingKey := forcePrivateKey(4)
+	deriveResponse, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         missingKey,
+		Metadata:          deriveMetadata,
+	})
+	assert.Nil(t, deriveResponse)
+	assert.Equal(t, ErrUnableToDerive.Code, err.Code)
+
+	deriveResponse, err = servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         publicKey1,
+		Metadata:          deriveMetadata,
+	})
+	assert.Nil(t, err)
+	sender := deriveResponse.AccountIdentifier.Address
+	redeemScript := deriveResponse.Metadata["redeem_script"].(string)
+	assert.Equal(
+		t,
+		"52210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f817982102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee52102f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f953ae", // nolint
+		redeemScript,
+	)
+
+	_, version, decodeErr := base58.CheckDecode(sender)
+	assert.NoError(t, decodeErr)
+	assert.Equal(t, dogecoin.TestnetParams.ScriptHashAddrID, version)
+	senderAddr, decodeErr := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	assert.NoError(t, decodeErr)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	assert.True(t, txscript.IsPayToScriptHash(senderScript))
+
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "scripthash",
+				Addresses:    []string{sender},
+			},
+		},
+	}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+			Metadata: map[string]interface{}{
+				"redeem_script": redeemScript,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, payloadsResponse.Payloads, 2)
+	assert.Equal(t, "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2", payloadsResponse.Payloads[0].AccountIdentifier.Address)
+	assert.Equal(t, "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64", payloadsResponse.Payloads[1].AccountIdentifier.Address)
+	assert.Equal(t, payloadsResponse.Payloads[0].Bytes, payloadsResponse.Payloads[1].Bytes)
+
+	// Signatures are ordered by redeem script position
+	// regardless of the order they are provided in.
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey2, payloadsResponse.Payloads[1]),
+				SigningPayload: payloadsResponse.Payloads[1],
+				PublicKey:      publicKey2,
+				SignatureType:  types.Ecdsa,
+			},
+			{
+				Bytes:          signPayload(t, privKey1, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey1,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assertValidInput(t, signedTx, 0, senderScript, 1000000000)
+
+	parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.AccountIdentifier{
+		{Address: "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"},
+		{Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64"},
+	}, parseSignedResponse.AccountIdentifierSigners)
+	assert.Equal(t, sender, parseSignedResponse.Operations[0].Account.Address)
+
+	// Explicit signers and missing signatures
+	ops[0].Metadata["signers"] = []interface{}{
+		hex.EncodeToString(publicKey3.Bytes),
+		hex.EncodeToString(publicKey1.Bytes),
+	}
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "nffXVTR75b93NzCRUszpVk5Nq54QjDL3So", payloadsResponse.Payloads[0].AccountIdentifier.Address)
+	assert.Equal(t, "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2", payloadsResponse.Payloads[1].AccountIdentifier.Address)
+
+	combineResponse, err = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey1, payloadsResponse.Payloads[1]),
+				SigningPayload: payloadsResponse.Payloads[1],
+				PublicKey:      publicKey1,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, combineResponse)
+	assert.Equal(t, ErrInvalidSignatures.Code, err.Code)
+
+	// Redeem script is required
+	delete(ops[0].Metadata, "redeem_script")
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrUnableToDecodeRedeemScript.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_NullData(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "pubkeyhash",
+				Addresses:    []string{sender},
+			},
+		},
+	}
+
+	val0 := int64(0)
+	val1 := int64(1)
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 2,
+			},
+			Type: bitcoin.OutputOpType,
+			Amount: &types.Amount{
+				Value:    "0",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			Metadata: map[string]interface{}{
+				"data": "696e766f6963652d3432",
+			},
+		},
+	}
+
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(10+148+9+25+9+12), preprocessResponse.Options["estimated_size"])
+
+	// The data output is unspendable, so it
+	// is not charged as a dust output.
+	var options preprocessOptions
+	assert.NoError(t, types.UnmarshalMap(preprocessResponse.Options, &options))
+	assert.Equal(t, []string{"999000000"}, options.OutputAmounts)
+
+	feePolicy := NewDogecoinFeePolicy()
+	mockIndexer.On("GetScriptPubKeys", ctx, options.Coins).Return(metadata.ScriptPubKeys, nil).Once()
+	mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		strconv.FormatInt(feePolicy.Fee(feePolicy.FeeRate(-1, nil), 10+148+9+25+9+12, nil), 10),
+		metadataResponse.SuggestedFee[0].Value,
+	)
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+
+	parseOps := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index:        1,
+				NetworkIndex: &val0,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index:        2,
+				NetworkIndex: &val1,
+			},
+			Type: bitcoin.OutputOpType,
+			Amount: &types.Amount{
+				Value:    "0",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			Metadata: map[string]interface{}{
+				"data": "696e766f6963652d3432",
+			},
+		},
+	}
+	parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            false,
+		Transaction:       payloadsResponse.UnsignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, parseOps, withoutScriptPubKeys(parseUnsignedResponse.Operations[1:]))
+
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assert.Equal(t, txscript.NullDataTy, txscript.GetScriptClass(signedTx.TxOut[1].PkScript))
+
+	parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+
+	// The size of the unsigned transaction is estimated
+	// from signatures of at most 73 bytes.
+	var unsignedMetadata, signedMetadata parseMetadata
+	assert.NoError(t, types.UnmarshalMap(parseUnsignedResponse.Metadata, &unsignedMetadata))
+	assert.NoError(t, types.UnmarshalMap(parseSignedResponse.Metadata, &signedMetadata))
+	assert.Equal(t, int64(signedTx.SerializeSize()), signedMetadata.Size)
+	assert.Equal(t, signedMetadata.Fee, unsignedMetadata.Fee)
+	assert.InDelta(t, signedMetadata.Size, unsignedMetadata.Size, 2)
+
+	for i, expectedType := range []string{"pubkeyhash", "pubkeyhash", "nulldata"} {
+		var opMetadata ParseOperationMetadata
+		assert.NoError(t, types.UnmarshalMap(parseSignedResponse.Operations[i].Metadata, &opMetadata))
+		assert.Equal(t, expectedType, opMetadata.ScriptPubKey.Type)
+	}
+	assert.Equal(t, parseOps, withoutScriptPubKeys(parseSignedResponse.Operations[1:]))
+
+	// Data outputs must have a zero amount
+	ops[2].Amount.Value = "1"
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	// Outputs without data must have an account
+	ops[2].Amount.Value = "0"
+	ops[2].Metadata = nil
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_DustLimit(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:         configuration.Online,
+		Network:      networkIdentifier,
+		Params:       dogecoin.TestnetParams,
+		Currency:     dogecoin.TestnetCurrency,
+		Construction: dogecoin.TestnetConstruction,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "99999",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 2,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "999999",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 3,
+			},
+			Type: bitcoin.OutputOpType,
+			Amount: &types.Amount{
+				Value:    "0",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			Metadata: map[string]interface{}{
+				"data": "696e766f6963652d3432",
+			},
+		},
+	}
+
+	// Output below the dust limit
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, preprocessResponse)
+	assert.Equal(t, ErrOutputBelowDustLimit.Code, err.Code)
+	assert.Equal(t, int64(1), err.Details["operation_index"])
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrOutputBelowDustLimit.Code, err.Code)
+	assert.Equal(t, int64(1), err.Details["operation_index"])
+
+	// Change below the minimum change
+	ops[1].Amount.Value = "100000"
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, preprocessResponse)
+	assert.Equal(t, ErrOutputBelowDustLimit.Code, err.Code)
+	assert.Equal(t, int64(2), err.Details["operation_index"])
+	assert.Contains(t, err.Details["context"], "minimum change")
+
+	ops[2].Amount.Value = "1000000"
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, preprocessResponse)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_EstimateSize(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	_, publicKey1 := forcePrivateKey(1)
+	_, publicKey2 := forcePrivateKey(2)
+	_, publicKey3 := forcePrivateKey(3)
+	redeemScript, _ := bitcoin.MultiSigScript(
+		dogecoin.TestnetParams,
+		[][]byte{publicKey1.Bytes, publicKey2.Bytes, publicKey3.Bytes},
+		2,
+	)
+	sender, _ := btcutil.NewAddressScriptHash(redeemScript, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(sender)
+
+	ops := []*types.Operation{}
+	coins := []*types.Coin{}
+	scripts := []*bitcoin.ScriptPubKey{}
+	for i := 0; i < 4; i++ {
+		coinIdentifier := &types.CoinIdentifier{
+			Identifier: fmt.Sprintf("b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:%d", i),
+		}
+		amount := &types.Amount{
+			Value:    "-1000000000",
+			Currency: dogecoin.TestnetCurrency,
+		}
+		ops = append(ops, &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: int64(i),
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender.EncodeAddress(),
+			},
+			Amount: amount,
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: coinIdentifier,
+				CoinAction:     types.CoinSpent,
+			},
+		})
+		coins = append(coins, &types.Coin{
+			CoinIdentifier: coinIdentifier,
+			Amount:         amount,
+		})
+		scripts = append(scripts, &bitcoin.ScriptPubKey{
+			Hex:          hex.EncodeToString(senderScript),
+			RequiredSigs: 1,
+			Type:         "scripthash",
+			Addresses:    []string{sender.EncodeAddress()},
+		})
+	}
+	ops = append(ops, &types.Operation{
+		OperationIdentifier: &types.OperationIdentifier{
+			Index: 4,
+		},
+		Type: bitcoin.OutputOpType,
+		Account: &types.AccountIdentifier{
+			Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+		},
+		Amount: &types.Amount{
+			Value:    "3990000000",
+			Currency: dogecoin.TestnetCurrency,
+		},
+	})
+
+	// The redeem script of P2SH inputs is unknown
+	// during preprocess.
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, err)
+	options := &preprocessOptions{
+		Coins:             coins,
+		EstimatedSize:     10 + 34,
+		OutputAmounts:     []string{"3990000000"},
+		UnestimatedInputs: []int{0, 1, 2, 3},
+	}
+	assert.Equal(t, forceMarshalMap(t, options), preprocessResponse.Options)
+
+	// Metadata re-estimates them from their ScriptPubKeys,
+	// which pushes the transaction above 1 kB.
+	mockIndexer.On("GetScriptPubKeys", ctx, coins).Return(scripts, nil).Once()
+	mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           forceMarshalMap(t, options),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2000000", metadataResponse.SuggestedFee[0].Value)
+
+	// Redeem scripts in the input metadata are used
+	// during preprocess.
+	for _, op := range ops[:4] {
+		op.Metadata = map[string]interface{}{
+			"redeem_script": hex.EncodeToString(redeemScript),
+		}
+	}
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(10+4*bitcoin.P2SHInputSize+34), preprocessResponse.Options["estimated_size"])
+	assert.Nil(t, preprocessResponse.Options["unestimated_inputs"])
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_CoinSelection(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := &types.AccountIdentifier{Address: "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"}
+	senderAddr, _ := btcutil.DecodeAddress(sender.Address, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	recipient := "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64"
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: recipient,
+			},
+			Amount: &types.Amount{
+				Value:    "300000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	// Inputs cannot be combined with coin selection.
+	_, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations: append([]*types.Operation{
+			{
+				OperationIdentifier: &types.OperationIdentifier{
+					Index: 1,
+				},
+				Type:    bitcoin.InputOpType,
+				Account: sender,
+			},
+		}, ops...),
+		Metadata: map[string]interface{}{"from": sender},
+	})
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	// Unknown strategies are rejected.
+	_, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"from":           sender,
+			"coin_selection": "random",
+		},
+	})
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"from":           sender,
+			"coin_selection": LargestFirst,
+		},
+	})
+	assert.Nil(t, err)
+	options := &preprocessOptions{
+		Coins:         []*types.Coin{},
+		EstimatedSize: 10 + 34,
+		OutputAmounts: []string{"300000000"},
+		From:          sender,
+		CoinSelection: LargestFirst,
+		ChangeAddress: sender.Address,
+		InputSize:     148,
+		ChangeSize:    34,
+	}
+	assert.Equal(t, forceMarshalMap(t, options), preprocessResponse.Options)
+
+	accountCoins := []*types.Coin{
+		{
+			CoinIdentifier: &types.CoinIdentifier{
+				Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:0",
+			},
+			Amount: &types.Amount{
+				Value:    "200000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+		{
+			CoinIdentifier: &types.CoinIdentifier{
+				Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+			},
+			Amount: &types.Amount{
+				Value:    "500000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+	selected := []*types.Coin{
+		{
+			CoinIdentifier: accountCoins[1].CoinIdentifier,
+			Amount: &types.Amount{
+				Value:    "-500000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+	scripts := []*bitcoin.ScriptPubKey{
+		{
+			Hex:          hex.EncodeToString(senderScript),
+			RequiredSigs: 1,
+			Type:         "pubkeyhash",
+			Addresses:    []string{sender.Address},
+		},
+	}
+
+	// Insufficient funds
+	mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+	mockIndexer.On("GetCoins", ctx, sender).Return(accountCoins[:1], nil, nil).Once()
+	_, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           forceMarshalMap(t, options),
+	})
+	assert.Equal(t, ErrInsufficientFunds.Code, err.Code)
+
+	mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+	mockIndexer.On("GetCoins", ctx, sender).Return(accountCoins, nil, nil).Once()
+	mockIndexer.On("GetScriptPubKeys", ctx, selected).Return(scripts, nil).Once()
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           forceMarshalMap(t, options),
+	})
+	assert.Nil(t, err)
+	change := &types.Operation{
+		OperationIdentifier: &types.OperationIdentifier{
+			Index: 2,
+		},
+		Type:    bitcoin.OutputOpType,
+		Account: sender,
+		Amount: &types.Amount{
+			Value:    "199000000",
+			Currency: dogecoin.TestnetCurrency,
+		},
+	}
+	metadata := &constructionMetadata{
+		ScriptPubKeys: scripts,
+		Coins:         selected,
+		Account:       sender,
+		Change:        change,
+		SuggestedFee:  1000000,
+	}
+	assert.Equal(t, &types.ConstructionMetadataResponse{
+		Metadata: forceMarshalMap(t, metadata),
+		SuggestedFee: []*types.Amount{
+			{
+				Value:    "1000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}, metadataResponse)
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          metadataResponse.Metadata,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, payloadsResponse.Payloads, 1)
+
+	// The unsigned transaction spends the selected
+	// coins and pays the change to the sender.
+	val0 := int64(0)
+	val1 := int64(1)
+	parseOps := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index:        0,
+				NetworkIndex: &val0,
+			},
+			Type:    bitcoin.InputOpType,
+			Account: sender,
+			Amount:  selected[0].Amount,
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: selected[0].CoinIdentifier,
+				CoinAction:     types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index:        1,
+				NetworkIndex: &val0,
+			},
+			Type:    bitcoin.OutputOpType,
+			Account: ops[0].Account,
+			Amount:  ops[0].Amount,
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index:        2,
+				NetworkIndex: &val1,
+			},
+			Type:    bitcoin.OutputOpType,
+			Account: sender,
+			Amount:  change.Amount,
+		},
+	}
+	parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            false,
+		Transaction:       payloadsResponse.UnsignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, parseOps, withoutScriptPubKeys(parseUnsignedResponse.Operations))
+
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assertValidInput(t, signedTx, 0, senderScript, 500000000)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_SigHashTypes(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	scriptPubKey := &bitcoin.ScriptPubKey{
+		Hex:          hex.EncodeToString(senderScript),
+		RequiredSigs: 1,
+		Type:         "pubkeyhash",
+		Addresses:    []string{sender},
+	}
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{scriptPubKey, scriptPubKey},
+	}
+
+	input := func(index int64, coin string, sigHashType string) *types.Operation {
+		return &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: index,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: coin,
+				},
+				CoinAction: types.CoinSpent,
+			},
+			Metadata: map[string]interface{}{
+				"sighash_type": sigHashType,
+			},
+		}
+	}
+	output := func(index int64, value string) *types.Operation {
+		return &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: index,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    value,
+				Currency: dogecoin.TestnetCurrency,
+			},
+		}
+	}
+	coin0 := "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:0"
+	coin1 := "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1"
+
+	// Unsupported sighash types are rejected.
+	_, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations: []*types.Operation{
+			input(0, coin0, "ANYONECANPAY"),
+			output(1, "999000000"),
+		},
+		Metadata: forceMarshalMap(t, &constructionMetadata{
+			ScriptPubKeys: []*bitcoin.ScriptPubKey{scriptPubKey},
+		}),
+	})
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	// SIGHASH_SINGLE requires an output at the index
+	// of the input.
+	_, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations: []*types.Operation{
+			input(0, coin0, "ALL"),
+			input(1, coin1, "SINGLE"),
+			output(2, "1999000000"),
+		},
+		Metadata: forceMarshalMap(t, metadata),
+	})
+	assert.Equal(t, ErrUnableToCalculateSignatureHash.Code, err.Code)
+
+	ops := []*types.Operation{
+		input(0, coin0, "SINGLE|ANYONECANPAY"),
+		input(1, coin1, "NONE"),
+		output(2, "1000000000"),
+		output(3, "999000000"),
+	}
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, payloadsResponse.Payloads, 2)
+
+	var unsigned unsignedTransaction
+	assert.NoError(t, json.Unmarshal(forceEnvelopePayload(t, payloadsResponse.UnsignedTransaction), &unsigned))
+	assert.Equal(t, []txscript.SigHashType{
+		txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+		txscript.SigHashNone,
+	}, unsigned.SigHashTypes)
+
+	signatures := []*types.Signature{
+		{
+			Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+			SigningPayload: payloadsResponse.Payloads[0],
+			PublicKey:      publicKey,
+			SignatureType:  types.Ecdsa,
+		},
+		{
+			Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[1]),
+			SigningPayload: payloadsResponse.Payloads[1],
+			PublicKey:      publicKey,
+			SignatureType:  types.Ecdsa,
+		},
+	}
+
+	// Signatures must be for the payload of their input.
+	_, err = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures:          []*types.Signature{signatures[1], signatures[0]},
+	})
+	assert.Equal(t, ErrInvalidSignatures.Code, err.Code)
+
+	// Signatures ending with their sighash type must
+	// match the sighash type of their input.
+	_, err = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          append(signatures[0].Bytes, byte(txscript.SigHashAll)),
+				SigningPayload: signatures[0].SigningPayload,
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+			signatures[1],
+		},
+	})
+	assert.Equal(t, ErrInvalidSignatures.Code, err.Code)
+	assert.Contains(t, err.Details["context"], "expected SINGLE|ANYONECANPAY")
+
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes: append(
+					append([]byte{}, signatures[0].Bytes...),
+					byte(txscript.SigHashSingle|txscript.SigHashAnyOneCanPay),
+				),
+				SigningPayload: signatures[0].SigningPayload,
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+			signatures[1],
+		},
+	})
+	assert.Nil(t, err)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assertValidInput(t, signedTx, 0, senderScript, 1000000000)
+	assertValidInput(t, signedTx, 1, senderScript, 1000000000)
+
+	for i, hashType := range unsigned.SigHashTypes {
+		pushes, pushErr := txscript.PushedData(signedTx.TxIn[i].SignatureScript)
+		assert.NoError(t, pushErr)
+		assert.Equal(t, byte(hashType), pushes[0][len(pushes[0])-1])
+	}
+}
+
+func TestConstructionService_VerifySignatures(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey1, publicKey1 := forcePrivateKey(1)
+	privKey2, publicKey2 := forcePrivateKey(2)
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "pubkeyhash",
+				Addresses:    []string{sender},
+			},
+		},
+	}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	payload := payloadsResponse.Payloads[0]
+
+	tests := map[string]struct {
+		signature *types.Signature
+
+		expectedErr *types.Error
+	}{
+		"valid signature": {
+			signature: &types.Signature{
+				Bytes:          signPayload(t, privKey1, payload),
+				SigningPayload: payload,
+				PublicKey:      publicKey1,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+		"signature of another key": {
+			signature: &types.Signature{
+				Bytes:          signPayload(t, privKey2, payload),
+				SigningPayload: payload,
+				PublicKey:      publicKey1,
+				SignatureType:  types.Ecdsa,
+			},
+			expectedErr: ErrSignatureVerificationFailed,
+		},
+		"public key not in script": {
+			signature: &types.Signature{
+				Bytes:          signPayload(t, privKey2, payload),
+				SigningPayload: payload,
+				PublicKey:      publicKey2,
+				SignatureType:  types.Ecdsa,
+			},
+			expectedErr: ErrPublicKeyMismatch,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+				Signatures:          []*types.Signature{test.signature},
+			})
+			if test.expectedErr == nil {
+				assert.Nil(t, err)
+				assertValidInput(t, forceSignedTx(t, combineResponse.SignedTransaction), 0, senderScript, 1000000000)
+				return
+			}
+
+			assert.Nil(t, combineResponse)
+			assert.Equal(t, test.expectedErr.Code, err.Code)
+			assert.Equal(t, 0, err.Details["input_index"])
+			assert.Contains(t, err.Details["context"], "input 0:")
+		})
+	}
+}
+
+func TestConstructionService_Submit(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	ctx := context.Background()
+	bitcoinTransaction := "010000000001017f9cf50b02dd5258f80cd5c3437302e027dd1336172a20cdc80305c5a55741b10100000000ffffffff02db910e000000000016001488ce6925f8513a234c05c922ee933f221323052071ae000000000000160014940726595c41fca0b4810c62991ad9d289eeb82802473044022025876ec8b9f51d343a5a56ac549c0c828005ef45ebe9da166db645c09157223f02204cd08b7278a8889a81135915bce10d1ef3bb92b217f81a0de7e79ffb3dfd6ac501210325c9a4252789b31dbb3454ec647e9516e7c596bcde2bd5da71a60fab8644e43800000000" // nolint
+	transactionHash := "6d87ad0e26025128f5a8357fa423b340cbcffb9703f79f432f5520fca59cd20b"
+
+	// The transaction pays a fee of 500 at 2252 per kB.
+	rawSigned, err := json.Marshal(&signedTransaction{
+		Transaction:  bitcoinTransaction,
+		InputAmounts: []string{"-1000000"},
+	})
+	assert.NoError(t, err)
+	signedRaw := hex.EncodeToString(rawSigned)
+
+	tests := map[string]struct {
+		maxFeeRate int64
+		submitErr  error
+
+		expectedErr *types.Error
+	}{
+		"successful": {},
+		"below max fee rate": {
+			maxFeeRate: 2252,
+		},
+		"above max fee rate": {
+			maxFeeRate:  2251,
+			expectedErr: ErrFeeRateTooHigh,
+		},
+		"already in chain": {
+			submitErr: &bitcoin.RPCError{
+				Code:    bitcoin.TransactionAlreadyInChainErrCode,
+				Message: "transaction already in block chain",
+			},
+			expectedErr: ErrTransactionAlreadyInChain,
+		},
+		"missing inputs": {
+			submitErr: &bitcoin.RPCError{
+				Code:    bitcoin.TransactionErrCode,
+				Message: "Missing inputs",
+			},
+			expectedErr: ErrMissingInputs,
+		},
+		"insufficient fee": {
+			submitErr: &bitcoin.RPCError{
+				Code:    bitcoin.TransactionRejectedErrCode,
+				Message: "66: min relay fee not met",
+			},
+			expectedErr: ErrInsufficientFee,
+		},
+		"absurdly high fee": {
+			submitErr: &bitcoin.RPCError{
+				Code:    bitcoin.TransactionRejectedErrCode,
+				Message: "256: absurdly-high-fee",
+			},
+			expectedErr: ErrFeeRateTooHigh,
+		},
+		"dust": {
+			submitErr: &bitcoin.RPCError{
+				Code:    bitcoin.TransactionRejectedErrCode,
+				Message: "64: dust",
+			},
+			expectedErr: ErrOutputBelowDustLimit,
+		},
+		"non-final": {
+			submitErr: &bitcoin.RPCError{
+				Code:    bitcoin.TransactionRejectedErrCode,
+				Message: "64: non-final",
+			},
+			expectedErr: ErrNonFinal,
+		},
+		"other rejection": {
+			submitErr: &bitcoin.RPCError{
+				Code:    bitcoin.TransactionRejectedErrCode,
+				Message: "16: mandatory-script-verify-flag-failed",
+			},
+			expectedErr: ErrBitcoind,
+		},
+		"wrapped rejection": {
+			submitErr: fmt.Errorf("%w: error submitting raw transaction", &bitcoin.RPCError{
+				Code:    bitcoin.TransactionErrCode,
+				Message: "Missing inputs",
+			}),
+			expectedErr: ErrMissingInputs,
+		},
+		"connection error": {
+			submitErr:   errors.New("connection refused"),
+			expectedErr: ErrBitcoind,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &configuration.Configuration{
+				Mode:     configuration.Online,
+				Network:  networkIdentifier,
+				Params:   dogecoin.TestnetParams,
+				Currency: dogecoin.TestnetCurrency,
+				Construction: &configuration.ConstructionConfiguration{
+					MaxFeeRate: test.maxFeeRate,
+				},
+			}
+
+			mockIndexer := &mocks.Indexer{}
+			mockClient := &mocks.Client{}
+			servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+
+			if test.maxFeeRate == 0 || test.expectedErr == nil {
+				mockClient.On(
+					"SendRawTransaction",
+					ctx,
+					bitcoinTransaction,
+				).Return(
+					transactionHash,
+					test.submitErr,
+				).Once()
+			}
+
+			submitResponse, err := servicer.ConstructionSubmit(ctx, &types.ConstructionSubmitRequest{
+				NetworkIdentifier: networkIdentifier,
+				SignedTransaction: signedRaw,
+			})
+			if test.expectedErr == nil {
+				assert.Nil(t, err)
+				assert.Equal(t, transactionHash, submitResponse.TransactionIdentifier.Hash)
+			} else {
+				assert.Nil(t, submitResponse)
+				assert.Equal(t, test.expectedErr.Code, err.Code)
+				assert.Equal(t, test.expectedErr.Retriable, err.Retriable)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConstructionService_LockTime(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	scriptPubKey := &bitcoin.ScriptPubKey{
+		Hex:          hex.EncodeToString(senderScript),
+		RequiredSigs: 1,
+		Type:         "pubkeyhash",
+		Addresses:    []string{sender},
+	}
+
+	input := func(index int64, coin string, metadata map[string]interface{}) *types.Operation {
+		return &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: index,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: coin,
+				},
+				CoinAction: types.CoinSpent,
+			},
+			Metadata: metadata,
+		}
+	}
+	output := &types.Operation{
+		OperationIdentifier: &types.OperationIdentifier{
+			Index: 2,
+		},
+		Type: bitcoin.OutputOpType,
+		Account: &types.AccountIdentifier{
+			Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+		},
+		Amount: &types.Amount{
+			Value:    "1999000000",
+			Currency: dogecoin.TestnetCurrency,
+		},
+	}
+	coin0 := "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:0"
+	coin1 := "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1"
+	final := map[string]interface{}{"sequence": float64(wire.MaxTxInSequenceNum)}
+
+	tests := map[string]struct {
+		lockTime   uint32
+		operations []*types.Operation
+
+		expectedErr *types.Error
+	}{
+		"no lock time": {
+			operations: []*types.Operation{input(0, coin0, nil), input(1, coin1, nil), output},
+		},
+		"height": {
+			lockTime:   2000000,
+			operations: []*types.Operation{input(0, coin0, nil), input(1, coin1, final), output},
+		},
+		"timestamp": {
+			lockTime:   1700000000,
+			operations: []*types.Operation{input(0, coin0, nil), input(1, coin1, nil), output},
+		},
+		"height before BIP65 activation": {
+			lockTime:    100000,
+			operations:  []*types.Operation{input(0, coin0, nil), input(1, coin1, nil), output},
+			expectedErr: ErrInvalidLockTime,
+		},
+		"every input final": {
+			lockTime:    2000000,
+			operations:  []*types.Operation{input(0, coin0, final), input(1, coin1, final), output},
+			expectedErr: ErrInvalidLockTime,
+		},
+		"invalid sequence": {
+			operations: []*types.Operation{
+				input(0, coin0, map[string]interface{}{"sequence": "final"}),
+				input(1, coin1, nil),
+				output,
+			},
+			expectedErr: ErrUnclearIntent,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			preprocessResponse, err := servicer.ConstructionPreprocess(
+				ctx,
+				&types.ConstructionPreprocessRequest{
+					NetworkIdentifier: networkIdentifier,
+					Operations:        test.operations,
+					Metadata:          map[string]interface{}{"lock_time": test.lockTime},
+				},
+			)
+			if test.expectedErr != nil {
+				assert.Nil(t, preprocessResponse)
+				assert.Equal(t, test.expectedErr.Code, err.Code)
+
+				// Payloads validates the same intent.
+				_, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+					NetworkIdentifier: networkIdentifier,
+					Operations:        test.operations,
+					Metadata: forceMarshalMap(t, &constructionMetadata{
+						ScriptPubKeys: []*bitcoin.ScriptPubKey{scriptPubKey, scriptPubKey},
+						LockTime:      test.lockTime,
+					}),
+				})
+				assert.Equal(t, test.expectedErr.Code, err.Code)
+				return
+			}
+			assert.Nil(t, err)
+
+			var options preprocessOptions
+			assert.NoError(t, types.UnmarshalMap(preprocessResponse.Options, &options))
+			assert.Equal(t, test.lockTime, options.LockTime)
+
+			mockIndexer.On(
+				"GetScriptPubKeys",
+				ctx,
+				options.Coins,
+			).Return(
+				[]*bitcoin.ScriptPubKey{scriptPubKey, scriptPubKey},
+				nil,
+			).Once()
+			mockClient.On(
+				"SuggestedFeeRate",
+				ctx,
+				defaultConfirmationTarget,
+			).Return(
+				float64(-1),
+				nil,
+			).Once()
+			metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+				NetworkIdentifier: networkIdentifier,
+				Options:           preprocessResponse.Options,
+			})
+			assert.Nil(t, err)
+
+			payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+				NetworkIdentifier: networkIdentifier,
+				Operations:        test.operations,
+				Metadata:          metadataResponse.Metadata,
+			})
+			assert.Nil(t, err)
+
+			var unsigned unsignedTransaction
+			assert.NoError(t, json.Unmarshal(forceEnvelopePayload(t, payloadsResponse.UnsignedTransaction), &unsigned))
+			var tx wire.MsgTx
+			assert.NoError(t, tx.Deserialize(bytes.NewReader(forceHexDecode(t, unsigned.Transaction))))
+			assert.Equal(t, test.lockTime, tx.LockTime)
+
+			expectedSequence := uint32(wire.MaxTxInSequenceNum)
+			if test.lockTime > 0 {
+				expectedSequence--
+			}
+			assert.Equal(t, expectedSequence, tx.TxIn[0].Sequence)
+
+			parseResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+				NetworkIdentifier: networkIdentifier,
+				Transaction:       payloadsResponse.UnsignedTransaction,
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, test.lockTime, parseResponse.Metadata["lock_time"])
+			for i, op := range withoutScriptPubKeys(parseResponse.Operations[:2]) {
+				assert.Equal(t, types.Hash(test.operations[i].Metadata), types.Hash(op.Metadata))
+			}
+
+			signatures := make([]*types.Signature, len(payloadsResponse.Payloads))
+			for i, payload := range payloadsResponse.Payloads {
+				signatures[i] = &types.Signature{
+					Bytes:          signPayload(t, privKey, payload),
+					SigningPayload: payload,
+					PublicKey:      publicKey,
+					SignatureType:  types.Ecdsa,
+				}
+			}
+			combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+				Signatures:          signatures,
+			})
+			assert.Nil(t, err)
+
+			parseResponse, err = servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+				NetworkIdentifier: networkIdentifier,
+				Signed:            true,
+				Transaction:       combineResponse.SignedTransaction,
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, test.lockTime, parseResponse.Metadata["lock_time"])
+			for i, op := range withoutScriptPubKeys(parseResponse.Operations[:2]) {
+				assert.Equal(t, types.Hash(test.operations[i].Metadata), types.Hash(op.Metadata))
+			}
+		})
+	}
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_LockTimeVault(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	deriveResponse, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         publicKey,
+		Metadata:          map[string]interface{}{"lock_time": 2000000},
+	})
+	assert.Nil(t, err)
+	vault := deriveResponse.AccountIdentifier.Address
+	redeemScript := deriveResponse.Metadata["redeem_script"].(string)
+	assert.Equal(
+		t,
+		"0380841eb175210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac", // nolint
+		redeemScript,
+	)
+
+	vaultAddr, decodeErr := btcutil.DecodeAddress(vault, dogecoin.TestnetParams)
+	assert.NoError(t, decodeErr)
+	vaultScript, _ := txscript.PayToAddrScript(vaultAddr)
+	assert.True(t, txscript.IsPayToScriptHash(vaultScript))
+
+	// Lock times before BIP65 activation are rejected
+	deriveResponse, err = servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         publicKey,
+		Metadata:          map[string]interface{}{"lock_time": 1000},
+	})
+	assert.Nil(t, deriveResponse)
+	assert.Equal(t, ErrUnableToDerive.Code, err.Code)
+
+	// Funding the vault records its redeem script
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	fundOps := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: vault,
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			Metadata: map[string]interface{}{
+				"redeem_script": redeemScript,
+			},
+		},
+	}
+	fundMetadata := forceMarshalMap(t, &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "pubkeyhash",
+				Addresses:    []string{sender},
+			},
+		},
+	})
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        fundOps,
+		Metadata:          fundMetadata,
+	})
+	assert.Nil(t, err)
+
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, fundOps[1].Metadata, withoutScriptPubKeys(parseSignedResponse.Operations)[1].Metadata)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	var signed signedTransaction
+	assert.NoError(t, json.Unmarshal(forceEnvelopePayload(t, combineResponse.SignedTransaction), &signed))
+	assert.Equal(t, []string{redeemScript}, signed.OutputRedeemScripts)
+	mockIndexer.On(
+		"AddRedeemScripts",
+		ctx,
+		[][]byte{forceHexDecode(t, redeemScript)},
+	).Return(nil).Once()
+	mockClient.On(
+		"SendRawTransaction",
+		ctx,
+		signed.Transaction,
+	).Return(signedTx.TxHash().String(), nil).Once()
+	submitResponse, err := servicer.ConstructionSubmit(ctx, &types.ConstructionSubmitRequest{
+		NetworkIdentifier: networkIdentifier,
+		SignedTransaction: combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, signedTx.TxHash().String(), submitResponse.TransactionIdentifier.Hash)
+
+	// The redeem script must match the output address
+	fundOps[1].Metadata["redeem_script"] = redeemScript + "00"
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        fundOps,
+		Metadata:          fundMetadata,
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrUnableToDecodeRedeemScript.Code, err.Code)
+
+	// Spending the vault uses the redeem script recorded
+	// by the indexer and its expiry as lock time.
+	spendOps := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: vault,
+			},
+			Amount: &types.Amount{
+				Value:    "-999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: signedTx.TxHash().String() + ":0",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "998000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+	spendMetadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(vaultScript),
+				RequiredSigs: 1,
+				Type:         "scripthash",
+				Addresses:    []string{vault},
+				RedeemScript: redeemScript,
+			},
+		},
+	}
+
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        spendOps,
+		Metadata:          forceMarshalMap(t, spendMetadata),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, payloadsResponse.Payloads, 1)
+	assert.Equal(t, sender, payloadsResponse.Payloads[0].AccountIdentifier.Address)
+
+	combineResponse, err = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	spendTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assert.Equal(t, uint32(2000000), spendTx.LockTime)
+	assert.Equal(t, uint32(wire.MaxTxInSequenceNum-1), spendTx.TxIn[0].Sequence)
+	assertValidInput(t, spendTx, 0, vaultScript, 999000000)
+
+	parseSignedResponse, err = servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.AccountIdentifier{{Address: sender}}, parseSignedResponse.AccountIdentifierSigners)
+	assert.Equal(t, vault, parseSignedResponse.Operations[0].Account.Address)
+
+	// A lock time before expiry cannot spend the vault
+	spendMetadata.LockTime = 1999999
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        spendOps,
+		Metadata:          forceMarshalMap(t, spendMetadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrInvalidLockTime.Code, err.Code)
+
+	// Final sequences disable the lock time check
+	spendMetadata.LockTime = 0
+	spendOps[0].Metadata = map[string]interface{}{"sequence": wire.MaxTxInSequenceNum}
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        spendOps,
+		Metadata:          forceMarshalMap(t, spendMetadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrInvalidLockTime.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_HTLC(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	recipientKey, recipientPublicKey := forcePrivateKey(1)
+	refundKey, refundPublicKey := forcePrivateKey(2)
+	preimage := bytes.Repeat([]byte{0x42}, bitcoin.PaymentHashSize)
+	paymentHash := sha256.Sum256(preimage)
+
+	deriveResponse, err := servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         recipientPublicKey,
+		Metadata: map[string]interface{}{
+			"payment_hash":      hex.EncodeToString(paymentHash[:]),
+			"refund_public_key": hex.EncodeToString(refundPublicKey.Bytes),
+			"lock_time":         2000000,
+		},
+	})
+	assert.Nil(t, err)
+	contract := deriveResponse.AccountIdentifier.Address
+	redeemScript := deriveResponse.Metadata["redeem_script"].(string)
+	expectedScript, _ := bitcoin.HTLCScript(
+		dogecoin.TestnetParams,
+		paymentHash[:],
+		recipientPublicKey.Bytes,
+		refundPublicKey.Bytes,
+		2000000,
+	)
+	assert.Equal(t, hex.EncodeToString(expectedScript), redeemScript)
+
+	contractAddr, decodeErr := btcutil.DecodeAddress(contract, dogecoin.TestnetParams)
+	assert.NoError(t, decodeErr)
+	contractScript, _ := txscript.PayToAddrScript(contractAddr)
+	assert.True(t, txscript.IsPayToScriptHash(contractScript))
+
+	// The transaction funding the contract.
+	previous := wire.NewMsgTx(wire.TxVersion)
+	previous.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: 1},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	previous.AddTxOut(&wire.TxOut{Value: 1000000000, PkScript: contractScript})
+	buf := bytes.NewBuffer(make([]byte, 0, previous.SerializeSize()))
+	assert.NoError(t, previous.Serialize(buf))
+	previousHex := hex.EncodeToString(buf.Bytes())
+
+	// The refund public key is required
+	deriveResponse, err = servicer.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: networkIdentifier,
+		PublicKey:         recipientPublicKey,
+		Metadata: map[string]interface{}{
+			"payment_hash": hex.EncodeToString(paymentHash[:]),
+			"lock_time":    2000000,
+		},
+	})
+	assert.Nil(t, deriveResponse)
+	assert.Equal(t, ErrUnableToDerive.Code, err.Code)
+
+	recipient := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	refundAddr, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160(refundPublicKey.Bytes), dogecoin.TestnetParams)
+	refund := refundAddr.EncodeAddress()
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: contract,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: previous.TxHash().String() + ":0",
+				},
+				CoinAction: types.CoinSpent,
+			},
+			Metadata: map[string]interface{}{
+				"redeem_script": redeemScript,
+				"preimage":      hex.EncodeToString(preimage),
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: recipient,
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(contractScript),
+				RequiredSigs: 1,
+				Type:         "scripthash",
+				Addresses:    []string{contract},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		preimage   string
+		privateKey *btcec.PrivateKey
+		publicKey  *types.PublicKey
+
+		expectedSigner   string
+		expectedLockTime uint32
+		expectedSequence uint32
+		expectedBranch   string
+	}{
+		"claim": {
+			preimage:         hex.EncodeToString(preimage),
+			privateKey:       recipientKey,
+			publicKey:        recipientPublicKey,
+			expectedSigner:   recipient,
+			expectedSequence: wire.MaxTxInSequenceNum,
+			expectedBranch:   htlcClaim,
+		},
+		"refund": {
+			privateKey:       refundKey,
+			publicKey:        refundPublicKey,
+			expectedSigner:   refund,
+			expectedLockTime: 2000000,
+			expectedSequence: wire.MaxTxInSequenceNum - 1,
+			expectedBranch:   htlcRefund,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ops[0].Metadata = map[string]interface{}{"redeem_script": redeemScript}
+			if len(test.preimage) > 0 {
+				ops[0].Metadata["preimage"] = test.preimage
+			}
+
+			payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+				NetworkIdentifier: networkIdentifier,
+				Operations:        ops,
+				Metadata:          forceMarshalMap(t, metadata),
+			})
+			assert.Nil(t, err)
+			assert.Len(t, payloadsResponse.Payloads, 1)
+			assert.Equal(t, test.expectedSigner, payloadsResponse.Payloads[0].AccountIdentifier.Address)
+
+			expectedMetadata := forceMarshalMap(t, &inputMetadata{
+				Preimage: test.preimage,
+				HTLC: &htlcMetadata{
+					PaymentHash:        hex.EncodeToString(paymentHash[:]),
+					RecipientPublicKey: hex.EncodeToString(recipientPublicKey.Bytes),
+					RefundPublicKey:    hex.EncodeToString(refundPublicKey.Bytes),
+					LockTime:           2000000,
+					Branch:             test.expectedBranch,
+				},
+			})
+			parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+				NetworkIdentifier: networkIdentifier,
+				Signed:            false,
+				Transaction:       payloadsResponse.UnsignedTransaction,
+			})
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				types.Hash(expectedMetadata),
+				types.Hash(withoutScriptPubKeys(parseUnsignedResponse.Operations)[0].Metadata),
+			)
+
+			// Only the expected public key can sign
+			wrongKey, wrongPublicKey := refundKey, refundPublicKey
+			if test.publicKey == refundPublicKey {
+				wrongKey, wrongPublicKey = recipientKey, recipientPublicKey
+			}
+			combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+				Signatures: []*types.Signature{
+					{
+						Bytes:          signPayload(t, wrongKey, payloadsResponse.Payloads[0]),
+						SigningPayload: payloadsResponse.Payloads[0],
+						PublicKey:      wrongPublicKey,
+						SignatureType:  types.Ecdsa,
+					},
+				},
+			})
+			assert.Nil(t, combineResponse)
+			assert.Equal(t, ErrInvalidSignatures.Code, err.Code)
+
+			combineResponse, err = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+				Signatures: []*types.Signature{
+					{
+						Bytes:          signPayload(t, test.privateKey, payloadsResponse.Payloads[0]),
+						SigningPayload: payloadsResponse.Payloads[0],
+						PublicKey:      test.publicKey,
+						SignatureType:  types.Ecdsa,
+					},
+				},
+			})
+			assert.Nil(t, err)
+
+			signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+			assert.Equal(t, test.expectedLockTime, signedTx.LockTime)
+			assert.Equal(t, test.expectedSequence, signedTx.TxIn[0].Sequence)
+			assertValidInput(t, signedTx, 0, contractScript, 1000000000)
+
+			// The PSBT of the transaction is signed
+			// and finalized in the same branch.
+			psbtMetadata := *metadata
+			psbtMetadata.PreviousTransactions = []string{previousHex}
+			psbtPayloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+				NetworkIdentifier: networkIdentifier,
+				Operations:        ops,
+				Metadata:          forceMarshalMap(t, &psbtMetadata),
+			})
+			assert.Nil(t, err)
+
+			var unsigned unsignedTransaction
+			assert.NoError(
+				t,
+				json.Unmarshal(forceEnvelopePayload(t, psbtPayloadsResponse.UnsignedTransaction), &unsigned),
+			)
+			psbtCombineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: unsigned.PSBT,
+				Signatures: []*types.Signature{
+					{
+						Bytes:          signPayload(t, test.privateKey, psbtPayloadsResponse.Payloads[0]),
+						SigningPayload: psbtPayloadsResponse.Payloads[0],
+						PublicKey:      test.publicKey,
+						SignatureType:  types.Ecdsa,
+					},
+				},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, combineResponse, psbtCombineResponse)
+
+			parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+				NetworkIdentifier: networkIdentifier,
+				Signed:            true,
+				Transaction:       combineResponse.SignedTransaction,
+			})
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				[]*types.AccountIdentifier{{Address: test.expectedSigner}},
+				parseSignedResponse.AccountIdentifierSigners,
+			)
+			assert.Equal(t, contract, parseSignedResponse.Operations[0].Account.Address)
+			assert.Equal(
+				t,
+				types.Hash(expectedMetadata),
+				types.Hash(withoutScriptPubKeys(parseSignedResponse.Operations)[0].Metadata),
+			)
+		})
+	}
+
+	// The preimage must match the payment hash
+	ops[0].Metadata = map[string]interface{}{
+		"redeem_script": redeemScript,
+		"preimage":      hex.EncodeToString(paymentHash[:]),
+	}
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrInvalidPreimage.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_Sweep(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := &types.AccountIdentifier{Address: "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"}
+	senderAddr, _ := btcutil.DecodeAddress(sender.Address, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	recipient := &types.AccountIdentifier{Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64"}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type:    bitcoin.OutputOpType,
+			Account: recipient,
+		},
+	}
+
+	// A sweep requires an account
+	_, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          map[string]interface{}{"sweep": true},
+	})
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	// The amount of a sweep is computed
+	_, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations: []*types.Operation{
+			{
+				OperationIdentifier: ops[0].OperationIdentifier,
+				Type:                bitcoin.OutputOpType,
+				Account:             recipient,
+				Amount: &types.Amount{
+					Value:    "300000000",
+					Currency: dogecoin.TestnetCurrency,
+				},
+			},
+		},
+		Metadata: map[string]interface{}{
+			"from":  sender,
+			"sweep": true,
+		},
+	})
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"from":  sender,
+			"sweep": true,
+		},
+	})
+	assert.Nil(t, err)
+	options := &preprocessOptions{
+		Coins:         []*types.Coin{},
+		EstimatedSize: 10 + 34,
+		OutputAmounts: []string{},
+		From:          sender,
+		ChangeAddress: recipient.Address,
+		InputSize:     148,
+		Sweep:         true,
+	}
+	assert.Equal(t, forceMarshalMap(t, options), preprocessResponse.Options)
+
+	accountCoins := []*types.Coin{}
+	for i, value := range []string{"200000000", "500000000", "50000000"} {
+		accountCoins = append(accountCoins, &types.Coin{
+			CoinIdentifier: &types.CoinIdentifier{
+				Identifier: fmt.Sprintf("b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:%d", i),
+			},
+			Amount: &types.Amount{
+				Value:    value,
+				Currency: dogecoin.TestnetCurrency,
+			},
+		})
+	}
+	spent := func(indexes ...int) []*types.Coin {
+		coins := []*types.Coin{}
+		for _, index := range indexes {
+			coins = append(coins, &types.Coin{
+				CoinIdentifier: accountCoins[index].CoinIdentifier,
+				Amount: &types.Amount{
+					Value:    "-" + accountCoins[index].Amount.Value,
+					Currency: dogecoin.TestnetCurrency,
+				},
+			})
+		}
+
+		return coins
+	}
+	script := &bitcoin.ScriptPubKey{
+		Hex:          hex.EncodeToString(senderScript),
+		RequiredSigs: 1,
+		Type:         "pubkeyhash",
+		Addresses:    []string{sender.Address},
+	}
+
+	tests := map[string]struct {
+		coins        []*types.Coin
+		maxCoins     int
+		maxInputSize int64
+
+		expectedCoins  []*types.Coin
+		expectedAmount string
+		expectedErr    *types.Error
+	}{
+		"every coin": {
+			coins:          accountCoins,
+			expectedCoins:  spent(0, 1, 2),
+			expectedAmount: "749000000",
+		},
+		"max coins": {
+			coins:          accountCoins,
+			maxCoins:       2,
+			expectedCoins:  spent(0, 1),
+			expectedAmount: "699000000",
+		},
+		"max input size": {
+			coins:          accountCoins,
+			maxInputSize:   200,
+			expectedCoins:  spent(1),
+			expectedAmount: "499000000",
+		},
+		"no coins": {
+			coins:       []*types.Coin{},
+			expectedErr: ErrInsufficientFunds,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			options.MaxCoins = test.maxCoins
+			options.MaxInputSize = test.maxInputSize
+			mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+			mockIndexer.On("GetCoins", ctx, sender).Return(test.coins, nil, nil).Once()
+			if test.expectedErr == nil {
+				scripts := []*bitcoin.ScriptPubKey{}
+				for range test.expectedCoins {
+					scripts = append(scripts, script)
+				}
+				mockIndexer.On("GetScriptPubKeys", ctx, test.expectedCoins).Return(scripts, nil).Once()
+			}
+
+			metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+				NetworkIdentifier: networkIdentifier,
+				Options:           forceMarshalMap(t, options),
+			})
+			if test.expectedErr != nil {
+				assert.Nil(t, metadataResponse)
+				assert.Equal(t, test.expectedErr.Code, err.Code)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "1000000", metadataResponse.SuggestedFee[0].Value)
+
+			var metadata constructionMetadata
+			assert.NoError(t, types.UnmarshalMap(metadataResponse.Metadata, &metadata))
+			assert.True(t, metadata.Sweep)
+			assert.Equal(t, test.expectedCoins, metadata.Coins)
+			assert.Equal(t, recipient, metadata.Change.Account)
+			assert.Equal(t, test.expectedAmount, metadata.Change.Amount.Value)
+
+			payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+				NetworkIdentifier: networkIdentifier,
+				Operations:        ops,
+				Metadata:          metadataResponse.Metadata,
+			})
+			assert.Nil(t, err)
+			assert.Len(t, payloadsResponse.Payloads, len(test.expectedCoins))
+
+			parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+				NetworkIdentifier: networkIdentifier,
+				Signed:            false,
+				Transaction:       payloadsResponse.UnsignedTransaction,
+			})
+			assert.Nil(t, err)
+			assert.Len(t, parseUnsignedResponse.Operations, len(test.expectedCoins)+1)
+			output := parseUnsignedResponse.Operations[len(test.expectedCoins)]
+			assert.Equal(t, recipient, output.Account)
+			assert.Equal(t, test.expectedAmount, output.Amount.Value)
+
+			signatures := []*types.Signature{}
+			for _, payload := range payloadsResponse.Payloads {
+				signatures = append(signatures, &types.Signature{
+					Bytes:          signPayload(t, privKey, payload),
+					SigningPayload: payload,
+					PublicKey:      publicKey,
+					SignatureType:  types.Ecdsa,
+				})
+			}
+			combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+				Signatures:          signatures,
+			})
+			assert.Nil(t, err)
+
+			signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+			assert.Len(t, signedTx.TxOut, 1)
+			for i, coin := range test.expectedCoins {
+				amount, _ := types.AmountValue(coin.Amount)
+				assertValidInput(t, signedTx, i, senderScript, -amount.Int64())
+			}
+		})
+	}
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_FeeBump(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := &types.AccountIdentifier{Address: "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"}
+	senderAddr, _ := btcutil.DecodeAddress(sender.Address, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	recipient := &types.AccountIdentifier{Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64"}
+	recipientAddr, _ := btcutil.DecodeAddress(recipient.Address, dogecoin.TestnetParams)
+	recipientScript, _ := txscript.PayToAddrScript(recipientAddr)
+
+	parentHash := "43370435829c24fabb266d2f802c0963180fe8b37f8d51ee712651f9b5d18393"
+	parent := &bitcoin.Transaction{
+		Hex:  "01000000017f9cd2e4a63e0a6d4b1f5a5d5e4ccbb6c3f9a2e8a52b4addc80305b5a55741b1010000000100ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac0065cd1d000000001976a914751e76e8199196d454941c45d1b3a323f1433bd688ac00000000", // nolint
+		Hash: parentHash,
+		Outputs: []*bitcoin.Output{
+			{
+				Value: 10,
+				Index: 0,
+				ScriptPubKey: &bitcoin.ScriptPubKey{
+					Hex:  hex.EncodeToString(recipientScript),
+					Type: "pubkeyhash",
+				},
+			},
+			{
+				Value: 5,
+				Index: 1,
+				ScriptPubKey: &bitcoin.ScriptPubKey{
+					Hex:  hex.EncodeToString(senderScript),
+					Type: "pubkeyhash",
+				},
+			},
+		},
+	}
+	coin := &types.CoinIdentifier{Identifier: parentHash + ":1"}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type:    bitcoin.OutputOpType,
+			Account: recipient,
+		},
+	}
+
+	// The amount of a fee bump is computed
+	_, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations: []*types.Operation{
+			{
+				OperationIdentifier: ops[0].OperationIdentifier,
+				Type:                bitcoin.OutputOpType,
+				Account:             recipient,
+				Amount: &types.Amount{
+					Value:    "300000000",
+					Currency: dogecoin.TestnetCurrency,
+				},
+			},
+		},
+		Metadata: map[string]interface{}{"bump_coin": coin},
+	})
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	// A fee bump does not spend coins of an account
+	_, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"bump_coin": coin,
+			"from":      sender,
+		},
+	})
+	assert.Equal(t, ErrUnclearIntent.Code, err.Code)
+
+	_, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"bump_coin": &types.CoinIdentifier{Identifier: parentHash},
+		},
+	})
+	assert.Equal(t, ErrInvalidCoin.Code, err.Code)
+
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          map[string]interface{}{"bump_coin": coin},
+	})
+	assert.Nil(t, err)
+	options := &preprocessOptions{
+		Coins:         []*types.Coin{},
+		EstimatedSize: 10 + 34,
+		OutputAmounts: []string{},
+		ChangeAddress: recipient.Address,
+		BumpCoin:      coin,
+	}
+	assert.Equal(t, forceMarshalMap(t, options), preprocessResponse.Options)
+
+	// The child of 192 bytes brings the fee rate
+	// of the package to the fallback fee rate.
+	tests := map[string]struct {
+		coin     *types.CoinIdentifier
+		entry    *bitcoin.MempoolEntry
+		entryErr error
+
+		expectedFee    string
+		expectedAmount string
+		expectedErr    *types.Error
+	}{
+		"stuck at the minimum relay fee": {
+			coin:           coin,
+			entry:          &bitcoin.MempoolEntry{Size: 226, AncestorCount: 1, AncestorSize: 226, AncestorFees: 100000},
+			expectedFee:    "900000",
+			expectedAmount: "499100000",
+		},
+		"stuck with an ancestor": {
+			coin:           coin,
+			entry:          &bitcoin.MempoolEntry{Size: 226, AncestorCount: 2, AncestorSize: 1500, AncestorFees: 150000},
+			expectedFee:    "1850000",
+			expectedAmount: "498150000",
+		},
+		"not stuck": {
+			coin:           coin,
+			entry:          &bitcoin.MempoolEntry{Size: 226, AncestorCount: 1, AncestorSize: 226, AncestorFees: 2000000},
+			expectedFee:    "100000",
+			expectedAmount: "499900000",
+		},
+		"not in mempool": {
+			coin:        coin,
+			entryErr:    bitcoin.ErrTransactionNotInMempool,
+			expectedErr: ErrTransactionNotInMempool,
+		},
+		"missing output": {
+			coin:        &types.CoinIdentifier{Identifier: parentHash + ":2"},
+			entry:       &bitcoin.MempoolEntry{Size: 226, AncestorCount: 1, AncestorSize: 226, AncestorFees: 100000},
+			expectedErr: ErrInvalidCoin,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			options.BumpCoin = test.coin
+			mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+			mockClient.On("MempoolEntry", ctx, parentHash).Return(test.entry, test.entryErr).Once()
+			if test.entryErr == nil {
+				mockClient.On("RawTransaction", ctx, parentHash).Return(parent, nil).Once()
+			}
+
+			metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+				NetworkIdentifier: networkIdentifier,
+				Options:           forceMarshalMap(t, options),
+			})
+			if test.expectedErr != nil {
+				assert.Nil(t, metadataResponse)
+				assert.Equal(t, test.expectedErr.Code, err.Code)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.expectedFee, metadataResponse.SuggestedFee[0].Value)
+
+			var metadata constructionMetadata
+			assert.NoError(t, types.UnmarshalMap(metadataResponse.Metadata, &metadata))
+			assert.Equal(t, sender, metadata.Account)
+			assert.Equal(t, recipient, metadata.Change.Account)
+			assert.Equal(t, test.expectedAmount, metadata.Change.Amount.Value)
+
+			payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+				NetworkIdentifier: networkIdentifier,
+				Operations:        ops,
+				Metadata:          metadataResponse.Metadata,
+			})
+			assert.Nil(t, err)
+			assert.Len(t, payloadsResponse.Payloads, 1)
+			assert.Equal(t, sender, payloadsResponse.Payloads[0].AccountIdentifier)
+
+			parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+				NetworkIdentifier: networkIdentifier,
+				Signed:            false,
+				Transaction:       payloadsResponse.UnsignedTransaction,
+			})
+			assert.Nil(t, err)
+			assert.Len(t, parseUnsignedResponse.Operations, 2)
+			assert.Equal(t, coin, parseUnsignedResponse.Operations[0].CoinChange.CoinIdentifier)
+			assert.Equal(t, "-500000000", parseUnsignedResponse.Operations[0].Amount.Value)
+			assert.Equal(t, test.expectedAmount, parseUnsignedResponse.Operations[1].Amount.Value)
+
+			combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+				NetworkIdentifier:   networkIdentifier,
+				UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+				Signatures: []*types.Signature{
+					{
+						Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+						SigningPayload: payloadsResponse.Payloads[0],
+						PublicKey:      publicKey,
+						SignatureType:  types.Ecdsa,
+					},
+				},
+			})
+			assert.Nil(t, err)
+
+			signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+			assert.Len(t, signedTx.TxOut, 1)
+			assertValidInput(t, signedTx, 0, senderScript, 500000000)
+		})
+	}
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_MaxFee(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+		Construction: &configuration.ConstructionConfiguration{
+			MaxFeeMultiple: dogecoin.MaxFeeMultiple,
+			MaxFee:         100000000,
+		},
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "pubkeyhash",
+				Addresses:    []string{sender},
+			},
+		},
+		SuggestedFee: 1000000,
+	}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "990000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	// A fee of 10 times the suggested fee is accepted
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, payloadsResponse)
+
+	// Above the multiple of the suggested fee
+	ops[1].Amount.Value = "989999999"
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrFeeTooHigh.Code, err.Code)
+	assert.Equal(t, int64(10000001), err.Details["fee"])
+	assert.Contains(t, err.Details["context"], "suggested fee")
+
+	// Above the maximum fee, without a suggested fee
+	ops[1].Amount.Value = "800000000"
+	metadata.SuggestedFee = 0
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrFeeTooHigh.Code, err.Code)
+	assert.Contains(t, err.Details["context"], "maximum fee")
+
+	// The check can be overridden
+	metadata.AllowHighFee = true
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, payloadsResponse)
+
+	// The override is passed from preprocess to payloads
+	coins := []*types.Coin{
+		{
+			CoinIdentifier: ops[0].CoinChange.CoinIdentifier,
+			Amount:         ops[0].Amount,
+		},
+	}
+	mockIndexer.On("GetScriptPubKeys", ctx, coins).Return(metadata.ScriptPubKeys, nil).Once()
+	mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"allow_high_fee": true,
+		},
+	})
+	assert.Nil(t, err)
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, true, metadataResponse.Metadata["allow_high_fee"])
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          metadataResponse.Metadata,
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, payloadsResponse)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_ForeignAddress(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{
+			{
+				Hex:          hex.EncodeToString(senderScript),
+				RequiredSigs: 1,
+				Type:         "pubkeyhash",
+				Addresses:    []string{sender},
+			},
+		},
+	}
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "bc1qqypqxpq9qcrsszg2pvxq6rs0zqg3yyc5fcj4z3",
+			},
+			Amount: &types.Amount{
+				Value:    "999000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	// Bitcoin segwit output
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, preprocessResponse)
+	assert.Equal(t, ErrForeignAddress.Code, err.Code)
+	assert.Equal(t, []string{"Bitcoin mainnet"}, err.Details["networks"])
+	assert.Equal(t, int64(1), err.Details["operation_index"])
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrForeignAddress.Code, err.Code)
+
+	// Dogecoin mainnet output
+	ops[1].Account.Address = "D5ERdEN1gsouFSs7zsq7VYJxyWP6dP28H1"
+	payloadsResponse, err = servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, payloadsResponse)
+	assert.Equal(t, ErrForeignAddress.Code, err.Code)
+	assert.Equal(t, []string{"Dogecoin mainnet"}, err.Details["networks"])
+	assert.Contains(t, err.Details["context"], "Dogecoin mainnet address")
+
+	// Litecoin change address
+	ops[1].Account.Address = "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64"
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops[1:],
+		Metadata: map[string]interface{}{
+			"from": map[string]interface{}{
+				"address": sender,
+			},
+			"change_address": "LKKHMBjCU89fyFNgSRprDoD8Jb25N8uWvd",
+		},
+	})
+	assert.Nil(t, preprocessResponse)
+	assert.Equal(t, ErrForeignAddress.Code, err.Code)
+	assert.Equal(t, []string{"Litecoin mainnet"}, err.Details["networks"])
+
+	// Invalid address
+	ops[1].Account.Address = "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa65"
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+	})
+	assert.Nil(t, preprocessResponse)
+	assert.Equal(t, ErrUnableToDecodeAddress.Code, err.Code)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_BIP69(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	script := &bitcoin.ScriptPubKey{
+		Hex:          hex.EncodeToString(senderScript),
+		RequiredSigs: 1,
+		Type:         "pubkeyhash",
+		Addresses:    []string{sender},
+	}
+
+	input := func(index int64, coin string, amount string) *types.Operation {
+		return &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: index,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    amount,
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: coin,
+				},
+				CoinAction: types.CoinSpent,
+			},
+		}
+	}
+	output := func(index int64, address string, amount string) *types.Operation {
+		return &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: index,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: address,
+			},
+			Amount: &types.Amount{
+				Value:    amount,
+				Currency: dogecoin.TestnetCurrency,
+			},
+		}
+	}
+
+	ops := []*types.Operation{
+		input(0, "e53e6b9a7c2c6f2b0e0d6f54b35e4e6f6d7a4bb0b2a5c43bb4ef2b53e2a32e2f:1", "-1000000000"),
+		input(1, "0e53ec5dfb2cb8a71fec32dc9a634a35b7e24799295ddd5278217822e0b31f57:0", "-2000000000"),
+		output(2, "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64", "2000000000"),
+		output(3, sender, "990000000"),
+	}
+
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"bip69": true,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, true, preprocessResponse.Options["bip69"])
+
+	metadata := &constructionMetadata{
+		ScriptPubKeys: []*bitcoin.ScriptPubKey{script, script},
+		BIP69:         true,
+	}
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          forceMarshalMap(t, metadata),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, payloadsResponse.Payloads, 2)
+
+	var unsigned unsignedTransaction
+	assert.NoError(t, json.Unmarshal(forceEnvelopePayload(t, payloadsResponse.UnsignedTransaction), &unsigned))
+	assert.Equal(t, []int64{1, 0}, unsigned.InputIndexes)
+	assert.Equal(t, []int64{3, 2}, unsigned.OutputIndexes)
+	assert.Equal(t, []string{"-2000000000", "-1000000000"}, unsigned.InputAmounts)
+
+	var tx wire.MsgTx
+	assert.NoError(t, tx.Deserialize(bytes.NewReader(forceHexDecode(t, unsigned.Transaction))))
+	assert.Equal(t, uint32(0), tx.TxIn[0].PreviousOutPoint.Index)
+	assert.Equal(t, int64(990000000), tx.TxOut[0].Value)
+
+	parseUnsignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            false,
+		Transaction:       payloadsResponse.UnsignedTransaction,
+	})
+	assert.Nil(t, err)
+	for i, op := range parseUnsignedResponse.Operations {
+		assert.Equal(t, ops[i].OperationIdentifier.Index, op.OperationIdentifier.Index)
+		assert.Equal(t, ops[i].Account, op.Account)
+		assert.Equal(t, ops[i].Amount, op.Amount)
+	}
+	assert.Equal(t, int64(1), *parseUnsignedResponse.Operations[0].OperationIdentifier.NetworkIndex)
+	assert.Equal(t, int64(0), *parseUnsignedResponse.Operations[3].OperationIdentifier.NetworkIndex)
+
+	signatures := make([]*types.Signature, len(payloadsResponse.Payloads))
+	for i, payload := range payloadsResponse.Payloads {
+		signatures[i] = &types.Signature{
+			Bytes:          signPayload(t, privKey, payload),
+			SigningPayload: payload,
+			PublicKey:      publicKey,
+			SignatureType:  types.Ecdsa,
+		}
+	}
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures:          signatures,
+	})
+	assert.Nil(t, err)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assertValidInput(t, signedTx, 0, senderScript, 2000000000)
+	assertValidInput(t, signedTx, 1, senderScript, 1000000000)
+
+	parseSignedResponse, err := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, err)
+	for i, op := range parseSignedResponse.Operations {
+		assert.Equal(t, ops[i].OperationIdentifier.Index, op.OperationIdentifier.Index)
+		assert.Equal(t, ops[i].Account, op.Account)
+		assert.Equal(t, ops[i].Amount, op.Amount)
+	}
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
+
+func TestConstructionService_Offline(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Offline,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey, publicKey := forcePrivateKey(1)
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: "b14157a5c50503c8cd202a173613dd27e0027343c3d50cf85852dd020bf59c7f:1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "998000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	// The fee rate must be relayed
+	preprocessResponse, err := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"fee_rate": 1000,
+		},
+	})
+	assert.Nil(t, err)
+	metadataResponse, err := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrInsufficientFee.Code, err.Code)
+
+	// Scripts must pay to the input addresses
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"fee_rate":        2000000,
+			"script_pub_keys": []string{"0014c005b00ad075d30b89a7b65b7dad8899ba6a9c55"},
+		},
+	})
+	assert.Nil(t, err)
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrUnableToDecodeScriptPubKey.Code, err.Code)
+
+	// Coins cannot be selected offline
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops[1:],
+		Metadata: map[string]interface{}{
+			"from": map[string]interface{}{
+				"address": sender,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, metadataResponse)
+	assert.Equal(t, ErrUnavailableOffline.Code, err.Code)
+
+	// Scripts are derived from the input addresses
+	preprocessResponse, err = servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata: map[string]interface{}{
+			"fee_rate": 2000000,
+		},
+	})
+	assert.Nil(t, err)
+	metadataResponse, err = servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2000000", metadataResponse.SuggestedFee[0].Value)
+
+	var metadata constructionMetadata
+	assert.NoError(t, types.UnmarshalMap(metadataResponse.Metadata, &metadata))
+	assert.Equal(t, []*bitcoin.ScriptPubKey{
+		{
+			ASM:          "OP_DUP OP_HASH160 751e76e8199196d454941c45d1b3a323f1433bd6 OP_EQUALVERIFY OP_CHECKSIG",
+			Hex:          hex.EncodeToString(senderScript),
+			RequiredSigs: 1,
+			Type:         "pubkeyhash",
+			Addresses:    []string{sender},
+		},
+	}, metadata.ScriptPubKeys)
+
+	payloadsResponse, err := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          metadataResponse.Metadata,
+	})
+	assert.Nil(t, err)
+
+	combineResponse, err := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: payloadsResponse.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				Bytes:          signPayload(t, privKey, payloadsResponse.Payloads[0]),
+				SigningPayload: payloadsResponse.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.Ecdsa,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assertValidInput(t, forceSignedTx(t, combineResponse.SignedTransaction), 0, senderScript, 1000000000)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// envelopeVersion is the version of the
+	// envelopes created by this implementation.
+	envelopeVersion = 1
+
+	// unsignedEnvelope and signedEnvelope are the
+	// types of the envelopes of an unsignedTransaction
+	// and of a signedTransaction.
+	unsignedEnvelope = "unsigned_transaction"
+	signedEnvelope   = "signed_transaction"
+
+	// checksumSize is the number of bytes of the
+	// double SHA-256 hash kept in a checksum.
+	checksumSize = 4
+)
+
+// envelopeChecksum returns the checksum of the fields
+// of e other than Checksum.
+func envelopeChecksum(e *envelope) string {
+	data := []byte(fmt.Sprintf(
+		"%d\n%s\n%s\n",
+		e.Version,
+		e.Type,
+		types.Hash(e.NetworkIdentifier),
+	))
+	data = append(data, e.Payload...)
+
+	return hex.EncodeToString(chainhash.DoubleHashB(data)[:checksumSize])
+}
+
+// encodeEnvelope returns the hex-encoded envelope
+// of type envelopeType wrapping payload for network.
+func encodeEnvelope(
+	envelopeType string,
+	network *types.NetworkIdentifier,
+	payload interface{},
+) (string, *types.Error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return "", wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to serialize %s", err, envelopeType),
+		)
+	}
+
+	e := &envelope{
+		Version:           envelopeVersion,
+		Type:              envelopeType,
+		NetworkIdentifier: network,
+		Payload:           rawPayload,
+	}
+	e.Checksum = envelopeChecksum(e)
+
+	rawEnvelope, err := json.Marshal(e)
+	if err != nil {
+		return "", wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to serialize envelope", err),
+		)
+	}
+
+	return hex.EncodeToString(rawEnvelope), nil
+}
+
+// decodeEnvelope unmarshals the payload of the hex-encoded
+// envelope of type envelopeType into payload. The envelope
+// must be of network and match its checksum. Transactions
+// created before envelopes are unmarshaled as is.
+func decodeEnvelope(
+	raw string,
+	envelopeType string,
+	network *types.NetworkIdentifier,
+	payload interface{},
+) *types.Error {
+	decoded, err := hex.DecodeString(raw)
+	if err != nil {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w %s cannot be decoded", err, envelopeType),
+		)
+	}
+
+	var e envelope
+	if err := json.Unmarshal(decoded, &e); err != nil {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to unmarshal %s", err, envelopeType),
+		)
+	}
+
+	// Transactions created before envelopes
+	// have no version.
+	rawPayload := decoded
+	if e.Version != 0 {
+		if rErr := validateEnvelope(&e, envelopeType, network); rErr != nil {
+			return rErr
+		}
+
+		rawPayload = e.Payload
+	}
+
+	if err := json.Unmarshal(rawPayload, payload); err != nil {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("%w unable to unmarshal %s", err, envelopeType),
+		)
+	}
+
+	return nil
+}
+
+// validateEnvelope returns an error if e is not a supported
+// envelope of type envelopeType for network or if its checksum
+// does not match.
+func validateEnvelope(e *envelope, envelopeType string, network *types.NetworkIdentifier) *types.Error {
+	if e.Version != envelopeVersion {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("envelope version %d is not supported", e.Version),
+		)
+	}
+
+	if e.Checksum != envelopeChecksum(e) {
+		rErr := wrapErr(
+			ErrInvalidChecksum,
+			fmt.Errorf("checksum %s does not match the %s", e.Checksum, envelopeType),
+		)
+		rErr.Details["checksum"] = e.Checksum
+
+		return rErr
+	}
+
+	if e.Type != envelopeType {
+		return wrapErr(
+			ErrUnableToParseIntermediateResult,
+			fmt.Errorf("expected a %s, got a %s", envelopeType, e.Type),
+		)
+	}
+
+	if types.Hash(e.NetworkIdentifier) != types.Hash(network) {
+		rErr := wrapErr(
+			ErrNetworkMismatch,
+			fmt.Errorf("%s was created for another network", envelopeType),
+		)
+		rErr.Details["network_identifier"] = e.NetworkIdentifier
+
+		return rErr
+	}
+
+	return nil
+}
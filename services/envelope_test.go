@@ -0,0 +1,135 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	testnet := &types.NetworkIdentifier{
+		Blockchain: dogecoin.Blockchain,
+		Network:    dogecoin.TestnetNetwork,
+	}
+	mainnet := &types.NetworkIdentifier{
+		Blockchain: dogecoin.Blockchain,
+		Network:    dogecoin.MainnetNetwork,
+	}
+	signed := &signedTransaction{
+		Transaction:  "0100000000000000000000",
+		InputAmounts: []string{"-1000000"},
+	}
+
+	raw, rErr := encodeEnvelope(signedEnvelope, testnet, signed)
+	assert.Nil(t, rErr)
+
+	// Tamper with the envelope before encoding it again
+	tamper := func(f func(e *envelope)) string {
+		var e envelope
+		assert.NoError(t, json.Unmarshal(forceHexDecode(t, raw), &e))
+		f(&e)
+
+		b, err := json.Marshal(&e)
+		assert.NoError(t, err)
+
+		return hex.EncodeToString(b)
+	}
+
+	legacy, err := json.Marshal(signed)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		raw          string
+		envelopeType string
+		network      *types.NetworkIdentifier
+
+		expectedErr *types.Error
+	}{
+		"envelope": {
+			raw:          raw,
+			envelopeType: signedEnvelope,
+			network:      testnet,
+		},
+		"legacy": {
+			raw:          hex.EncodeToString(legacy),
+			envelopeType: signedEnvelope,
+			network:      testnet,
+		},
+		"other network": {
+			raw:          raw,
+			envelopeType: signedEnvelope,
+			network:      mainnet,
+			expectedErr:  ErrNetworkMismatch,
+		},
+		"other type": {
+			raw:          raw,
+			envelopeType: unsignedEnvelope,
+			network:      testnet,
+			expectedErr:  ErrUnableToParseIntermediateResult,
+		},
+		"altered payload": {
+			raw: tamper(func(e *envelope) {
+				e.Payload = json.RawMessage(`{"transaction":"00","input_amounts":["-1000000"]}`)
+			}),
+			envelopeType: signedEnvelope,
+			network:      testnet,
+			expectedErr:  ErrInvalidChecksum,
+		},
+		"altered network": {
+			raw: tamper(func(e *envelope) {
+				e.NetworkIdentifier = mainnet
+			}),
+			envelopeType: signedEnvelope,
+			network:      mainnet,
+			expectedErr:  ErrInvalidChecksum,
+		},
+		"unsupported version": {
+			raw: tamper(func(e *envelope) {
+				e.Version = envelopeVersion + 1
+				e.Checksum = envelopeChecksum(e)
+			}),
+			envelopeType: signedEnvelope,
+			network:      testnet,
+			expectedErr:  ErrUnableToParseIntermediateResult,
+		},
+		"malformed hex": {
+			raw:          "not hex",
+			envelopeType: signedEnvelope,
+			network:      testnet,
+			expectedErr:  ErrUnableToParseIntermediateResult,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var decoded signedTransaction
+			rErr := decodeEnvelope(test.raw, test.envelopeType, test.network, &decoded)
+			if test.expectedErr != nil {
+				assert.Equal(t, test.expectedErr.Code, rErr.Code)
+				return
+			}
+
+			assert.Nil(t, rErr)
+			assert.Equal(t, signed, &decoded)
+		})
+	}
+}
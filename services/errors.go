@@ -41,6 +41,27 @@ var (
 		ErrTransactionNotFound,
 		ErrCouldNotGetFeeRate,
 		ErrUnableToGetBalance,
+		ErrUnableToDecodeRedeemScript,
+		ErrInvalidSignatures,
+		ErrOutputBelowDustLimit,
+		ErrInsufficientFunds,
+		ErrUnableToGetRawTransactions,
+		ErrSignatureVerificationFailed,
+		ErrTransactionAlreadyInChain,
+		ErrMissingInputs,
+		ErrInsufficientFee,
+		ErrNonFinal,
+		ErrFeeRateTooHigh,
+		ErrInvalidLockTime,
+		ErrUnableToSaveRedeemScripts,
+		ErrInvalidPreimage,
+		ErrFeeTooHigh,
+		ErrForeignAddress,
+		ErrPublicKeyMismatch,
+		ErrTransactionNotInMempool,
+		ErrInvalidMessageSignature,
+		ErrNetworkMismatch,
+		ErrInvalidChecksum,
 	}
 
 	// ErrUnimplemented is returned when an endpoint
@@ -183,6 +204,181 @@ var (
 		Code:    18, //nolint
 		Message: "Unable to get balance",
 	}
+
+	// ErrUnableToDecodeRedeemScript is returned when
+	// the redeem script of a P2SH input is missing, does
+	// not match the ScriptPubKey or cannot be parsed.
+	ErrUnableToDecodeRedeemScript = &types.Error{
+		Code:    19, //nolint
+		Message: "Unable to decode redeem script",
+	}
+
+	// ErrInvalidSignatures is returned when the
+	// signatures provided to /construction/combine
+	// do not match the inputs being signed.
+	ErrInvalidSignatures = &types.Error{
+		Code:    20, //nolint
+		Message: "Signatures do not match inputs",
+	}
+
+	// ErrOutputBelowDustLimit is returned when an
+	// OUTPUT operation is below the dust limit or a
+	// change output is below the minimum change. The
+	// offending operation is named in the details. It
+	// is also returned when bitcoind rejects a dust
+	// output on submission.
+	ErrOutputBelowDustLimit = &types.Error{
+		Code:    21, //nolint
+		Message: "Output amount is below the dust limit",
+	}
+
+	// ErrInsufficientFunds is returned when the coins
+	// of an account cannot fund a transaction.
+	ErrInsufficientFunds = &types.Error{
+		Code:    22, //nolint
+		Message: "Insufficient funds",
+	}
+
+	// ErrUnableToGetRawTransactions is returned by the indexer
+	// when it is not possible to get the transactions creating
+	// the coins spent.
+	ErrUnableToGetRawTransactions = &types.Error{
+		Code:    23, //nolint
+		Message: "Unable to get raw transactions",
+	}
+
+	// ErrSignatureVerificationFailed is returned when
+	// the script of a signed input does not execute
+	// successfully. The input is named in the details.
+	ErrSignatureVerificationFailed = &types.Error{
+		Code:    24, //nolint
+		Message: "Signature verification failed",
+	}
+
+	// ErrTransactionAlreadyInChain is returned when
+	// bitcoind rejects a transaction that is already
+	// included in a block.
+	ErrTransactionAlreadyInChain = &types.Error{
+		Code:    25, //nolint
+		Message: "Transaction already in chain",
+	}
+
+	// ErrMissingInputs is returned when bitcoind rejects
+	// a transaction spending coins it does not know about.
+	// The coins may be created by a transaction that has
+	// not reached the node yet, so the submission can
+	// be retried.
+	ErrMissingInputs = &types.Error{
+		Code:      26, //nolint
+		Message:   "Transaction inputs are missing or spent",
+		Retriable: true,
+	}
+
+	// ErrInsufficientFee is returned when bitcoind
+	// rejects a transaction paying less than the
+	// relay or mempool minimum fee.
+	ErrInsufficientFee = &types.Error{
+		Code:    27, //nolint
+		Message: "Insufficient fee",
+	}
+
+	// ErrNonFinal is returned when bitcoind rejects a
+	// transaction whose locktime or sequence locks are
+	// not satisfied yet.
+	ErrNonFinal = &types.Error{
+		Code:      28, //nolint
+		Message:   "Transaction is not final",
+		Retriable: true,
+	}
+
+	// ErrFeeRateTooHigh is returned when the fee rate of a
+	// transaction exceeds the configured maximum or when
+	// bitcoind rejects an absurdly high fee.
+	ErrFeeRateTooHigh = &types.Error{
+		Code:    29, //nolint
+		Message: "Fee rate is too high",
+	}
+
+	// ErrInvalidLockTime is returned when the lock time
+	// of a transaction is invalid or cannot be enforced
+	// with the sequence numbers of its inputs.
+	ErrInvalidLockTime = &types.Error{
+		Code:    30, //nolint
+		Message: "Invalid lock time",
+	}
+
+	// ErrUnableToSaveRedeemScripts is returned by the
+	// indexer when it is not possible to record the
+	// redeem scripts of submitted P2SH outputs.
+	ErrUnableToSaveRedeemScripts = &types.Error{
+		Code:    31, //nolint
+		Message: "Unable to save redeem scripts",
+	}
+
+	// ErrInvalidPreimage is returned when the preimage
+	// claiming a hash time locked contract does not match
+	// its payment hash.
+	ErrInvalidPreimage = &types.Error{
+		Code:    32, //nolint
+		Message: "Invalid preimage",
+	}
+
+	// ErrFeeTooHigh is returned when the fee implied by
+	// the operations of a transaction exceeds the configured
+	// multiple of the suggested fee or the maximum fee.
+	// Operations missing a change output usually cause it.
+	ErrFeeTooHigh = &types.Error{
+		Code:    33, //nolint
+		Message: "Fee is too high",
+	}
+
+	// ErrForeignAddress is returned when an address
+	// belongs to another Dogecoin network, to Bitcoin
+	// or to Litecoin.
+	ErrForeignAddress = &types.Error{
+		Code:    34, //nolint
+		Message: "Address belongs to another network",
+	}
+
+	// ErrPublicKeyMismatch is returned when the public
+	// key of a signature does not hash to the public key
+	// hash of the input it signs.
+	ErrPublicKeyMismatch = &types.Error{
+		Code:    35, //nolint
+		Message: "Public key does not match the input",
+	}
+
+	// ErrTransactionNotInMempool is returned when the
+	// transaction of a coin to bump is not in the mempool,
+	// usually because it has been confirmed.
+	ErrTransactionNotInMempool = &types.Error{
+		Code:    36, //nolint
+		Message: "Transaction is not in the mempool",
+	}
+
+	// ErrInvalidMessageSignature is returned when the
+	// signature of a message is malformed or of the
+	// wrong type.
+	ErrInvalidMessageSignature = &types.Error{
+		Code:    37, //nolint
+		Message: "Invalid message signature",
+	}
+
+	// ErrNetworkMismatch is returned when an unsigned
+	// or signed transaction was created for another
+	// network.
+	ErrNetworkMismatch = &types.Error{
+		Code:    38, //nolint
+		Message: "Transaction belongs to another network",
+	}
+
+	// ErrInvalidChecksum is returned when the checksum
+	// of an unsigned or signed transaction does not
+	// match, usually because it was altered.
+	ErrInvalidChecksum = &types.Error{
+		Code:    39, //nolint
+		Message: "Transaction checksum does not match",
+	}
 )
 
 // wrapErr adds details to the types.Error provided. We use a function
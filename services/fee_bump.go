@@ -0,0 +1,222 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// validateFeeBump returns an error if operations cannot
+// spend the coin of a stuck transaction in metadata.
+func (s *ConstructionAPIService) validateFeeBump(
+	operations []*types.Operation,
+	metadata *preprocessMetadata,
+) *types.Error {
+	if metadata.From != nil || metadata.Sweep {
+		return wrapErr(
+			ErrUnclearIntent,
+			errors.New("a fee bump spends a coin of the stuck transaction, not of an account"),
+		)
+	}
+
+	if len(metadata.ScriptPubKeys) > 0 {
+		return wrapErr(
+			ErrUnclearIntent,
+			errors.New("script pub keys cannot be provided for a fee bump"),
+		)
+	}
+
+	if len(operations) != 1 ||
+		operations[0].Type != bitcoin.OutputOpType ||
+		operations[0].Account == nil ||
+		operations[0].Amount != nil {
+		return wrapErr(
+			ErrUnclearIntent,
+			errors.New("a fee bump has a single output with an account and no amount"),
+		)
+	}
+
+	if _, _, err := bitcoin.ParseCoinIdentifier(metadata.BumpCoin); err != nil {
+		return wrapErr(ErrInvalidCoin, err)
+	}
+
+	return nil
+}
+
+// bumpCoin spends options.BumpCoin to a single output paying
+// its amount minus the fee bringing the fee rate of the stuck
+// transaction, of its unconfirmed ancestors and of the child
+// to feeRate. The child pays at least the minimum relay fee of
+// its own size. The coin and the output are added to options
+// and metadata. It returns the fee of the child.
+func (s *ConstructionAPIService) bumpCoin(
+	ctx context.Context,
+	options *preprocessOptions,
+	feeRate int64,
+	metadata *constructionMetadata,
+) (int64, *types.Error) {
+	hash, index, err := bitcoin.ParseCoinIdentifier(options.BumpCoin)
+	if err != nil {
+		return 0, wrapErr(ErrInvalidCoin, err)
+	}
+
+	entry, err := s.client.MempoolEntry(ctx, hash.String())
+	if errors.Is(err, bitcoin.ErrTransactionNotInMempool) {
+		return 0, wrapErr(ErrTransactionNotInMempool, err)
+	}
+	if err != nil {
+		return 0, wrapErr(ErrBitcoind, err)
+	}
+
+	parent, err := s.client.RawTransaction(ctx, hash.String())
+	if err != nil {
+		return 0, wrapErr(ErrBitcoind, err)
+	}
+
+	if int(index) >= len(parent.Outputs) {
+		return 0, wrapErr(
+			ErrInvalidCoin,
+			fmt.Errorf("transaction %s has no output %d", hash.String(), index),
+		)
+	}
+
+	output := parent.Outputs[index]
+	balance, err := btcutil.NewAmount(output.Value)
+	if err != nil {
+		return 0, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	if output.ScriptPubKey == nil {
+		return 0, wrapErr(
+			ErrUnableToDecodeScriptPubKey,
+			fmt.Errorf("output %d of transaction %s has no script", index, hash.String()),
+		)
+	}
+
+	script, err := hex.DecodeString(output.ScriptPubKey.Hex)
+	if err != nil {
+		return 0, wrapErr(ErrUnableToDecodeScriptPubKey, err)
+	}
+
+	class, addr, err := bitcoin.ParseSingleAddress(s.config.Params, script)
+	if err != nil {
+		return 0, wrapErr(
+			ErrUnableToDecodeAddress,
+			fmt.Errorf("%w unable to parse address of coin %s", err, options.BumpCoin.Identifier),
+		)
+	}
+
+	// Coins of P2SH scripts require a redeem script
+	// only known by the owner of the coin.
+	childSize := int64(math.Ceil(options.EstimatedSize))
+	switch class {
+	case txscript.PubKeyHashTy:
+		childSize += int64(bitcoin.EstimateInputSize(s.config.Params, class, nil, true))
+	case txscript.WitnessV0PubKeyHashTy:
+		childSize += int64(bitcoin.EstimateInputSize(s.config.Params, class, nil, true))
+		childSize += bitcoin.TransactionOverhead - bitcoin.LegacyTransactionOverhead
+	default:
+		return 0, wrapErr(
+			ErrUnsupportedScriptType,
+			fmt.Errorf("coins of %s scripts cannot be bumped", class),
+		)
+	}
+
+	// The fee of the output may depend on its
+	// amount, so it is computed for the actual amount.
+	childFee := func(amount int64) int64 {
+		packageSize := entry.AncestorSize + childSize
+		fee := s.feePolicy.Fee(feeRate, packageSize, []int64{amount}) - entry.AncestorFees
+		if minFee := s.feePolicy.MinRelayFee(childSize, []int64{amount}); fee < minFee {
+			return minFee
+		}
+
+		return fee
+	}
+	fee := childFee(int64(balance))
+	fee = childFee(int64(balance) - fee)
+	if int64(balance)-fee <= 0 {
+		return 0, wrapErr(
+			ErrInsufficientFunds,
+			fmt.Errorf(
+				"%w: coin %s worth %d cannot pay a fee of %d",
+				errInsufficientFunds,
+				options.BumpCoin.Identifier,
+				int64(balance),
+				fee,
+			),
+		)
+	}
+
+	// The child of a large stuck transaction pays a high
+	// fee rate of its own, which ConstructionSubmit refuses
+	// above the maximum fee rate.
+	if s.config.Construction != nil && s.config.Construction.MaxFeeRate > 0 {
+		if childRate := fee * bytesPerKB / childSize; childRate > s.config.Construction.MaxFeeRate {
+			rErr := wrapErr(ErrFeeRateTooHigh, fmt.Errorf(
+				"child fee of %d at %d per kB exceeds the maximum fee rate of %d per kB",
+				fee,
+				childRate,
+				s.config.Construction.MaxFeeRate,
+			))
+			rErr.Details["fee"] = fee
+			rErr.Details["fee_rate"] = childRate
+			rErr.Details["max_fee_rate"] = s.config.Construction.MaxFeeRate
+
+			return 0, rErr
+		}
+	}
+
+	// The coin is not known by the indexer until the
+	// stuck transaction is confirmed, so its script
+	// is checked against its address instead.
+	address := addr.EncodeAddress()
+	options.Coins = []*types.Coin{
+		{
+			CoinIdentifier: options.BumpCoin,
+			Amount: &types.Amount{
+				Value:    strconv.FormatInt(-int64(balance), 10),
+				Currency: s.config.Currency,
+			},
+		},
+	}
+	options.ScriptPubKeys = []string{output.ScriptPubKey.Hex}
+	options.InputAddresses = []string{address}
+
+	metadata.Coins = options.Coins
+	metadata.Account = &types.AccountIdentifier{Address: address}
+	metadata.Change = s.changeOperation(options, 0, int64(balance)-fee)
+	metadata.Sweep = true
+	if options.PSBT {
+		metadata.PreviousTransactions = []string{parent.Hex}
+	}
+
+	if rErr := s.validateOutputs(selectedOperations(nil, metadata)); rErr != nil {
+		return 0, rErr
+	}
+
+	return fee, nil
+}
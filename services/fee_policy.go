@@ -0,0 +1,121 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
+)
+
+const (
+	// bytesPerKB is the number of bytes in a kB
+	// for fee calculations.
+	bytesPerKB = int64(1000) // nolint:gomnd
+)
+
+// FeePolicy determines the fee rate and the fee of
+// transactions built by the Construction API.
+type FeePolicy interface {
+	// FeeRate returns the fee rate in Satoshis per kB to
+	// use given the fee rate estimated by the node in DOGE
+	// per kB and an optional multiplier. A non-positive
+	// estimate means the node could not estimate a fee rate.
+	FeeRate(estimate float64, multiplier *float64) int64
+
+	// Fee returns the fee in Satoshis of a transaction of
+	// size bytes with the provided output amounts.
+	Fee(feeRate int64, size int64, outputs []int64) int64
+
+	// MinRelayFee returns the smallest fee in Satoshis
+	// the node relays for a transaction of size bytes with
+	// the provided output amounts.
+	MinRelayFee(size int64, outputs []int64) int64
+}
+
+// DogecoinFeePolicy implements the relay and wallet fee
+// rules of Dogecoin Core 1.14. The size of a transaction is
+// rounded up to the next started kB and every output below
+// the dust limit is charged the fee rate once more.
+type DogecoinFeePolicy struct {
+	// MinRelayFeeRate is the relay fee rate in Satoshis per kB.
+	MinRelayFeeRate int64
+
+	// MinFeeRate is the lowest fee rate in Satoshis per kB
+	// suggested by the policy.
+	MinFeeRate int64
+
+	// FallbackFeeRate is the fee rate in Satoshis per kB used
+	// when the node cannot estimate a fee rate.
+	FallbackFeeRate int64
+
+	// DustLimit is the amount in Satoshis below which an
+	// output is charged an extra fee.
+	DustLimit int64
+}
+
+// NewDogecoinFeePolicy returns a *DogecoinFeePolicy with
+// the defaults of Dogecoin Core 1.14.
+func NewDogecoinFeePolicy() *DogecoinFeePolicy {
+	return &DogecoinFeePolicy{
+		MinRelayFeeRate: dogecoin.MinRelayFeeRate,
+		MinFeeRate:      dogecoin.RecommendedMinFeeRate,
+		FallbackFeeRate: dogecoin.FallbackFeeRate,
+		DustLimit:       dogecoin.DustLimit,
+	}
+}
+
+// FeeRate implements the FeePolicy interface.
+func (p *DogecoinFeePolicy) FeeRate(estimate float64, multiplier *float64) int64 {
+	feeRate := float64(p.FallbackFeeRate)
+	if estimate > 0 {
+		feeRate = estimate * float64(dogecoin.SatoshisInBitcoin)
+	}
+
+	if multiplier != nil {
+		feeRate *= *multiplier
+	}
+
+	if int64(feeRate) < p.MinFeeRate {
+		return p.MinFeeRate
+	}
+
+	return int64(feeRate)
+}
+
+// Fee implements the FeePolicy interface.
+func (p *DogecoinFeePolicy) Fee(feeRate int64, size int64, outputs []int64) int64 {
+	fee := feeRate * roundUpToKB(size) / bytesPerKB
+	for _, output := range outputs {
+		if output < p.DustLimit {
+			fee += feeRate
+		}
+	}
+
+	return fee
+}
+
+// MinRelayFee implements the FeePolicy interface.
+func (p *DogecoinFeePolicy) MinRelayFee(size int64, outputs []int64) int64 {
+	return p.Fee(p.MinRelayFeeRate, size, outputs)
+}
+
+// roundUpToKB rounds size up to the next started kB,
+// like CFeeRate::GetFee in Dogecoin Core.
+func roundUpToKB(size int64) int64 {
+	if remainder := size % bytesPerKB; remainder > 0 {
+		return size + bytesPerKB - remainder
+	}
+
+	return size
+}
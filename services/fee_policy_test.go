@@ -0,0 +1,106 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDogecoinFeePolicy_FeeRate(t *testing.T) {
+	policy := NewDogecoinFeePolicy()
+	half := float64(0.5)
+	double := float64(2)
+
+	tests := map[string]struct {
+		estimate   float64
+		multiplier *float64
+		expected   int64
+	}{
+		"estimate above minimum": {
+			estimate: 0.05,
+			expected: 5000000,
+		},
+		"estimate below minimum": {
+			estimate: 0.00001,
+			expected: 1000000,
+		},
+		"no estimate": {
+			estimate: -1,
+			expected: 1000000,
+		},
+		"no estimate with multiplier": {
+			estimate:   -1,
+			multiplier: &double,
+			expected:   2000000,
+		},
+		"multiplier below minimum": {
+			estimate:   0.015,
+			multiplier: &half,
+			expected:   1000000,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, policy.FeeRate(test.estimate, test.multiplier))
+		})
+	}
+}
+
+func TestDogecoinFeePolicy_Fee(t *testing.T) {
+	policy := NewDogecoinFeePolicy()
+
+	tests := map[string]struct {
+		feeRate  int64
+		size     int64
+		outputs  []int64
+		expected int64
+	}{
+		"exactly 1 kB": {
+			feeRate:  1000000,
+			size:     1000,
+			outputs:  []int64{100000000},
+			expected: 1000000,
+		},
+		"rounds up started kB": {
+			feeRate:  1000000,
+			size:     1001,
+			outputs:  []int64{100000000},
+			expected: 2000000,
+		},
+		"small transaction": {
+			feeRate:  1000000,
+			size:     226,
+			outputs:  []int64{100000000, 200000000},
+			expected: 1000000,
+		},
+		"dust outputs": {
+			feeRate:  1000000,
+			size:     226,
+			outputs:  []int64{999999, 1000000, 1},
+			expected: 3000000,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, policy.Fee(test.feeRate, test.size, test.outputs))
+		})
+	}
+
+	assert.Equal(t, int64(200000), policy.MinRelayFee(226, []int64{1}))
+}
@@ -100,6 +100,7 @@ func (s *NetworkAPIService) NetworkOptions(
 			OperationTypes:          bitcoin.OperationTypes,
 			Errors:                  Errors,
 			HistoricalBalanceLookup: HistoricalBalanceLookup,
+			CallMethods:             CallMethods,
 			MempoolCoins:            MempoolCoins,
 		},
 	}, nil
@@ -41,6 +41,7 @@ var (
 			OperationTypes:          bitcoin.OperationTypes,
 			Errors:                  Errors,
 			HistoricalBalanceLookup: HistoricalBalanceLookup,
+			CallMethods:             CallMethods,
 		},
 	}
 
@@ -0,0 +1,564 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+const (
+	// psbtMagic is the prefix of a serialized PSBT.
+	psbtMagic = "psbt\xff"
+
+	// psbtBase64Magic is the prefix of a base64
+	// encoded PSBT.
+	psbtBase64Magic = "cHNidP8"
+
+	// psbtProprietaryPrefix is the identifier of the
+	// proprietary PSBT fields set by buildPSBT.
+	psbtProprietaryPrefix = "rosetta-dogecoin"
+
+	// psbtNetworkSubtype is the proprietary field holding
+	// the hash of the network identifier of the PSBT.
+	psbtNetworkSubtype = 0x00
+
+	// psbtInputIndexesSubtype and psbtOutputIndexesSubtype
+	// are the proprietary fields holding the InputIndexes
+	// and OutputIndexes of the unsigned transaction.
+	psbtInputIndexesSubtype  = 0x01
+	psbtOutputIndexesSubtype = 0x02
+
+	// psbtPreimageSubtype is the proprietary field holding
+	// the preimage of an input claiming a hash time locked
+	// contract, as BIP-174 has no field for it.
+	psbtPreimageSubtype = 0x03
+)
+
+// psbtProprietaryKey returns the key of the
+// proprietary input field of subtype.
+func psbtProprietaryKey(subtype byte) []byte {
+	key := []byte{byte(psbt.ProprietaryInputType), byte(len(psbtProprietaryPrefix))}
+	key = append(key, psbtProprietaryPrefix...)
+
+	return append(key, subtype)
+}
+
+// addPSBTProprietary sets the proprietary
+// field of subtype of input to value.
+func addPSBTProprietary(input *psbt.PInput, subtype byte, value []byte) {
+	input.Unknowns = append(input.Unknowns, &psbt.Unknown{
+		Key:   psbtProprietaryKey(subtype),
+		Value: value,
+	})
+}
+
+// psbtProprietary returns the value of the proprietary
+// field of subtype of input, or nil if it is not set.
+func psbtProprietary(input *psbt.PInput, subtype byte) []byte {
+	key := psbtProprietaryKey(subtype)
+	for _, unknown := range input.Unknowns {
+		if bytes.Equal(unknown.Key, key) {
+			return unknown.Value
+		}
+	}
+
+	return nil
+}
+
+// encodePSBTIndexes serializes indexes as
+// a list of variable length integers.
+func encodePSBTIndexes(indexes []int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := wire.WriteVarInt(&buf, 0, uint64(len(indexes))); err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		if err := wire.WriteVarInt(&buf, 0, uint64(index)); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodePSBTIndexes deserializes the indexes
+// serialized by encodePSBTIndexes.
+func decodePSBTIndexes(value []byte) ([]int64, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	r := bytes.NewReader(value)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every index takes at least one byte.
+	if count > uint64(r.Len()) {
+		return nil, fmt.Errorf("expected %d indexes in %d bytes", count, r.Len())
+	}
+
+	indexes := make([]int64, count)
+	for i := range indexes {
+		index, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes[i] = int64(index)
+	}
+
+	return indexes, nil
+}
+
+// buildPSBT returns the base64 encoded BIP-174 PSBT of the
+// transaction tx of unsigned. Every input includes the
+// transaction it spends and its sighash type, P2SH inputs
+// and outputs also include their redeem script and inputs
+// claiming a hash time locked contract their preimage.
+//
+// The first input also holds the proprietary fields binding
+// the PSBT to network and the BIP-69 indexes of unsigned, as
+// the psbt package does not serialize global unknowns.
+func buildPSBT(
+	tx *wire.MsgTx,
+	network *types.NetworkIdentifier,
+	unsigned *unsignedTransaction,
+	rawTransactions []string,
+	scriptPubKeys [][]byte,
+) (string, error) {
+	if len(rawTransactions) != len(tx.TxIn) {
+		return "", fmt.Errorf(
+			"expected %d previous transactions, got %d",
+			len(tx.TxIn),
+			len(rawTransactions),
+		)
+	}
+
+	// The unsigned transaction of a PSBT has no
+	// signature scripts or witnesses.
+	packet, err := psbt.NewFromUnsignedTx(tx.Copy())
+	if err != nil {
+		return "", err
+	}
+
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return "", err
+	}
+
+	for i, txIn := range tx.TxIn {
+		rawTransaction, err := hex.DecodeString(rawTransactions[i])
+		if err != nil {
+			return "", fmt.Errorf("%w: unable to decode previous transaction %d", err, i)
+		}
+
+		var previous wire.MsgTx
+		if err := previous.Deserialize(bytes.NewReader(rawTransaction)); err != nil {
+			return "", fmt.Errorf("%w: unable to deserialize previous transaction %d", err, i)
+		}
+
+		outPoint := txIn.PreviousOutPoint
+		if previous.TxHash() != outPoint.Hash || int(outPoint.Index) >= len(previous.TxOut) {
+			return "", fmt.Errorf("previous transaction %d does not contain %s", i, outPoint)
+		}
+
+		if !bytes.Equal(previous.TxOut[outPoint.Index].PkScript, scriptPubKeys[i]) {
+			return "", fmt.Errorf("previous transaction %d does not match script pub key", i)
+		}
+
+		if txscript.IsPayToWitnessPubKeyHash(scriptPubKeys[i]) {
+			err = updater.AddInWitnessUtxo(previous.TxOut[outPoint.Index], i)
+		} else {
+			err = updater.AddInNonWitnessUtxo(&previous, i)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if err := updater.AddInSighashType(unsigned.sigHashType(i), i); err != nil {
+			return "", err
+		}
+
+		if i < len(unsigned.RedeemScripts) && len(unsigned.RedeemScripts[i]) > 0 {
+			redeemScript, err := hex.DecodeString(unsigned.RedeemScripts[i])
+			if err != nil {
+				return "", fmt.Errorf("%w: unable to decode redeem script %d", err, i)
+			}
+
+			if err := updater.AddInRedeemScript(redeemScript, i); err != nil {
+				return "", err
+			}
+		}
+
+		if rawPreimage := unsigned.preimage(i); len(rawPreimage) > 0 {
+			preimage, err := hex.DecodeString(rawPreimage)
+			if err != nil {
+				return "", fmt.Errorf("%w: unable to decode preimage %d", err, i)
+			}
+
+			addPSBTProprietary(&packet.Inputs[i], psbtPreimageSubtype, preimage)
+		}
+	}
+
+	for i, rawRedeemScript := range unsigned.OutputRedeemScripts {
+		if len(rawRedeemScript) == 0 {
+			continue
+		}
+
+		redeemScript, err := hex.DecodeString(rawRedeemScript)
+		if err != nil {
+			return "", fmt.Errorf("%w: unable to decode output redeem script %d", err, i)
+		}
+
+		if err := updater.AddOutRedeemScript(redeemScript, i); err != nil {
+			return "", err
+		}
+	}
+
+	addPSBTProprietary(&packet.Inputs[0], psbtNetworkSubtype, []byte(types.Hash(network)))
+	if unsigned.InputIndexes != nil {
+		inputIndexes, err := encodePSBTIndexes(unsigned.InputIndexes)
+		if err != nil {
+			return "", err
+		}
+
+		outputIndexes, err := encodePSBTIndexes(unsigned.OutputIndexes)
+		if err != nil {
+			return "", err
+		}
+
+		addPSBTProprietary(&packet.Inputs[0], psbtInputIndexesSubtype, inputIndexes)
+		addPSBTProprietary(&packet.Inputs[0], psbtOutputIndexesSubtype, outputIndexes)
+	}
+
+	return packet.B64Encode()
+}
+
+// decodePSBT decodes a base64 or hex encoded PSBT. It
+// returns false if transaction is not a PSBT.
+func decodePSBT(transaction string) (*psbt.Packet, bool, error) {
+	if strings.HasPrefix(transaction, psbtBase64Magic) {
+		packet, err := psbt.NewFromRawBytes(strings.NewReader(transaction), true)
+		return packet, true, err
+	}
+
+	if strings.HasPrefix(transaction, hex.EncodeToString([]byte(psbtMagic))) {
+		raw, err := hex.DecodeString(transaction)
+		if err != nil {
+			return nil, true, err
+		}
+
+		packet, err := psbt.NewFromRawBytes(bytes.NewReader(raw), false)
+		return packet, true, err
+	}
+
+	return nil, false, nil
+}
+
+// finalizePSBTInput sets the final signature script of
+// input inIndex of packet from its partial signatures.
+func (s *ConstructionAPIService) finalizePSBTInput(packet *psbt.Packet, inIndex int) error {
+	input := packet.Inputs[inIndex]
+	if input.FinalScriptSig != nil || input.FinalScriptWitness != nil {
+		return nil
+	}
+
+	if input.RedeemScript == nil {
+		_, err := psbt.MaybeFinalize(packet, inIndex)
+		return err
+	}
+
+	// The finalizer of the psbt package only supports
+	// n-of-n multisig redeem scripts, so P2SH inputs use
+	// the first signatures in redeem script order.
+	// Hash time locked contracts are claimed when the
+	// input holds a preimage and refunded otherwise.
+	var builder *txscript.ScriptBuilder
+	var pushes [][]byte
+	pubKeys, nRequired, err := bitcoin.ParseMultiSigScript(s.config.Params, input.RedeemScript)
+	if err == nil {
+		builder = txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+	} else {
+		var pubKey *btcutil.AddressPubKey
+		preimage := psbtProprietary(&input, psbtPreimageSubtype)
+		pubKey, pushes, err = s.scriptHashSpend(input.RedeemScript, preimage)
+		if err != nil {
+			return err
+		}
+
+		pubKeys = []*btcutil.AddressPubKey{pubKey}
+		nRequired = 1
+		builder = txscript.NewScriptBuilder()
+	}
+
+	signatures := 0
+	for _, pubKey := range pubKeys {
+		for _, partial := range input.PartialSigs {
+			if signatures < nRequired && bytes.Equal(partial.PubKey, pubKey.ScriptAddress()) {
+				builder.AddData(partial.Signature)
+				signatures++
+			}
+		}
+	}
+
+	if signatures < nRequired {
+		return fmt.Errorf("expected %d signatures, got %d", nRequired, signatures)
+	}
+
+	for _, push := range pushes {
+		builder.AddData(push)
+	}
+
+	sigScript, err := builder.AddData(input.RedeemScript).Script()
+	if err != nil {
+		return err
+	}
+
+	packet.Inputs[inIndex] = *psbt.NewPsbtInput(input.NonWitnessUtxo, nil)
+	packet.Inputs[inIndex].FinalScriptSig = sigScript
+
+	return nil
+}
+
+// psbtPrevOut returns the output spent by
+// input inIndex of packet.
+func psbtPrevOut(packet *psbt.Packet, inIndex int) (*wire.TxOut, error) {
+	input := packet.Inputs[inIndex]
+	if input.WitnessUtxo != nil {
+		return input.WitnessUtxo, nil
+	}
+
+	outPoint := packet.UnsignedTx.TxIn[inIndex].PreviousOutPoint
+	if input.NonWitnessUtxo == nil ||
+		input.NonWitnessUtxo.TxHash() != outPoint.Hash ||
+		int(outPoint.Index) >= len(input.NonWitnessUtxo.TxOut) {
+		return nil, fmt.Errorf("input %d is missing the output it spends", inIndex)
+	}
+
+	return input.NonWitnessUtxo.TxOut[outPoint.Index], nil
+}
+
+// psbtSigHashType returns the signature hash
+// type of input inIndex of packet.
+func psbtSigHashType(packet *psbt.Packet, inIndex int) txscript.SigHashType {
+	if packet.Inputs[inIndex].SighashType == 0 {
+		return txscript.SigHashAll
+	}
+
+	return packet.Inputs[inIndex].SighashType
+}
+
+// psbtSignatureHash returns the signature hash of
+// input inIndex of packet.
+func psbtSignatureHash(packet *psbt.Packet, inIndex int, prevOut *wire.TxOut) ([]byte, error) {
+	class := txscript.GetScriptClass(prevOut.PkScript)
+	script := prevOut.PkScript
+	if packet.Inputs[inIndex].RedeemScript != nil {
+		script = packet.Inputs[inIndex].RedeemScript
+	}
+
+	return signatureHash(
+		packet.UnsignedTx,
+		inIndex,
+		class,
+		script,
+		prevOut.Value,
+		psbtSigHashType(packet, inIndex),
+	)
+}
+
+// psbtIndexes returns the BIP-69 indexes of the
+// operations of the inputs and outputs of packet.
+func psbtIndexes(packet *psbt.Packet) ([]int64, []int64, error) {
+	inputIndexes, err := decodePSBTIndexes(psbtProprietary(&packet.Inputs[0], psbtInputIndexesSubtype))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unable to decode input indexes", err)
+	}
+
+	outputIndexes, err := decodePSBTIndexes(psbtProprietary(&packet.Inputs[0], psbtOutputIndexesSubtype))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unable to decode output indexes", err)
+	}
+
+	if inputIndexes == nil && outputIndexes == nil {
+		return nil, nil, nil
+	}
+
+	if len(inputIndexes) != len(packet.UnsignedTx.TxIn) ||
+		len(outputIndexes) != len(packet.UnsignedTx.TxOut) {
+		return nil, nil, errors.New("indexes do not match the inputs and outputs")
+	}
+
+	return inputIndexes, outputIndexes, nil
+}
+
+// combinePSBT adds signatures to the partially or
+// fully signed packet and finalizes it. Signatures are
+// matched to inputs by their signing payload. Only
+// PSBTs built for the network of s are accepted.
+func (s *ConstructionAPIService) combinePSBT(
+	packet *psbt.Packet,
+	signatures []*types.Signature,
+) (*types.ConstructionCombineResponse, *types.Error) {
+	if len(packet.Inputs) == 0 ||
+		!bytes.Equal(
+			psbtProprietary(&packet.Inputs[0], psbtNetworkSubtype),
+			[]byte(types.Hash(s.config.Network)),
+		) {
+		return nil, wrapErr(
+			ErrNetworkMismatch,
+			errors.New("psbt was not created for this network"),
+		)
+	}
+
+	// Finalized inputs lose their proprietary
+	// fields, so the indexes are read first.
+	inputIndexes, outputIndexes, err := psbtIndexes(packet)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	var outputRedeemScripts []string
+	for i, output := range packet.Outputs {
+		if len(output.RedeemScript) == 0 {
+			continue
+		}
+
+		if outputRedeemScripts == nil {
+			outputRedeemScripts = make([]string, len(packet.Outputs))
+		}
+		outputRedeemScripts[i] = hex.EncodeToString(output.RedeemScript)
+	}
+
+	inputAmounts := make([]string, len(packet.UnsignedTx.TxIn))
+	hashes := make([][]byte, len(packet.UnsignedTx.TxIn))
+	prevOuts := make([]*wire.TxOut, len(packet.UnsignedTx.TxIn))
+	for i := range packet.UnsignedTx.TxIn {
+		prevOut, err := psbtPrevOut(packet, i)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+		}
+		prevOuts[i] = prevOut
+
+		inputAmounts[i] = strconv.FormatInt(-prevOut.Value, 10)
+		hashes[i], err = psbtSignatureHash(packet, i, prevOut)
+		if err != nil {
+			return nil, wrapErr(ErrUnableToCalculateSignatureHash, err)
+		}
+	}
+
+	for _, signature := range signatures {
+		if signature == nil || signature.SigningPayload == nil || signature.PublicKey == nil {
+			return nil, wrapErr(
+				ErrInvalidSignatures,
+				errors.New("signature is missing its signing payload or public key"),
+			)
+		}
+
+		inIndex := -1
+		for i, hash := range hashes {
+			if bytes.Equal(hash, signature.SigningPayload.Bytes) {
+				inIndex = i
+				break
+			}
+		}
+
+		if inIndex < 0 {
+			return nil, wrapErr(
+				ErrInvalidSignatures,
+				errors.New("signing payload does not match any input"),
+			)
+		}
+
+		sig, rErr := inputSignature(signature, inIndex, hashes[inIndex], psbtSigHashType(packet, inIndex))
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		outcome, err := updater.Sign(inIndex, sig, signature.PublicKey.Bytes, nil, nil)
+		if err != nil {
+			return nil, wrapErr(
+				ErrInvalidSignatures,
+				fmt.Errorf("%w: unable to add signature to input %d", err, inIndex),
+			)
+		}
+
+		if outcome == psbt.SignInvalid {
+			return nil, wrapErr(
+				ErrInvalidSignatures,
+				fmt.Errorf("signature of input %d is invalid", inIndex),
+			)
+		}
+	}
+
+	for i := range packet.Inputs {
+		if err := s.finalizePSBTInput(packet, i); err != nil {
+			return nil, wrapErr(
+				ErrInvalidSignatures,
+				fmt.Errorf("%w: unable to finalize input %d", err, i),
+			)
+		}
+	}
+
+	tx, err := psbt.Extract(packet)
+	if err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, err)
+	}
+
+	if rErr := verifySignatures(tx, prevOuts); rErr != nil {
+		return nil, rErr
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+	if err := tx.Serialize(buf); err != nil {
+		return nil, wrapErr(ErrUnableToParseIntermediateResult, fmt.Errorf("%w serialize tx", err))
+	}
+
+	rawTx, rErr := encodeEnvelope(signedEnvelope, s.config.Network, &signedTransaction{
+		Transaction:         hex.EncodeToString(buf.Bytes()),
+		InputAmounts:        inputAmounts,
+		OutputRedeemScripts: outputRedeemScripts,
+		InputIndexes:        inputIndexes,
+		OutputIndexes:       outputIndexes,
+	})
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	return &types.ConstructionCombineResponse{
+		SignedTransaction: rawTx,
+	}, nil
+}
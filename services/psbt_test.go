@@ -0,0 +1,339 @@
+// Copyright 2021 Rosetta Dogecoin Developers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/configuration"
+	"github.com/rosetta-dogecoin/rosetta-dogecoin/dogecoin"
+	mocks "github.com/rosetta-dogecoin/rosetta-dogecoin/mocks/services"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructionService_PSBT(t *testing.T) {
+	networkIdentifier = &types.NetworkIdentifier{
+		Network:    dogecoin.TestnetNetwork,
+		Blockchain: dogecoin.Blockchain,
+	}
+
+	cfg := &configuration.Configuration{
+		Mode:     configuration.Online,
+		Network:  networkIdentifier,
+		Params:   dogecoin.TestnetParams,
+		Currency: dogecoin.TestnetCurrency,
+	}
+
+	mockIndexer := &mocks.Indexer{}
+	mockClient := &mocks.Client{}
+	servicer := NewConstructionAPIService(cfg, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	ctx := context.Background()
+
+	privKey1, publicKey1 := forcePrivateKey(1)
+	privKey2, publicKey2 := forcePrivateKey(2)
+	_, publicKey3 := forcePrivateKey(3)
+
+	sender := "nesRpRaAbTDmZHwmzBkLd2AtF7Z9L9z5S2"
+	senderAddr, _ := btcutil.DecodeAddress(sender, dogecoin.TestnetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+
+	redeemScript, err := bitcoin.MultiSigScript(
+		dogecoin.TestnetParams,
+		[][]byte{publicKey1.Bytes, publicKey2.Bytes, publicKey3.Bytes},
+		2,
+	)
+	assert.NoError(t, err)
+	multiSigAddr, _ := btcutil.NewAddressScriptHash(redeemScript, dogecoin.TestnetParams)
+	multiSigScript, _ := txscript.PayToAddrScript(multiSigAddr)
+
+	// The transaction creating the coins spent.
+	previous := wire.NewMsgTx(wire.TxVersion)
+	previous.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: 3},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	previous.AddTxOut(&wire.TxOut{Value: 1000000000, PkScript: senderScript})
+	previous.AddTxOut(&wire.TxOut{Value: 500000000, PkScript: multiSigScript})
+	buf := bytes.NewBuffer(make([]byte, 0, previous.SerializeSize()))
+	assert.NoError(t, previous.Serialize(buf))
+	previousHex := hex.EncodeToString(buf.Bytes())
+	previousHash := previous.TxHash().String()
+
+	ops := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 0,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: sender,
+			},
+			Amount: &types.Amount{
+				Value:    "-1000000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: previousHash + ":0",
+				},
+				CoinAction: types.CoinSpent,
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 1,
+			},
+			Type: bitcoin.InputOpType,
+			Account: &types.AccountIdentifier{
+				Address: multiSigAddr.EncodeAddress(),
+			},
+			Amount: &types.Amount{
+				Value:    "-500000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+			CoinChange: &types.CoinChange{
+				CoinIdentifier: &types.CoinIdentifier{
+					Identifier: previousHash + ":1",
+				},
+				CoinAction: types.CoinSpent,
+			},
+			Metadata: map[string]interface{}{
+				"redeem_script": hex.EncodeToString(redeemScript),
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{
+				Index: 2,
+			},
+			Type: bitcoin.OutputOpType,
+			Account: &types.AccountIdentifier{
+				Address: "nUoWxHhgWqZjMQtMdzDm5Hv34zkbacZa64",
+			},
+			Amount: &types.Amount{
+				Value:    "1490000000",
+				Currency: dogecoin.TestnetCurrency,
+			},
+		},
+	}
+
+	preprocessResponse, rErr := servicer.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          map[string]interface{}{"psbt": true, "bip69": true},
+	})
+	assert.Nil(t, rErr)
+	assert.Equal(t, true, preprocessResponse.Options["psbt"])
+
+	var options preprocessOptions
+	assert.NoError(t, types.UnmarshalMap(preprocessResponse.Options, &options))
+	scripts := []*bitcoin.ScriptPubKey{
+		{
+			Hex:          hex.EncodeToString(senderScript),
+			RequiredSigs: 1,
+			Type:         "pubkeyhash",
+			Addresses:    []string{sender},
+		},
+		{
+			Hex:          hex.EncodeToString(multiSigScript),
+			RequiredSigs: 1,
+			Type:         "scripthash",
+			Addresses:    []string{multiSigAddr.EncodeAddress()},
+		},
+	}
+	mockIndexer.On("GetScriptPubKeys", ctx, options.Coins).Return(scripts, nil).Once()
+	mockIndexer.On(
+		"GetRawTransactions",
+		ctx,
+		options.Coins,
+	).Return(
+		[]string{previousHex, previousHex},
+		nil,
+	).Once()
+	mockClient.On("SuggestedFeeRate", ctx, defaultConfirmationTarget).Return(float64(-1), nil).Once()
+	metadataResponse, rErr := servicer.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+		NetworkIdentifier: networkIdentifier,
+		Options:           preprocessResponse.Options,
+	})
+	assert.Nil(t, rErr)
+	assert.Equal(t, forceMarshalMap(t, &constructionMetadata{
+		ScriptPubKeys:        scripts,
+		PreviousTransactions: []string{previousHex, previousHex},
+		SuggestedFee:         1000000,
+		BIP69:                true,
+	}), metadataResponse.Metadata)
+
+	payloadsResponse, rErr := servicer.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: networkIdentifier,
+		Operations:        ops,
+		Metadata:          metadataResponse.Metadata,
+	})
+	assert.Nil(t, rErr)
+	assert.Len(t, payloadsResponse.Payloads, 3)
+
+	var unsigned unsignedTransaction
+	assert.NoError(t, json.Unmarshal(forceEnvelopePayload(t, payloadsResponse.UnsignedTransaction), &unsigned))
+	packet, err := psbt.NewFromRawBytes(strings.NewReader(unsigned.PSBT), true)
+	assert.NoError(t, err)
+	assert.Equal(t, previous.TxHash(), packet.Inputs[0].NonWitnessUtxo.TxHash())
+	assert.Equal(t, previous.TxHash(), packet.Inputs[1].NonWitnessUtxo.TxHash())
+	assert.Nil(t, packet.Inputs[0].RedeemScript)
+	assert.Equal(t, redeemScript, packet.Inputs[1].RedeemScript)
+	assert.Equal(t, txscript.SigHashAll, packet.Inputs[1].SighashType)
+	assert.Equal(
+		t,
+		[]byte(types.Hash(networkIdentifier)),
+		psbtProprietary(&packet.Inputs[0], psbtNetworkSubtype),
+	)
+
+	// An external signer signs the P2PKH input.
+	updater, err := psbt.NewUpdater(packet)
+	assert.NoError(t, err)
+	outcome, err := updater.Sign(
+		0,
+		normalizeSignature(signPayload(t, privKey1, payloadsResponse.Payloads[0]), txscript.SigHashAll),
+		publicKey1.Bytes,
+		nil,
+		nil,
+	)
+	assert.NoError(t, err)
+	assert.EqualValues(t, psbt.SignSuccesful, outcome)
+	partial, err := packet.B64Encode()
+	assert.NoError(t, err)
+
+	multiSigSignatures := []*types.Signature{
+		{
+			Bytes:          signPayload(t, privKey1, payloadsResponse.Payloads[1]),
+			SigningPayload: payloadsResponse.Payloads[1],
+			PublicKey:      publicKey1,
+			SignatureType:  types.Ecdsa,
+		},
+		{
+			Bytes:          signPayload(t, privKey2, payloadsResponse.Payloads[2]),
+			SigningPayload: payloadsResponse.Payloads[2],
+			PublicKey:      publicKey2,
+			SignatureType:  types.Ecdsa,
+		},
+	}
+
+	// PSBTs of other networks are refused.
+	mainnetServicer := NewConstructionAPIService(&configuration.Configuration{
+		Mode: configuration.Online,
+		Network: &types.NetworkIdentifier{
+			Network:    dogecoin.MainnetNetwork,
+			Blockchain: dogecoin.Blockchain,
+		},
+		Params:   dogecoin.MainnetParams,
+		Currency: dogecoin.MainnetCurrency,
+	}, mockClient, mockIndexer, NewDogecoinFeePolicy())
+	_, rErr = mainnetServicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: partial,
+		Signatures:          multiSigSignatures,
+	})
+	assert.Equal(t, ErrNetworkMismatch.Code, rErr.Code)
+
+	// Signatures without a signing payload are refused.
+	_, rErr = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: partial,
+		Signatures: []*types.Signature{
+			{
+				Bytes:         multiSigSignatures[0].Bytes,
+				PublicKey:     publicKey1,
+				SignatureType: types.Ecdsa,
+			},
+		},
+	})
+	assert.Equal(t, ErrInvalidSignatures.Code, rErr.Code)
+
+	// The partially signed PSBT cannot be finalized
+	// without all signatures.
+	_, rErr = servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: partial,
+		Signatures:          multiSigSignatures[:1],
+	})
+	assert.Equal(t, ErrInvalidSignatures.Code, rErr.Code)
+
+	combineResponse, rErr := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: partial,
+		Signatures:          multiSigSignatures,
+	})
+	assert.Nil(t, rErr)
+
+	signedTx := forceSignedTx(t, combineResponse.SignedTransaction)
+	assertValidInput(t, signedTx, 0, senderScript, 1000000000)
+	assertValidInput(t, signedTx, 1, multiSigScript, 500000000)
+
+	var signed signedTransaction
+	assert.NoError(t, json.Unmarshal(forceEnvelopePayload(t, combineResponse.SignedTransaction), &signed))
+	assert.Equal(t, []string{"-1000000000", "-500000000"}, signed.InputAmounts)
+	assert.Equal(t, []int64{0, 1}, signed.InputIndexes)
+	assert.Equal(t, []int64{2}, signed.OutputIndexes)
+
+	parseResponse, rErr := servicer.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: networkIdentifier,
+		Signed:            true,
+		Transaction:       combineResponse.SignedTransaction,
+	})
+	assert.Nil(t, rErr)
+	assert.Len(t, parseResponse.Operations, len(ops))
+	for i, op := range parseResponse.Operations {
+		assert.Equal(t, ops[i].OperationIdentifier.Index, op.OperationIdentifier.Index)
+		assert.Equal(t, ops[i].Account.Address, op.Account.Address)
+	}
+
+	// Fully signed PSBTs are finalized without
+	// additional signatures.
+	packet, err = psbt.NewFromRawBytes(strings.NewReader(partial), true)
+	assert.NoError(t, err)
+	updater, err = psbt.NewUpdater(packet)
+	assert.NoError(t, err)
+	for _, signature := range multiSigSignatures {
+		_, err := updater.Sign(
+			1,
+			normalizeSignature(signature.Bytes, txscript.SigHashAll),
+			signature.PublicKey.Bytes,
+			nil,
+			nil,
+		)
+		assert.NoError(t, err)
+	}
+	var full bytes.Buffer
+	assert.NoError(t, packet.Serialize(&full))
+
+	fullResponse, rErr := servicer.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   networkIdentifier,
+		UnsignedTransaction: hex.EncodeToString(full.Bytes()),
+	})
+	assert.Nil(t, rErr)
+	assert.Equal(t, combineResponse, fullResponse)
+
+	mockClient.AssertExpectations(t)
+	mockIndexer.AssertExpectations(t)
+}
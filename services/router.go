@@ -50,7 +50,13 @@ func NewBlockchainRouter(
 		asserter,
 	)
 
-	constructionAPIService := NewConstructionAPIService(config, client, i)
+	feePolicy := NewDogecoinFeePolicy()
+	constructionAPIService := NewConstructionAPIService(
+		config,
+		client,
+		i,
+		feePolicy,
+	)
 	constructionAPIController := server.NewConstructionAPIController(
 		constructionAPIService,
 		asserter,
@@ -62,11 +68,18 @@ func NewBlockchainRouter(
 		asserter,
 	)
 
+	callAPIService := NewCallAPIService(config, client, i, feePolicy)
+	callAPIController := server.NewCallAPIController(
+		callAPIService,
+		asserter,
+	)
+
 	return server.NewRouter(
 		networkAPIController,
 		blockAPIController,
 		accountAPIController,
 		constructionAPIController,
 		mempoolAPIController,
+		callAPIController,
 	)
 }
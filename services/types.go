@@ -17,9 +17,11 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/rosetta-dogecoin/rosetta-dogecoin/bitcoin"
 
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/coinbase/rosetta-sdk-go/types"
 )
 
@@ -49,13 +51,15 @@ const (
 	MiddlewareVersion = "0.0.9"
 )
 
-// Client is used by the servicers to get Peer information
-// and to submit transactions.
+// Client is used by the servicers to get Peer information,
+// to inspect the mempool and to submit transactions.
 type Client interface {
 	GetPeers(context.Context) ([]*types.Peer, error)
 	SendRawTransaction(context.Context, string) (string, error)
 	SuggestedFeeRate(context.Context, int64) (float64, error)
 	RawMempool(context.Context) ([]string, error)
+	MempoolEntry(context.Context, string) (*bitcoin.MempoolEntry, error)
+	RawTransaction(context.Context, string) (*bitcoin.Transaction, error)
 }
 
 // Indexer is used by the servicers to get block and account data.
@@ -77,6 +81,14 @@ type Indexer interface {
 		context.Context,
 		[]*types.Coin,
 	) ([]*bitcoin.ScriptPubKey, error)
+	GetRawTransactions(
+		context.Context,
+		[]*types.Coin,
+	) ([]string, error)
+	AddRedeemScripts(
+		context.Context,
+		[][]byte,
+	) error
 	GetBalance(
 		context.Context,
 		*types.AccountIdentifier,
@@ -85,30 +97,451 @@ type Indexer interface {
 	) (*types.Amount, *types.BlockIdentifier, error)
 }
 
+// envelope wraps the unsignedTransaction and the
+// signedTransaction passed between the Construction
+// API endpoints.
+type envelope struct {
+	Version           int                      `json:"version"`
+	Type              string                   `json:"type"`
+	NetworkIdentifier *types.NetworkIdentifier `json:"network_identifier"`
+	Payload           json.RawMessage          `json:"payload"`
+
+	// Checksum is the hex-encoded first bytes of the
+	// double SHA-256 hash of the other fields.
+	Checksum string `json:"checksum"`
+}
+
 type unsignedTransaction struct {
 	Transaction    string                  `json:"transaction"`
 	ScriptPubKeys  []*bitcoin.ScriptPubKey `json:"scriptPubKeys"`
 	InputAmounts   []string                `json:"input_amounts"`
 	InputAddresses []string                `json:"input_addresses"`
+	RedeemScripts  []string                `json:"redeem_scripts,omitempty"`
+
+	// OutputRedeemScripts are the redeem scripts of the
+	// P2SH outputs, recorded by the indexer when the
+	// transaction is submitted.
+	OutputRedeemScripts []string `json:"output_redeem_scripts,omitempty"`
+
+	// SigHashTypes are the signature hash types of
+	// the inputs when any is not SIGHASH_ALL.
+	SigHashTypes []txscript.SigHashType `json:"sighash_types,omitempty"`
+
+	// Preimages are the preimages of the inputs
+	// claiming a hash time locked contract.
+	Preimages []string `json:"preimages,omitempty"`
+
+	// InputIndexes and OutputIndexes are the indexes of
+	// the operations of the inputs and outputs when they
+	// are sorted per BIP-69.
+	InputIndexes  []int64 `json:"input_indexes,omitempty"`
+	OutputIndexes []int64 `json:"output_indexes,omitempty"`
+
+	// PublicKeyFormats are the formats of the public
+	// keys hashed by the P2PKH inputs when any is
+	// uncompressed.
+	PublicKeyFormats []string `json:"public_key_formats,omitempty"`
+
+	// PSBT is the base64 encoded BIP-174 PSBT of
+	// Transaction for signers that do not support
+	// the Rosetta signing payloads.
+	PSBT string `json:"psbt,omitempty"`
+}
+
+// sigHashType returns the signature hash
+// type of input index.
+func (u *unsignedTransaction) sigHashType(index int) txscript.SigHashType {
+	if index >= len(u.SigHashTypes) {
+		return txscript.SigHashAll
+	}
+
+	return u.SigHashTypes[index]
+}
+
+// preimage returns the hex-encoded preimage
+// of input index, if any.
+func (u *unsignedTransaction) preimage(index int) string {
+	if index >= len(u.Preimages) {
+		return ""
+	}
+
+	return u.Preimages[index]
+}
+
+// publicKeyFormat returns the format of the
+// public key expected by input index.
+func (u *unsignedTransaction) publicKeyFormat(index int) string {
+	if index >= len(u.PublicKeyFormats) {
+		return publicKeyCompressed
+	}
+
+	return u.PublicKeyFormats[index]
+}
+
+// deriveMetadata is the optional metadata accepted by
+// /construction/derive to derive a P2SH multisig, lock
+// time or hash time locked contract address instead of
+// a P2PKH address.
+type deriveMetadata struct {
+	PublicKeys []string `json:"public_keys"`
+	Threshold  int      `json:"threshold"`
+
+	// LockTime derives the P2SH address locking coins
+	// to the public key until a block height or, from
+	// 500000000, a UNIX timestamp.
+	LockTime int64 `json:"lock_time,omitempty"`
+
+	// PaymentHash derives the P2SH address of a hash
+	// time locked contract claimed by the public key
+	// with the preimage of this hex-encoded SHA-256
+	// hash, or refunded to RefundPublicKey from
+	// LockTime.
+	PaymentHash     string `json:"payment_hash,omitempty"`
+	RefundPublicKey string `json:"refund_public_key,omitempty"`
+}
+
+// deriveResponseMetadata is returned from
+// /construction/derive for P2SH addresses and
+// for P2PKH addresses of uncompressed keys.
+type deriveResponseMetadata struct {
+	RedeemScript string `json:"redeem_script,omitempty"`
+
+	// PublicKeyFormat is publicKeyUncompressed when the
+	// address hashes an uncompressed public key. It must
+	// be set in the metadata of the inputs spending it.
+	PublicKeyFormat string `json:"public_key_format,omitempty"`
+}
+
+// inputMetadata is the optional metadata on an
+// INPUT operation.
+type inputMetadata struct {
+	// RedeemScript is required to spend a P2SH coin.
+	RedeemScript string `json:"redeem_script,omitempty"`
+
+	// Signers are the hex-encoded public keys expected
+	// to sign a multisig input. If empty, the first
+	// m keys of the redeem script are used.
+	Signers []string `json:"signers,omitempty"`
+
+	// SigHashType is the signature hash type of the
+	// input, like "SINGLE|ANYONECANPAY". It defaults
+	// to "ALL".
+	SigHashType string `json:"sighash_type,omitempty"`
+
+	// Sequence is the sequence number of the input. It
+	// defaults to the final sequence number, or to one
+	// less when the transaction has a lock time.
+	Sequence *uint32 `json:"sequence,omitempty"`
+
+	// Preimage is the hex-encoded preimage claiming a
+	// hash time locked contract. Contracts spent without
+	// a preimage are refunded.
+	Preimage string `json:"preimage,omitempty"`
+
+	// PublicKeyFormat is the format of the public key
+	// hashed by a P2PKH input, "compressed" or
+	// "uncompressed". It defaults to "compressed".
+	PublicKeyFormat string `json:"public_key_format,omitempty"`
+
+	// HTLC describes the hash time locked contract
+	// spent by the input. It is only returned from
+	// /construction/parse.
+	HTLC *htlcMetadata `json:"htlc,omitempty"`
+}
+
+// htlcMetadata describes a hash time locked contract.
+type htlcMetadata struct {
+	PaymentHash        string `json:"payment_hash"`
+	RecipientPublicKey string `json:"recipient_public_key"`
+	RefundPublicKey    string `json:"refund_public_key"`
+	LockTime           int64  `json:"lock_time"`
+
+	// Branch is htlcClaim or htlcRefund.
+	Branch string `json:"branch"`
+}
+
+// scriptHashSpends are the redeem scripts and the
+// preimages of the P2SH inputs of a transaction.
+type scriptHashSpends struct {
+	RedeemScripts []string
+	Preimages     []string
+
+	// LockedInputs are the indexes of the inputs
+	// spending a script checking the lock time.
+	LockedInputs []int
+}
+
+// outputMetadata is the optional metadata on an
+// OUTPUT operation.
+type outputMetadata struct {
+	// Data is hex-encoded data to embed in an OP_RETURN
+	// output. Data outputs have no account and a zero amount.
+	Data string `json:"data,omitempty"`
+
+	// RedeemScript is the redeem script of a P2SH
+	// output, like the one returned by /construction/derive.
+	RedeemScript string `json:"redeem_script,omitempty"`
 }
 
 type preprocessOptions struct {
 	Coins         []*types.Coin `json:"coins"`
 	EstimatedSize float64       `json:"estimated_size"`
 	FeeMultiplier *float64      `json:"fee_multiplier,omitempty"`
+	OutputAmounts []string      `json:"output_amounts,omitempty"`
+
+	// UnestimatedInputs are the indexes of the coins
+	// not included in EstimatedSize because their script
+	// class is unknown until ConstructionMetadata.
+	UnestimatedInputs []int `json:"unestimated_inputs,omitempty"`
+
+	// From is the account funding the transaction
+	// when its coins are selected in ConstructionMetadata.
+	From          *types.AccountIdentifier `json:"from,omitempty"`
+	CoinSelection string                   `json:"coin_selection,omitempty"`
+	ChangeAddress string                   `json:"change_address,omitempty"`
+	InputSize     int64                    `json:"input_size,omitempty"`
+	ChangeSize    int64                    `json:"change_size,omitempty"`
+
+	// Sweep spends every coin of From, up to MaxCoins
+	// coins and MaxInputSize bytes of inputs, to the
+	// address of the only OUTPUT operation.
+	Sweep        bool  `json:"sweep,omitempty"`
+	MaxCoins     int   `json:"max_coins,omitempty"`
+	MaxInputSize int64 `json:"max_input_size,omitempty"`
+
+	// PSBT requests the previous transactions of
+	// the coins in ConstructionMetadata.
+	PSBT bool `json:"psbt,omitempty"`
+
+	LockTime uint32 `json:"lock_time,omitempty"`
+
+	AllowHighFee bool `json:"allow_high_fee,omitempty"`
+	BIP69        bool `json:"bip69,omitempty"`
+
+	// FeeRate, ScriptPubKeys and InputAddresses let
+	// ConstructionMetadata run without a node.
+	FeeRate        int64    `json:"fee_rate,omitempty"`
+	ScriptPubKeys  []string `json:"script_pub_keys,omitempty"`
+	InputAddresses []string `json:"input_addresses,omitempty"`
+
+	// BumpCoin is the coin of a stuck transaction spent
+	// in ConstructionMetadata to the address of the only
+	// OUTPUT operation, ChangeAddress.
+	BumpCoin *types.CoinIdentifier `json:"bump_coin,omitempty"`
+}
+
+// preprocessMetadata is the metadata of a
+// ConstructionPreprocessRequest.
+type preprocessMetadata struct {
+	// From is the account whose coins fund a transaction
+	// with only OUTPUT operations.
+	From          *types.AccountIdentifier `json:"from,omitempty"`
+	CoinSelection string                   `json:"coin_selection,omitempty"`
+
+	// ChangeAddress receives the change. It defaults
+	// to the address of From.
+	ChangeAddress string `json:"change_address,omitempty"`
+
+	// Sweep spends every coin of From to the address
+	// of the only OUTPUT operation, which has no amount.
+	// The output pays the balance minus the fee. The
+	// coins are spent from the largest and can be capped
+	// by MaxCoins or by MaxInputSize, the estimated size
+	// of the inputs in bytes.
+	Sweep        bool  `json:"sweep,omitempty"`
+	MaxCoins     int   `json:"max_coins,omitempty"`
+	MaxInputSize int64 `json:"max_input_size,omitempty"`
+
+	// PSBT adds a BIP-174 PSBT to the unsigned
+	// transaction returned by ConstructionPayloads.
+	PSBT bool `json:"psbt,omitempty"`
+
+	// LockTime is the block height or, from 500000000,
+	// the UNIX timestamp before which the transaction
+	// cannot be included in a block.
+	LockTime uint32 `json:"lock_time,omitempty"`
+
+	// AllowHighFee disables the check of the fee
+	// implied by the operations in ConstructionPayloads.
+	AllowHighFee bool `json:"allow_high_fee,omitempty"`
+
+	// BIP69 sorts the inputs and the outputs of the
+	// transaction built by ConstructionPayloads per
+	// BIP-69 instead of in the order of the operations.
+	BIP69 bool `json:"bip69,omitempty"`
+
+	// FeeRate is the fee rate in Satoshis per kB used
+	// instead of the fee rate estimated by the node.
+	// Offline, the fee policy falls back to its default
+	// fee rate when it is not provided.
+	FeeRate int64 `json:"fee_rate,omitempty"`
+
+	// ScriptPubKeys are the hex-encoded scripts of the
+	// coins spent, used instead of the scripts recorded
+	// by the indexer. Offline, the scripts of coins of
+	// P2PKH and segwit addresses are derived from the
+	// addresses when they are not provided.
+	ScriptPubKeys []string `json:"script_pub_keys,omitempty"`
+
+	// BumpCoin is a coin of a transaction stuck in the
+	// mempool, like "txid:index". It is spent by a child
+	// transaction to the address of the only OUTPUT
+	// operation, which has no amount. The child pays the
+	// fee bringing the fee rate of the stuck transaction,
+	// of its unconfirmed ancestors and of the child to
+	// FeeRate or to the fee rate estimated by the node.
+	BumpCoin *types.CoinIdentifier `json:"bump_coin,omitempty"`
 }
 
 type constructionMetadata struct {
 	ScriptPubKeys []*bitcoin.ScriptPubKey `json:"script_pub_keys"`
+
+	// Coins, Account and Change are populated when
+	// the coins are selected in ConstructionMetadata.
+	// ConstructionPayloads spends Coins from Account
+	// and appends Change to the OUTPUT operations.
+	Coins   []*types.Coin            `json:"coins,omitempty"`
+	Account *types.AccountIdentifier `json:"account,omitempty"`
+	Change  *types.Operation         `json:"change,omitempty"`
+
+	// Sweep replaces the OUTPUT operation without
+	// amount of a sweep or of a fee bump by Change.
+	Sweep bool `json:"sweep,omitempty"`
+
+	// PreviousTransactions are the serialized
+	// transactions creating the coins spent, used
+	// to build a PSBT in ConstructionPayloads.
+	PreviousTransactions []string `json:"previous_transactions,omitempty"`
+
+	LockTime uint32 `json:"lock_time,omitempty"`
+
+	// SuggestedFee is the fee in Satoshis suggested by
+	// ConstructionMetadata. ConstructionPayloads refuses
+	// operations implying a much higher fee unless
+	// AllowHighFee is set.
+	SuggestedFee int64 `json:"suggested_fee,omitempty"`
+	AllowHighFee bool  `json:"allow_high_fee,omitempty"`
+
+	BIP69 bool `json:"bip69,omitempty"`
 }
 
 type signedTransaction struct {
-	Transaction  string   `json:"transaction"`
-	InputAmounts []string `json:"input_amounts"`
+	Transaction         string   `json:"transaction"`
+	InputAmounts        []string `json:"input_amounts"`
+	OutputRedeemScripts []string `json:"output_redeem_scripts,omitempty"`
+	InputIndexes        []int64  `json:"input_indexes,omitempty"`
+	OutputIndexes       []int64  `json:"output_indexes,omitempty"`
+}
+
+// messageParams are the parameters of MessageDigestMethod.
+type messageParams struct {
+	Address string `json:"address"`
+	Message string `json:"message"`
+}
+
+// messageSignatureParams are the parameters of
+// MessageSignatureMethod. Signature is the
+// ecdsa_recovery signature of the digest.
+type messageSignatureParams struct {
+	Address   string           `json:"address"`
+	Message   string           `json:"message"`
+	Signature *types.Signature `json:"signature"`
+}
+
+// verifyMessageParams are the parameters of
+// VerifyMessageMethod. Signature is base64 encoded.
+type verifyMessageParams struct {
+	Address   string `json:"address"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+type messageDigestResult struct {
+	Payload *types.SigningPayload `json:"payload"`
+}
+
+type messageSignatureResult struct {
+	Signature string `json:"signature"`
+}
+
+type verifyMessageResult struct {
+	Valid bool `json:"valid"`
+}
+
+// batchPayoutParams are the parameters of BatchPayoutMethod.
+type batchPayoutParams struct {
+	// From is the account whose coins fund the payouts.
+	From       *types.AccountIdentifier `json:"from"`
+	Recipients []*batchRecipient        `json:"recipients"`
+
+	// ChangeAddress receives the change of each
+	// transaction. It defaults to the address of From.
+	ChangeAddress string `json:"change_address,omitempty"`
+	CoinSelection string `json:"coin_selection,omitempty"`
+
+	// FeeRate is the fee rate in Satoshis per kB used
+	// instead of the fee rate estimated by the node.
+	FeeRate       int64    `json:"fee_rate,omitempty"`
+	FeeMultiplier *float64 `json:"fee_multiplier,omitempty"`
+
+	// MaxSize is the largest estimated size in bytes of
+	// each transaction. It defaults to, and cannot exceed,
+	// the standard size limit.
+	MaxSize int64 `json:"max_size,omitempty"`
+}
+
+// batchRecipient is an output of a batch payout
+// paying Amount Satoshis to Address.
+type batchRecipient struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// batchPayoutResult is the result of BatchPayoutMethod.
+type batchPayoutResult struct {
+	Transactions []*batchTransaction `json:"transactions"`
+}
+
+// batchTransaction is a transaction of a batch payout.
+// Operations and Metadata are the operations and the
+// metadata of a ConstructionPayloadsRequest.
+type batchTransaction struct {
+	Operations []*types.Operation     `json:"operations"`
+	Metadata   map[string]interface{} `json:"metadata"`
+
+	// Recipients are the indexes of the recipients
+	// paid by the transaction, in the order of its
+	// OUTPUT operations.
+	Recipients []int `json:"recipients"`
+
+	// Fee is the fee in Satoshis of the transaction of
+	// Size bytes, estimated from its inputs and outputs.
+	Fee  int64 `json:"fee"`
+	Size int64 `json:"size"`
+}
+
+// parseMetadata is the metadata of the
+// transaction returned from ConstructionParse.
+type parseMetadata struct {
+	// Fee is the fee implied by the input
+	// amounts and the outputs, in satoshis.
+	Fee int64 `json:"fee"`
+
+	// Size is the serialized size of the transaction in
+	// bytes. The size of an unsigned transaction is
+	// estimated from the signatures it expects.
+	Size int64 `json:"size"`
+
+	// FeeRate is Fee per kB of Size.
+	FeeRate  int64  `json:"fee_rate"`
+	LockTime uint32 `json:"lock_time"`
 }
 
 // ParseOperationMetadata is returned from
-// ConstructionParse.
+// ConstructionParse in the metadata of each
+// operation, along with its input or output
+// metadata. ScriptPubKey is the script spent
+// by an input or locking an output.
 type ParseOperationMetadata struct {
 	ScriptPubKey *bitcoin.ScriptPubKey `json:"scriptPubKey"`
 }